@@ -0,0 +1,71 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"sync"
+)
+
+// Action names shared by every input that can trigger playback
+const (
+	ActionPlayAudioFile   = "playAudioFile"
+	ActionStopAudioFile   = "stopAudioFile"
+	ActionStopAll         = "stopAll"
+	ActionSetVolume       = "setVolume"
+	ActionSetMicGain      = "setMicGain"
+	ActionToggleEffect    = "toggleEffect"
+	ActionLearnNoiseFloor = "learnNoiseFloor"
+	ActionSaveReplay      = "saveReplay"
+)
+
+// ActionHandler runs an action. arg selects the target, such as an audio file,
+// and value is the trigger strength normalized to 0..1.
+type ActionHandler func(arg string, value float64) error
+
+// ActionRegistry maps action names to their handlers
+type ActionRegistry struct {
+	mu       sync.RWMutex
+	handlers map[string]ActionHandler
+}
+
+// NewActionRegistry creates a new ActionRegistry
+func NewActionRegistry() *ActionRegistry {
+	return &ActionRegistry{
+		handlers: make(map[string]ActionHandler),
+	}
+}
+
+// Register registers the handler for the named action, replacing any previous one
+func (r *ActionRegistry) Register(name string, handler ActionHandler) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.handlers[name] = handler
+}
+
+// Run runs the named action
+func (r *ActionRegistry) Run(name string, arg string, value float64) error {
+	r.mu.RLock()
+	handler, ok := r.handlers[name]
+	r.mu.RUnlock()
+
+	if !ok {
+		return fmt.Errorf("unknown action %q", name)
+	}
+
+	return handler(arg, value)
+}
+
+// Names lists the registered action names
+func (r *ActionRegistry) Names() []string {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	names := make([]string, 0, len(r.handlers))
+	for name := range r.handlers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	return names
+}
@@ -5,26 +5,70 @@ import (
 	"encoding/hex"
 	"encoding/json"
 	"fmt"
-	"io"
 	"log"
+	"math"
+	"net"
+	"net/http"
 	"os"
 	"path/filepath"
-	"strings"
+	"sync"
+	"sync/atomic"
 
 	"github.com/adrg/xdg"
 	"github.com/gen2brain/malgo"
-	"github.com/hajimehoshi/go-mp3"
 	hook "github.com/robotn/gohook"
 	"github.com/wailsapp/wails/v2/pkg/runtime"
-	"github.com/youpy/go-wav"
+	"golang.org/x/net/websocket"
 )
 
 // App struct
 type App struct {
-	ctx                          context.Context
-	fs                           *FileStorage
-	cancelFunctionsForAudioFiles map[string]context.CancelFunc
-	cancelLoopbackAudio          context.CancelFunc
+	ctx                      context.Context
+	headless                 bool
+	fs                       *FileStorage
+	cancelLoopbackAudio      context.CancelFunc
+	loopbackDone             chan struct{}
+	loopbackDevices          LoopbackDevices
+	loopbackMu               sync.Mutex
+	loopbackStats            atomic.Pointer[LoopbackStats]
+	deviceMonitor            *DeviceMonitor
+	engine                   *AudioEngine
+	cancelDeviceMonitor      context.CancelFunc
+	cancelMIDIInput          context.CancelFunc
+	midiInputMu              sync.Mutex
+	volume                   atomic.Uint64
+	micGain                  atomic.Uint64
+	effectSettings           atomic.Pointer[EffectSettings]
+	effectChain              atomic.Pointer[LiveEffectChain]
+	noiseSuppressionSettings atomic.Pointer[NoiseSuppressionSettings]
+	noiseProfiler            atomic.Pointer[NoiseProfiler]
+	latencyProbe             atomic.Pointer[LatencyProbe]
+	roundTripMilliseconds    atomic.Uint64
+	recording                *recording
+	recordingMu              sync.Mutex
+	recordingState           atomic.Pointer[RecordingState]
+	replayBuffer             atomic.Pointer[ReplayBuffer]
+	cancelReplayBuffer       context.CancelFunc
+	replayBufferDone         chan struct{}
+	replayBufferMu           sync.Mutex
+	stopClipRecording        chan struct{}
+	clipRecordingMu          sync.Mutex
+	virtualMic               *VirtualMic
+	virtualMicMu             sync.Mutex
+	voices                   *VoiceManager
+	mixer                    *Mixer
+	actions                  *ActionRegistry
+	hotkeys                  *HotkeyDispatcher
+	midi                     *MIDIDispatcher
+	midiFeedback             *MIDIFeedback
+	remoteControl            *http.Server
+	cancelRemoteControl      context.CancelFunc
+	remoteControlConns       map[*websocket.Conn]struct{}
+	remoteControlMu          sync.Mutex
+	osc                      atomic.Pointer[OSCServer]
+	oscMu                    sync.Mutex
+	control                  net.Listener
+	launchRequests           []ControlRequest
 }
 
 // NewApp creates a new App application struct
@@ -41,26 +85,160 @@ func NewApp() *App {
 	}
 	file.Close()
 
-	return &App{
-		fs:                           NewFileStorage(filePath),
-		cancelFunctionsForAudioFiles: make(map[string]context.CancelFunc),
+	actions := NewActionRegistry()
+
+	a := &App{
+		fs:           NewFileStorage(filePath),
+		voices:       NewVoiceManager(),
+		mixer:        NewMixer(),
+		engine:       NewAudioEngine(),
+		actions:      actions,
+		hotkeys:      NewHotkeyDispatcher(),
+		midi:         NewMIDIDispatcher(actions.Run),
+		midiFeedback: NewMIDIFeedback(),
 	}
+	a.registerActions()
+
+	return a
+}
+
+// registerActions registers the actions that hotkeys and controllers can trigger
+func (a *App) registerActions() {
+	a.actions.Register(ActionPlayAudioFile, func(audioFile string, _ float64) error {
+		return a.PlayAudioFile(audioFile)
+	})
+	a.actions.Register(ActionStopAudioFile, func(audioFile string, _ float64) error {
+		return a.StopAudioFile(audioFile)
+	})
+	a.actions.Register(ActionStopAll, func(_ string, _ float64) error {
+		return a.StopAllAudioFiles()
+	})
+	a.actions.Register(ActionSetVolume, func(_ string, value float64) error {
+		return a.SetVolume(value)
+	})
+	a.actions.Register(ActionSetMicGain, func(_ string, value float64) error {
+		return a.SetMicGain(value)
+	})
+	a.actions.Register(ActionToggleEffect, func(effect string, _ float64) error {
+		return a.ToggleEffect(effect)
+	})
+	a.actions.Register(ActionLearnNoiseFloor, func(_ string, _ float64) error {
+		// Learning takes a while, which would hold up the hotkey or controller
+		go func() {
+			if err := a.LearnNoiseFloor(); err != nil {
+				log.Println(err)
+			}
+		}()
+		return nil
+	})
+	a.actions.Register(ActionSaveReplay, func(_ string, _ float64) error {
+		_, err := a.SaveReplay()
+		return err
+	})
 }
 
 // startup is called at application startup
 func (a *App) startup(ctx context.Context) {
 	a.ctx = ctx
 
-	ctx, cancel := context.WithCancel(ctx)
-	a.cancelLoopbackAudio = cancel
+	volume, err := a.GetVolume()
+	if err != nil {
+		log.Println(err)
+		volume = 1
+	}
+	a.volume.Store(math.Float64bits(volume))
 
-	go func() {
-		if err := a.LoopbackAudio(ctx); err != nil {
-			log.Fatal(err)
+	micGain, err := a.GetMicGain()
+	if err != nil {
+		log.Println(err)
+		micGain = 1
+	}
+	a.micGain.Store(math.Float64bits(micGain))
+
+	if err := a.applyEffectSettings(); err != nil {
+		log.Println(err)
+	}
+
+	if err := a.applyDuckingSettings(); err != nil {
+		log.Println(err)
+	}
+
+	if err := a.applyOutputBuses(); err != nil {
+		log.Println(err)
+	}
+
+	if err := a.restartReplayBuffer(); err != nil {
+		log.Println(err)
+	}
+
+	a.voices.Subscribe(func(event AudioFileStateEvent) {
+		if err := a.midiFeedback.Update(event.AudioFile, event.State); err != nil {
+			log.Println(err)
 		}
-	}()
 
-	go a.registerAudioFileKeybindings()
+		a.broadcastOSCState(event)
+
+		a.emit("audioFileState", event)
+	})
+
+	if err := a.startAudioEngine(); err != nil {
+		log.Println(err)
+	}
+
+	a.migrateDevicePreferences()
+
+	// The virtual microphone's sink has to exist before the first enumeration
+	a.startVirtualMic()
+
+	// The first enumeration starts the loopback
+	a.startDeviceMonitor()
+
+	if a.control != nil {
+		go a.serveControl()
+	}
+
+	if hotkeysAvailable() {
+		go a.hotkeys.Run(hook.Start())
+	}
+
+	if err := a.registerKeybindings(); err != nil {
+		log.Println(err)
+	}
+
+	midiFeedbackProfile, err := a.GetMIDIFeedbackProfile()
+	if err != nil {
+		log.Println(err)
+	}
+	a.midiFeedback.SetProfile(midiFeedbackProfile)
+
+	if err := a.registerMIDIBindings(); err != nil {
+		log.Println(err)
+	}
+
+	a.restartMIDIInput()
+
+	if err := a.restartRemoteControl(); err != nil {
+		log.Println(err)
+	}
+
+	if err := a.restartOSC(); err != nil {
+		log.Println(err)
+	}
+
+	for _, request := range a.launchRequests {
+		if _, err := a.handleControlRequest(request); err != nil {
+			log.Println(err)
+		}
+	}
+}
+
+// emit emits an event to the frontend, if there is one
+func (a *App) emit(eventName string, optionalData ...interface{}) {
+	if a.headless {
+		return
+	}
+
+	runtime.EventsEmit(a.ctx, eventName, optionalData...)
 }
 
 // domReady is called after front-end resources have been loaded
@@ -75,155 +253,179 @@ func (a *App) beforeClose(ctx context.Context) (prevent bool) {
 
 // shutdown is called at application termination
 func (a *App) shutdown(ctx context.Context) {
-	// Perform your teardown here
-}
+	a.stopRemoteControl()
+	a.stopOSC()
+	a.stopControl()
 
-// MediaDeviceInfo struct
-type MediaDeviceInfo struct {
-	DeviceID string `json:"deviceId"`
-	GroupID  string `json:"groupId"`
-	Kind     string `json:"kind"`
-	Label    string `json:"label"`
-}
+	a.stopMIDIInput()
 
-// ListCaptureDevices lists all available capture devices
-func (a *App) ListCaptureDevices() ([]MediaDeviceInfo, error) {
-	ctx, err := malgo.InitContext(nil, malgo.ContextConfig{}, nil)
-	if err != nil {
-		return nil, err
+	if a.cancelDeviceMonitor != nil {
+		a.cancelDeviceMonitor()
 	}
-	defer func() {
-		if err := ctx.Uninit(); err != nil {
-			log.Fatal(err)
-		}
-		ctx.Free()
-	}()
 
-	deviceInfos, err := ctx.Devices(malgo.Capture)
-	if err != nil {
-		return nil, err
+	if err := a.StopRecording(); err != nil {
+		log.Println(err)
 	}
 
-	var devices []MediaDeviceInfo
-	for _, deviceInfo := range deviceInfos {
-		devices = append(devices, MediaDeviceInfo{
-			DeviceID: deviceInfo.ID.String(),
-			Kind:     "audioinput",
-			Label:    deviceInfo.Name(),
-		})
-	}
+	a.replayBufferMu.Lock()
+	a.stopReplayBuffer()
+	a.replayBufferMu.Unlock()
 
-	return devices, nil
-}
+	a.loopbackMu.Lock()
+	a.stopLoopbackAudio()
+	a.loopbackMu.Unlock()
 
-// GetCaptureDeviceID gets the capture device by ID
-func (a *App) GetCaptureDeviceID() (string, error) {
-	serializedCaptureDeviceID, _ := a.fs.GetItem("captureDeviceID")
-	if serializedCaptureDeviceID == "" {
-		return "", nil
-	}
+	a.voices.StopAll()
+
+	a.engine.Stop()
 
-	var captureDeviceID string
-	if err := json.Unmarshal([]byte(serializedCaptureDeviceID), &captureDeviceID); err != nil {
-		return "", err
+	if err := a.removeVirtualMic(); err != nil {
+		log.Println(err)
 	}
 
-	return captureDeviceID, nil
+	if hotkeysAvailable() {
+		hook.End()
+	}
 }
 
-// SetCaptureDeviceID sets the capture device by ID
-func (a *App) SetCaptureDeviceID(captureDeviceID string) error {
-	serializedCaptureDeviceID, err := json.Marshal(captureDeviceID)
-	if err != nil {
-		return err
-	}
+// MediaDeviceInfo struct
+type MediaDeviceInfo struct {
+	DeviceID      string         `json:"deviceId"`
+	GroupID       string         `json:"groupId"`
+	Kind          string         `json:"kind"`
+	Label         string         `json:"label"`
+	Backend       string         `json:"backend"`
+	IsDefault     bool           `json:"isDefault"`
+	Formats       []DeviceFormat `json:"formats"`
+	MinChannels   uint32         `json:"minChannels"`
+	MaxChannels   uint32         `json:"maxChannels"`
+	MinSampleRate uint32         `json:"minSampleRate"`
+	MaxSampleRate uint32         `json:"maxSampleRate"`
+}
 
-	if err := a.fs.SetItem("captureDeviceID", string(serializedCaptureDeviceID)); err != nil {
-		return err
-	}
+// DeviceFormat is a native data format of a device. A channel count or
+// sample rate of 0 means the device accepts any.
+type DeviceFormat struct {
+	Format     string `json:"format"`
+	Channels   uint32 `json:"channels"`
+	SampleRate uint32 `json:"sampleRate"`
+}
 
-	a.cancelLoopbackAudio()
+// sampleFormatNames names the sample formats
+var sampleFormatNames = map[malgo.FormatType]string{
+	malgo.FormatU8:  "u8",
+	malgo.FormatS16: "s16",
+	malgo.FormatS24: "s24",
+	malgo.FormatS32: "s32",
+	malgo.FormatF32: "f32",
+}
 
-	ctx, cancel := context.WithCancel(a.ctx)
-	a.cancelLoopbackAudio = cancel
+// ListCaptureDevices lists all available capture devices
+func (a *App) ListCaptureDevices() ([]MediaDeviceInfo, error) {
+	return a.listMediaDevices(malgo.Capture, true)
+}
 
-	go func() {
-		if err := a.LoopbackAudio(ctx); err != nil {
-			log.Fatal(err)
-		}
-	}()
+// GetCaptureDeviceID gets the current ID of the selected capture device
+func (a *App) GetCaptureDeviceID() (string, error) {
+	return a.getDeviceID("captureDevice", "captureDeviceID", "audioinput")
+}
 
-	return nil
+// SetCaptureDeviceID selects the capture device by ID, remembering it by name too
+func (a *App) SetCaptureDeviceID(captureDeviceID string) error {
+	return a.setDeviceID("captureDevice", "captureDeviceID", "audioinput", captureDeviceID)
 }
 
 // ListPlaybackDevices lists all available playback devices
 func (a *App) ListPlaybackDevices() ([]MediaDeviceInfo, error) {
-	ctx, err := malgo.InitContext(nil, malgo.ContextConfig{}, nil)
-	if err != nil {
-		return nil, err
-	}
-	defer func() {
-		if err := ctx.Uninit(); err != nil {
-			log.Fatal(err)
+	return a.listMediaDevices(malgo.Playback, true)
+}
+
+// listMediaDevices lists the capture or playback devices. Probing each device
+// for its capabilities can be slow, so it is only done when detailed is set.
+func (a *App) listMediaDevices(deviceType malgo.DeviceType, detailed bool) ([]MediaDeviceInfo, error) {
+	var deviceInfos []malgo.DeviceInfo
+	var backend string
+	err := a.engine.WithContext(func(ctx malgo.Context, contextBackend string) error {
+		var err error
+		deviceInfos, err = ctx.Devices(deviceType)
+		if err != nil {
+			return err
+		}
+		backend = contextBackend
+
+		if detailed {
+			// Enumeration leaves out the native formats on most backends
+			for i, deviceInfo := range deviceInfos {
+				if fullDeviceInfo, err := ctx.DeviceInfo(deviceType, deviceInfo.ID, malgo.Shared); err == nil {
+					deviceInfos[i] = fullDeviceInfo
+				}
+			}
 		}
-		ctx.Free()
-	}()
 
-	deviceInfos, err := ctx.Devices(malgo.Playback)
+		return nil
+	})
 	if err != nil {
 		return nil, err
 	}
 
+	kind := "audioinput"
+	if deviceType == malgo.Playback {
+		kind = "audiooutput"
+	}
+
 	var devices []MediaDeviceInfo
 	for _, deviceInfo := range deviceInfos {
-		devices = append(devices, MediaDeviceInfo{
-			DeviceID: deviceInfo.ID.String(),
-			Kind:     "audiooutput",
-			Label:    deviceInfo.Name(),
-		})
+		device := MediaDeviceInfo{
+			DeviceID:  deviceInfo.ID.String(),
+			Kind:      kind,
+			Label:     deviceInfo.Name(),
+			Backend:   backend,
+			IsDefault: deviceInfo.IsDefault != 0,
+		}
+
+		if detailed {
+			for _, dataFormat := range deviceInfo.Formats {
+				device.Formats = append(device.Formats, DeviceFormat{
+					Format:     sampleFormatNames[dataFormat.Format],
+					Channels:   dataFormat.Channels,
+					SampleRate: dataFormat.SampleRate,
+				})
+			}
+			device.MinChannels, device.MaxChannels = formatRange(device.Formats, func(f DeviceFormat) uint32 { return f.Channels })
+			device.MinSampleRate, device.MaxSampleRate = formatRange(device.Formats, func(f DeviceFormat) uint32 { return f.SampleRate })
+		}
+
+		devices = append(devices, device)
 	}
 
 	return devices, nil
 }
 
-// GetPlaybackDeviceID gets the playback device by ID
-func (a *App) GetPlaybackDeviceID() (string, error) {
-	serializedPlaybackDeviceID, _ := a.fs.GetItem("playbackDeviceID")
-	if serializedPlaybackDeviceID == "" {
-		return "", nil
-	}
-
-	var playbackDeviceID string
-	if err := json.Unmarshal([]byte(serializedPlaybackDeviceID), &playbackDeviceID); err != nil {
-		return "", err
+// formatRange returns the smallest and largest value across formats, ignoring
+// the 0 that stands for any value
+func formatRange(formats []DeviceFormat, value func(DeviceFormat) uint32) (uint32, uint32) {
+	var lo, hi uint32
+	for _, format := range formats {
+		v := value(format)
+		if v == 0 {
+			continue
+		}
+		if lo == 0 || v < lo {
+			lo = v
+		}
+		hi = max(hi, v)
 	}
+	return lo, hi
+}
 
-	return playbackDeviceID, nil
+// GetPlaybackDeviceID gets the current ID of the selected playback device
+func (a *App) GetPlaybackDeviceID() (string, error) {
+	return a.getDeviceID("playbackDevice", "playbackDeviceID", "audiooutput")
 }
 
-// SetPlaybackDeviceID sets the playback device by ID
+// SetPlaybackDeviceID selects the playback device by ID, remembering it by name too
 func (a *App) SetPlaybackDeviceID(playbackDeviceID string) error {
-	serializedPlaybackDeviceID, err := json.Marshal(playbackDeviceID)
-	if err != nil {
-		return err
-	}
-
-	if err := a.fs.SetItem("playbackDeviceID", string(serializedPlaybackDeviceID)); err != nil {
-		return err
-	}
-
-	a.cancelLoopbackAudio()
-
-	ctx, cancel := context.WithCancel(a.ctx)
-	a.cancelLoopbackAudio = cancel
-
-	go func() {
-		if err := a.LoopbackAudio(ctx); err != nil {
-			log.Fatal(err)
-		}
-	}()
-
-	return nil
+	return a.setDeviceID("playbackDevice", "playbackDeviceID", "audiooutput", playbackDeviceID)
 }
 
 // ListAudioFiles lists all available audio files
@@ -243,7 +445,7 @@ func (a *App) ListAudioFiles() ([]string, error) {
 
 // AddAudioFile adds an audio file
 func (a *App) AddAudioFile(audioFile string) error {
-	return a.fs.UpdateItem("audioFiles", func(value string) (string, error) {
+	if err := a.fs.UpdateItem("audioFiles", func(value string) (string, error) {
 		var audioFiles []string
 		if value != "" {
 			if err := json.Unmarshal([]byte(value), &audioFiles); err != nil {
@@ -259,12 +461,18 @@ func (a *App) AddAudioFile(audioFile string) error {
 		}
 
 		return string(serializedAudioFiles), nil
-	})
+	}); err != nil {
+		return err
+	}
+
+	a.emit("audioFilesChanged")
+
+	return nil
 }
 
 // RemoveAudioFile removes an audio file
 func (a *App) RemoveAudioFile(audioFile string) error {
-	return a.fs.UpdateItem("audioFiles", func(value string) (string, error) {
+	if err := a.fs.UpdateItem("audioFiles", func(value string) (string, error) {
 		var audioFiles []string
 		if value != "" {
 			if err := json.Unmarshal([]byte(value), &audioFiles); err != nil {
@@ -285,139 +493,229 @@ func (a *App) RemoveAudioFile(audioFile string) error {
 		}
 
 		return string(serializedAudioFiles), nil
-	})
+	}); err != nil {
+		return err
+	}
+
+	a.emit("audioFilesChanged")
+
+	return nil
 }
 
 // PlayAudioFile plays an audio file
 func (a *App) PlayAudioFile(audioFile string) error {
-	a.StopAudioFile(audioFile)
+	stream, err := openAudioStream(audioFile)
+	if err != nil {
+		a.voices.Notify(audioFile)
+		return err
+	}
+
+	audioFileLoops, err := a.ListAudioFileLoops()
+	if err != nil {
+		stream.Close()
+		return err
+	}
+	loop := audioFileLoops[audioFile]
+
+	audioFileBuses, err := a.ListAudioFileBuses()
+	if err != nil {
+		stream.Close()
+		return err
+	}
 
 	ctx, cancel := context.WithCancel(a.ctx)
-	a.cancelFunctionsForAudioFiles[audioFile] = cancel
+	id := a.voices.Start(audioFile, loop, cancel)
+	clip := NewMixerClip(audioFile, stream, loop, audioFileBuses[audioFile])
 
 	go func() {
-		audioContext, err := malgo.InitContext(nil, malgo.ContextConfig{}, nil)
-		if err != nil {
-			log.Fatal(err)
-		}
-		defer func() {
-			if err := audioContext.Uninit(); err != nil {
-				log.Fatal(err)
+		defer a.voices.Finish(audioFile, id)
+		defer clip.Close()
+
+		// Clips are mixed into the loopback, where they can duck the mic or
+		// be ducked under it, and only get a device of their own without it.
+		// They end along with the loopback.
+		if detached, ok := a.mixer.Add(clip); ok {
+			select {
+			case <-ctx.Done():
+			case <-clip.Done():
+			case <-detached:
 			}
-			audioContext.Free()
-		}()
+			a.mixer.Remove(clip)
+			return
+		}
 
-		file, err := os.Open(audioFile)
+		if err := a.playAudioStream(ctx, clip, stream.sampleRate, stream.channels); err != nil {
+			log.Println(err)
+		}
+	}()
+
+	return nil
+}
+
+// playAudioStream plays a clip on its own device until it ends or ctx is done
+func (a *App) playAudioStream(ctx context.Context, clip *MixerClip, sampleRate uint32, channels uint32) error {
+	deviceConfig := malgo.DefaultDeviceConfig(malgo.Playback)
+	deviceConfig.Alsa.NoMMap = 1
+	deviceConfig.Playback.Channels = channels
+	deviceConfig.Playback.Format = malgo.FormatF32
+	deviceConfig.SampleRate = sampleRate
+
+	// Play on the same device as the loopback, including any fallback
+	loopbackDevices, _ := a.GetLoopbackDevices()
+	if deviceID := loopbackDevices.PlaybackDeviceID; deviceID != "" {
+		deviceID, err := ParseHexStringToDeviceID(deviceID)
 		if err != nil {
-			log.Fatal(err)
+			return err
 		}
-		defer file.Close()
-
-		var audioFormat malgo.FormatType
-		var channels uint32
-		var reader io.Reader
-		var sampleRate uint32
-
-		switch filepath.Ext(audioFile) {
-		case ".wav":
-			w := wav.NewReader(file)
-			f, err := w.Format()
-			if err != nil {
-				log.Fatal(err)
-			}
+		deviceConfig.Playback.DeviceID = deviceID.Pointer()
+	}
 
-			switch f.AudioFormat {
-			case 1:
-				switch f.BitsPerSample {
-				case 8:
-					audioFormat = malgo.FormatU8
-				case 16:
-					audioFormat = malgo.FormatS16
-				case 24:
-					audioFormat = malgo.FormatS24
-				case 32:
-					audioFormat = malgo.FormatS32
-				default:
-					log.Fatal("Unsupported bits per sample: ", f.BitsPerSample)
-				}
-			case 3:
-				switch f.BitsPerSample {
-				case 32:
-					audioFormat = malgo.FormatF32
-				default:
-					log.Fatal("Unsupported bits per sample: ", f.BitsPerSample)
-				}
-			default:
-				log.Fatal("Unsupported audio format: ", f.AudioFormat)
-			}
+	deviceCallbacks := malgo.DeviceCallbacks{
+		Data: func(pOutputSample, _ []byte, _ uint32) {
+			clip.Read(float32Samples(pOutputSample), sampleRate, int(channels))
+			scaleSamples(pOutputSample, malgo.FormatF32, math.Float64frombits(a.volume.Load()))
+		},
+	}
 
-			channels = uint32(f.NumChannels)
-			reader = w
-			sampleRate = f.SampleRate
-		case ".mp3":
-			m, err := mp3.NewDecoder(file)
-			if err != nil {
-				log.Fatal(err)
-			}
+	device, err := a.engine.OpenDevice(deviceConfig, deviceCallbacks)
+	if err != nil {
+		return err
+	}
+	defer device.Close()
 
-			audioFormat = malgo.FormatS16
-			channels = 2
-			reader = m
-			sampleRate = uint32(m.SampleRate())
-		default:
-			log.Fatal("Unsupported audio file format: ", filepath.Ext(audioFile))
-		}
+	if err := device.Start(); err != nil {
+		return err
+	}
+
+	select {
+	case <-ctx.Done():
+	case <-clip.Done():
+	}
+
+	return device.Stop()
+}
+
+// StopAudioFile stops an audio file
+func (a *App) StopAudioFile(audioFile string) error {
+	a.voices.Stop(audioFile)
+
+	return nil
+}
+
+// StopAllAudioFiles stops all playing audio files
+func (a *App) StopAllAudioFiles() error {
+	a.voices.StopAll()
 
-		deviceConfig := malgo.DefaultDeviceConfig(malgo.Playback)
-		deviceConfig.Alsa.NoMMap = 1
-		deviceConfig.Playback.Channels = channels
-		deviceConfig.Playback.Format = audioFormat
-		deviceConfig.SampleRate = sampleRate
-
-		deviceID, _ := a.GetPlaybackDeviceID()
-		if deviceID != "" {
-			deviceID, err := ParseHexStringToDeviceID(deviceID)
-			if err != nil {
-				log.Fatal(err)
+	return nil
+}
+
+// GetAudioFileState gets whether an audio file is idle, playing, looping or missing
+func (a *App) GetAudioFileState(audioFile string) (string, error) {
+	return a.voices.State(audioFile), nil
+}
+
+// ListAudioFileLoops lists which audio files loop until stopped
+func (a *App) ListAudioFileLoops() (map[string]bool, error) {
+	serializedAudioFileLoops, _ := a.fs.GetItem("audioFileLoops")
+	if serializedAudioFileLoops == "" {
+		return map[string]bool{}, nil
+	}
+
+	var audioFileLoops map[string]bool
+	if err := json.Unmarshal([]byte(serializedAudioFileLoops), &audioFileLoops); err != nil {
+		return nil, err
+	}
+
+	return audioFileLoops, nil
+}
+
+// SetAudioFileLoop sets whether an audio file loops until stopped
+func (a *App) SetAudioFileLoop(audioFile string, loop bool) error {
+	return a.fs.UpdateItem("audioFileLoops", func(value string) (string, error) {
+		audioFileLoops := make(map[string]bool)
+		if value != "" {
+			if err := json.Unmarshal([]byte(value), &audioFileLoops); err != nil {
+				return "", err
 			}
-			deviceConfig.Playback.DeviceID = deviceID.Pointer()
 		}
 
-		deviceCallbacks := malgo.DeviceCallbacks{
-			Data: func(pOutputSample, _ []byte, _ uint32) {
-				io.ReadFull(reader, pOutputSample)
-			},
+		if loop {
+			audioFileLoops[audioFile] = true
+		} else {
+			delete(audioFileLoops, audioFile)
 		}
 
-		device, err := malgo.InitDevice(audioContext.Context, deviceConfig, deviceCallbacks)
+		serializedAudioFileLoops, err := json.Marshal(audioFileLoops)
 		if err != nil {
-			log.Fatal(err)
+			return "", err
 		}
 
-		if err := device.Start(); err != nil {
-			log.Fatal(err)
-		}
+		return string(serializedAudioFileLoops), nil
+	})
+}
 
-		<-ctx.Done()
+// GetVolume gets the volume audio files are played at, from 0 to 1
+func (a *App) GetVolume() (float64, error) {
+	serializedVolume, _ := a.fs.GetItem("volume")
+	if serializedVolume == "" {
+		return 1, nil
+	}
 
-		if err := device.Stop(); err != nil {
-			log.Fatal(err)
-		}
-	}()
+	var volume float64
+	if err := json.Unmarshal([]byte(serializedVolume), &volume); err != nil {
+		return 0, err
+	}
+
+	return volume, nil
+}
+
+// SetVolume sets the volume audio files are played at, from 0 to 1
+func (a *App) SetVolume(volume float64) error {
+	volume = math.Max(0, math.Min(1, volume))
+
+	serializedVolume, err := json.Marshal(volume)
+	if err != nil {
+		return err
+	}
+
+	if err := a.fs.SetItem("volume", string(serializedVolume)); err != nil {
+		return err
+	}
+
+	a.volume.Store(math.Float64bits(volume))
 
 	return nil
 }
 
-// StopAudioFile stops an audio file
-func (a *App) StopAudioFile(audioFile string) error {
-	cancel, ok := a.cancelFunctionsForAudioFiles[audioFile]
-	if !ok {
-		return nil
+// GetMicGain gets the gain applied to the capture device, from 0 to 1
+func (a *App) GetMicGain() (float64, error) {
+	serializedMicGain, _ := a.fs.GetItem("micGain")
+	if serializedMicGain == "" {
+		return 1, nil
+	}
+
+	var micGain float64
+	if err := json.Unmarshal([]byte(serializedMicGain), &micGain); err != nil {
+		return 0, err
+	}
+
+	return micGain, nil
+}
+
+// SetMicGain sets the gain applied to the capture device, from 0 to 1
+func (a *App) SetMicGain(micGain float64) error {
+	micGain = math.Max(0, math.Min(1, micGain))
+
+	serializedMicGain, err := json.Marshal(micGain)
+	if err != nil {
+		return err
 	}
 
-	cancel()
+	if err := a.fs.SetItem("micGain", string(serializedMicGain)); err != nil {
+		return err
+	}
 
-	delete(a.cancelFunctionsForAudioFiles, audioFile)
+	a.micGain.Store(math.Float64bits(micGain))
 
 	return nil
 }
@@ -462,92 +760,6 @@ func (a *App) OpenMultipleFilesDialog(dialogOptions OpenDialogOptions) ([]string
 	})
 }
 
-// LoopbackAudio loops back audio from the capture device to the playback device
-func (a *App) LoopbackAudio(ctx context.Context) error {
-	audioContext, err := malgo.InitContext(nil, malgo.ContextConfig{}, nil)
-	if err != nil {
-		return err
-	}
-
-	captureDeviceConfig := malgo.DefaultDeviceConfig(malgo.Capture)
-	captureDeviceConfig.Alsa.NoMMap = 1
-	captureDeviceConfig.Capture.Channels = 1
-	captureDeviceConfig.Capture.Format = malgo.FormatS16
-	captureDeviceConfig.SampleRate = 44100
-
-	captureDeviceID, _ := a.GetCaptureDeviceID()
-	if captureDeviceID != "" {
-		deviceID, err := ParseHexStringToDeviceID(captureDeviceID)
-		if err != nil {
-			return err
-		}
-		captureDeviceConfig.Capture.DeviceID = deviceID.Pointer()
-	}
-
-	pInputSamplesChannel := make(chan []byte)
-
-	captureDeviceCallbacks := malgo.DeviceCallbacks{
-		Data: func(_, pInputSamples []byte, _ uint32) {
-			pInputSamplesChannel <- pInputSamples
-		},
-	}
-
-	captureDevice, err := malgo.InitDevice(audioContext.Context, captureDeviceConfig, captureDeviceCallbacks)
-	if err != nil {
-		return err
-	}
-
-	if err := captureDevice.Start(); err != nil {
-		return err
-	}
-
-	playbackDeviceConfig := malgo.DefaultDeviceConfig(malgo.Playback)
-	playbackDeviceConfig.Alsa.NoMMap = 1
-	playbackDeviceConfig.Playback.Channels = 1
-	playbackDeviceConfig.Playback.Format = malgo.FormatS16
-	playbackDeviceConfig.SampleRate = 44100
-
-	playbackDeviceID, _ := a.GetPlaybackDeviceID()
-	if playbackDeviceID != "" {
-		deviceID, err := ParseHexStringToDeviceID(playbackDeviceID)
-		if err != nil {
-			return err
-		}
-		playbackDeviceConfig.Playback.DeviceID = deviceID.Pointer()
-	}
-
-	playbackDeviceCallbacks := malgo.DeviceCallbacks{
-		Data: func(pOutputSample, _ []byte, _ uint32) {
-			pInputSamples, ok := <-pInputSamplesChannel
-			if !ok {
-				return
-			}
-			copy(pOutputSample, pInputSamples)
-		},
-	}
-
-	playbackDevice, err := malgo.InitDevice(audioContext.Context, playbackDeviceConfig, playbackDeviceCallbacks)
-	if err != nil {
-		return err
-	}
-
-	if err := playbackDevice.Start(); err != nil {
-		return err
-	}
-
-	<-ctx.Done()
-
-	if err := captureDevice.Stop(); err != nil {
-		return err
-	}
-
-	if err := playbackDevice.Stop(); err != nil {
-		return err
-	}
-
-	return nil
-}
-
 // ListAudioFileKeybindings lists all available audio file keybindings
 func (a *App) ListAudioFileKeybindings() (map[string]string, error) {
 	serializedAudioFileKeybindings, _ := a.fs.GetItem("audioFileKeybindings")
@@ -585,9 +797,7 @@ func (a *App) SetAudioFileKeybinding(audioFile string, keybinding string) error
 		return err
 	}
 
-	go a.reloadAudioFileKeybindings()
-
-	return nil
+	return a.registerKeybindings()
 }
 
 // RemoveAudioFileKeybinding removes the keybinding for an audio file
@@ -612,17 +822,22 @@ func (a *App) RemoveAudioFileKeybinding(audioFile string) error {
 		return err
 	}
 
-	go a.reloadAudioFileKeybindings()
-
-	return nil
+	return a.registerKeybindings()
 }
 
-func (a *App) registerAudioFileKeybindings() error {
+// registerKeybindings registers the audio file and effect keybindings as hotkeys
+func (a *App) registerKeybindings() error {
 	audioFileKeybindings, err := a.ListAudioFileKeybindings()
 	if err != nil {
 		return err
 	}
 
+	effectKeybindings, err := a.ListEffectKeybindings()
+	if err != nil {
+		return err
+	}
+
+	bindings := make(map[string]func())
 	for audioFile, keybinding := range audioFileKeybindings {
 		if keybinding == "" {
 			continue
@@ -630,23 +845,58 @@ func (a *App) registerAudioFileKeybindings() error {
 
 		audioFile := audioFile
 
-		hook.Register(hook.KeyDown, strings.Split(strings.ToLower(keybinding), " + "), func(e hook.Event) {
-			if err := a.PlayAudioFile(audioFile); err != nil {
-				log.Fatal(err)
+		bindings[keybinding] = func() {
+			if err := a.actions.Run(ActionPlayAudioFile, audioFile, 1); err != nil {
+				log.Println(err)
+			}
+		}
+	}
+
+	for effect, keybinding := range effectKeybindings {
+		if keybinding == "" {
+			continue
+		}
+
+		effect := effect
+
+		bindings[keybinding] = func() {
+			if err := a.actions.Run(ActionToggleEffect, effect, 1); err != nil {
+				log.Println(err)
 			}
-		})
+		}
 	}
 
-	s := hook.Start()
-	<-hook.Process(s)
+	replayBufferSettings, err := a.GetReplayBufferSettings()
+	if err != nil {
+		return err
+	}
+
+	if replayBufferSettings.Enabled && replayBufferSettings.Keybinding != "" {
+		bindings[replayBufferSettings.Keybinding] = func() {
+			if err := a.actions.Run(ActionSaveReplay, "", 1); err != nil {
+				log.Println(err)
+			}
+		}
+	}
+
+	return a.hotkeys.SetBindings(bindings)
+}
+
+// BeginHotkeyCapture records the next key chords pressed anywhere on the system,
+// emitting each as a canonical keybinding through the "hotkeyCapture" event
+func (a *App) BeginHotkeyCapture() error {
+	a.hotkeys.BeginCapture(func(keybinding string) {
+		a.emit("hotkeyCapture", keybinding)
+	})
 
 	return nil
 }
 
-func (a *App) reloadAudioFileKeybindings() error {
-	hook.End()
+// EndHotkeyCapture stops recording key chords
+func (a *App) EndHotkeyCapture() error {
+	a.hotkeys.EndCapture()
 
-	return a.registerAudioFileKeybindings()
+	return nil
 }
 
 // ParseHexStringToDeviceID parses a hex string to a malgo.DeviceID
@@ -0,0 +1,74 @@
+package main
+
+import (
+	"context"
+	"math"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/adrg/xdg"
+)
+
+// newTestApp creates an App like NewApp does, storing its data in a temporary
+// folder and playing through the null audio backend
+func newTestApp(t *testing.T) *App {
+	t.Helper()
+
+	// Cleanups run last first, so this reloads after the variables are restored
+	t.Cleanup(xdg.Reload)
+	t.Setenv("XDG_DATA_HOME", t.TempDir())
+	t.Setenv("DISPLAY", "")
+	t.Setenv(audioBackendEnv, "null")
+	xdg.Reload()
+
+	a := NewApp()
+	a.headless = true
+
+	return a
+}
+
+func TestAppStartup(t *testing.T) {
+	a := newTestApp(t)
+
+	a.startup(context.Background())
+	defer a.shutdown(context.Background())
+
+	if err := a.SetMIDIBinding(MIDIBinding{Type: MIDIBindingNote, Number: 36, Action: ActionStopAll}); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := a.StopAllAudioFiles(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// writeTestWAV writes a sine tone to a WAV file in a temporary folder
+func writeTestWAV(t *testing.T, sampleRate uint32, channels int, seconds float64) string {
+	t.Helper()
+
+	filePath := filepath.Join(t.TempDir(), "tone.wav")
+	file, err := os.Create(filePath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer file.Close()
+
+	encoder, err := NewWAVEncoder(file, sampleRate, channels)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	samples := make([]float32, int(seconds*float64(sampleRate))*channels)
+	for i := range samples {
+		samples[i] = float32(0.5 * math.Sin(2*math.Pi*440*float64(i/channels)/float64(sampleRate)))
+	}
+	if err := encoder.Write(samples); err != nil {
+		t.Fatal(err)
+	}
+	if err := encoder.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	return filePath
+}
@@ -0,0 +1,161 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"runtime"
+
+	"github.com/gen2brain/malgo"
+)
+
+// audioBackendEnv overrides the audio backend setting, so that tests and
+// headless machines can run on the null backend without sound hardware
+const audioBackendEnv = "VARIOUS_YAM_AUDIO_BACKEND"
+
+// backendNull is miniaudio's null backend, which needs no sound hardware.
+// malgo.BackendNull is off by one, as malgo leaves out the custom backend that
+// comes before it.
+const backendNull = malgo.BackendNull + 1
+
+// audioBackendNames names the miniaudio backends
+var audioBackendNames = map[malgo.Backend]string{
+	malgo.BackendWasapi:     "wasapi",
+	malgo.BackendDsound:     "dsound",
+	malgo.BackendWinmm:      "winmm",
+	malgo.BackendCoreaudio:  "coreaudio",
+	malgo.BackendSndio:      "sndio",
+	malgo.BackendAudio4:     "audio4",
+	malgo.BackendOss:        "oss",
+	malgo.BackendPulseaudio: "pulseaudio",
+	malgo.BackendAlsa:       "alsa",
+	malgo.BackendJack:       "jack",
+	backendNull:             "null",
+}
+
+// platformAudioBackends lists the backends available on this platform in
+// miniaudio's order of preference. PipeWire is reached through its PulseAudio
+// and JACK compatibility.
+func platformAudioBackends() []malgo.Backend {
+	switch runtime.GOOS {
+	case "windows":
+		return []malgo.Backend{malgo.BackendWasapi, malgo.BackendDsound, malgo.BackendWinmm}
+	case "darwin":
+		return []malgo.Backend{malgo.BackendCoreaudio}
+	case "linux":
+		return []malgo.Backend{malgo.BackendPulseaudio, malgo.BackendAlsa, malgo.BackendJack}
+	default:
+		return []malgo.Backend{malgo.BackendSndio, malgo.BackendAudio4, malgo.BackendOss}
+	}
+}
+
+// initAudioContext initializes a context on the named backend, or on the first
+// backend that works if name is empty, returning the backend's name along with
+// it. Device IDs are only meaningful to the backend that listed them.
+func initAudioContext(name string) (*malgo.AllocatedContext, string, error) {
+	backends := platformAudioBackends()
+	if name != "" {
+		backends = nil
+		for backend, backendName := range audioBackendNames {
+			if backendName == name {
+				backends = []malgo.Backend{backend}
+			}
+		}
+		if backends == nil {
+			return nil, "", fmt.Errorf("unknown audio backend %q", name)
+		}
+	}
+
+	var errs []error
+	for _, backend := range backends {
+		ctx, err := malgo.InitContext([]malgo.Backend{backend}, malgo.ContextConfig{}, nil)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", audioBackendNames[backend], err))
+			continue
+		}
+
+		return ctx, audioBackendNames[backend], nil
+	}
+
+	return nil, "", errors.Join(errs...)
+}
+
+// ListAudioBackends lists the audio backends that can be selected on this platform
+func (a *App) ListAudioBackends() ([]string, error) {
+	var backends []string
+	for _, backend := range platformAudioBackends() {
+		backends = append(backends, audioBackendNames[backend])
+	}
+
+	return append(backends, audioBackendNames[backendNull]), nil
+}
+
+// GetAudioBackend gets the preferred audio backend, where an empty name picks
+// the first one that works
+func (a *App) GetAudioBackend() (string, error) {
+	serializedAudioBackend, _ := a.fs.GetItem("audioBackend")
+	if serializedAudioBackend == "" {
+		return "", nil
+	}
+
+	var audioBackend string
+	if err := json.Unmarshal([]byte(serializedAudioBackend), &audioBackend); err != nil {
+		return "", err
+	}
+
+	return audioBackend, nil
+}
+
+// SetAudioBackend sets the preferred audio backend and moves all audio onto it
+func (a *App) SetAudioBackend(audioBackend string) error {
+	serializedAudioBackend, err := json.Marshal(audioBackend)
+	if err != nil {
+		return err
+	}
+
+	if err := a.fs.SetItem("audioBackend", string(serializedAudioBackend)); err != nil {
+		return err
+	}
+
+	if err := a.restartAudioEngine(); err != nil {
+		return err
+	}
+
+	a.emitDevices()
+
+	return a.restartLoopbackAudio()
+}
+
+// GetActiveAudioBackend gets the backend of the shared context
+func (a *App) GetActiveAudioBackend() (string, error) {
+	return a.engine.Backend()
+}
+
+// startAudioEngine starts the engine on the preferred backend
+func (a *App) startAudioEngine() error {
+	audioBackend, err := a.GetAudioBackend()
+	if err != nil {
+		return err
+	}
+
+	if env := os.Getenv(audioBackendEnv); env != "" {
+		audioBackend = env
+	}
+
+	return a.engine.Start(audioBackend)
+}
+
+// restartAudioEngine stops the loopback and all voices, then restarts the
+// engine on the preferred backend
+func (a *App) restartAudioEngine() error {
+	// Holding loopbackMu keeps the device monitor from restarting the loopback
+	// on the old context in the meantime
+	a.loopbackMu.Lock()
+	defer a.loopbackMu.Unlock()
+
+	a.stopLoopbackAudio()
+	a.voices.StopAll()
+
+	return a.startAudioEngine()
+}
@@ -0,0 +1,152 @@
+package main
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"path/filepath"
+)
+
+// Recording formats
+const (
+	recordingFormatWAV  = "wav"
+	recordingFormatFLAC = "flac"
+)
+
+// AudioEncoder writes float samples to a file in some format. The header
+// carries the length of the audio, so it is only complete once closed.
+type AudioEncoder interface {
+	// Write encodes interleaved samples, which must be whole frames
+	Write(samples []float32) error
+	// Close writes what is left and completes the header
+	Close() error
+}
+
+// newAudioEncoder creates an encoder of the format writing to w
+func newAudioEncoder(w io.WriteSeeker, format string, sampleRate uint32, channels int) (AudioEncoder, error) {
+	switch format {
+	case recordingFormatWAV:
+		return NewWAVEncoder(w, sampleRate, channels)
+	case recordingFormatFLAC:
+		return NewFLACEncoder(w, sampleRate, channels)
+	default:
+		return nil, fmt.Errorf("unsupported recording format %q", format)
+	}
+}
+
+// createAudioFile creates a new file in path for writing, named name with the
+// extension, or with a counter after the name if that file exists already
+func createAudioFile(path string, name string, ext string) (*os.File, error) {
+	filePath := filepath.Join(path, name+"."+ext)
+	for i := 2; ; i++ {
+		file, err := os.OpenFile(filePath, os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0644)
+		if !errors.Is(err, os.ErrExist) {
+			return file, err
+		}
+		filePath = filepath.Join(path, fmt.Sprintf("%s-%d.%s", name, i, ext))
+	}
+}
+
+// quantizeSample converts a float sample to a 16-bit one, clipping it
+func quantizeSample(s float32) int16 {
+	return int16(math.Round(float64(max(-1, min(1, s))) * math.MaxInt16))
+}
+
+// wavHeaderSize is the size of a canonical WAV header
+const wavHeaderSize = 44
+
+// WAVEncoder writes 16-bit PCM WAV files
+type WAVEncoder struct {
+	w        io.WriteSeeker
+	buffered *bufio.Writer
+	channels int
+	dataSize uint32
+	buf      []byte
+}
+
+// NewWAVEncoder creates a new WAVEncoder, writing a header with no audio yet
+func NewWAVEncoder(w io.WriteSeeker, sampleRate uint32, channels int) (*WAVEncoder, error) {
+	e := &WAVEncoder{
+		w:        w,
+		buffered: bufio.NewWriter(w),
+		channels: channels,
+	}
+
+	header := make([]byte, wavHeaderSize)
+	copy(header[0:], "RIFF")
+	copy(header[8:], "WAVE")
+	copy(header[12:], "fmt ")
+	binary.LittleEndian.PutUint32(header[16:], 16)
+	binary.LittleEndian.PutUint16(header[20:], 1)
+	binary.LittleEndian.PutUint16(header[22:], uint16(channels))
+	binary.LittleEndian.PutUint32(header[24:], sampleRate)
+	binary.LittleEndian.PutUint32(header[28:], sampleRate*uint32(channels)*2)
+	binary.LittleEndian.PutUint16(header[32:], uint16(channels)*2)
+	binary.LittleEndian.PutUint16(header[34:], 16)
+	copy(header[36:], "data")
+	e.putSizes(header)
+
+	if _, err := e.buffered.Write(header); err != nil {
+		return nil, err
+	}
+
+	return e, nil
+}
+
+// putSizes puts the sizes of the RIFF and data chunks into the header
+func (e *WAVEncoder) putSizes(header []byte) {
+	binary.LittleEndian.PutUint32(header[4:], wavHeaderSize-8+e.dataSize)
+	binary.LittleEndian.PutUint32(header[40:], e.dataSize)
+}
+
+// Write implements AudioEncoder
+func (e *WAVEncoder) Write(samples []float32) error {
+	if math.MaxUint32-e.dataSize < uint32(len(samples)*2) {
+		return errRecordingTooLong
+	}
+
+	if cap(e.buf) < len(samples)*2 {
+		e.buf = make([]byte, len(samples)*2)
+	}
+	buf := e.buf[:len(samples)*2]
+	for i, s := range samples {
+		binary.LittleEndian.PutUint16(buf[i*2:], uint16(quantizeSample(s)))
+	}
+
+	n, err := e.buffered.Write(buf)
+	e.dataSize += uint32(n)
+
+	return err
+}
+
+// Close implements AudioEncoder
+func (e *WAVEncoder) Close() error {
+	if err := e.buffered.Flush(); err != nil {
+		return err
+	}
+
+	header := make([]byte, wavHeaderSize)
+	e.putSizes(header)
+
+	if _, err := e.w.Seek(4, io.SeekStart); err != nil {
+		return err
+	}
+	if _, err := e.w.Write(header[4:8]); err != nil {
+		return err
+	}
+
+	if _, err := e.w.Seek(40, io.SeekStart); err != nil {
+		return err
+	}
+	if _, err := e.w.Write(header[40:44]); err != nil {
+		return err
+	}
+
+	_, err := e.w.Seek(0, io.SeekEnd)
+
+	return err
+}
@@ -0,0 +1,193 @@
+package main
+
+import (
+	"errors"
+	"log"
+	"sync"
+	"sync/atomic"
+
+	"github.com/gen2brain/malgo"
+)
+
+// errNoAudioContext is returned while the engine has no context
+var errNoAudioContext = errors.New("no audio backend available")
+
+// AudioEngine owns the audio context shared by the whole application and
+// tracks the devices opened on it. The context is only replaced or released
+// once every device on it has been closed.
+type AudioEngine struct {
+	mu        sync.Mutex
+	released  *sync.Cond
+	context   *malgo.AllocatedContext
+	backend   string
+	users     int
+	replacing bool
+	contexts  atomic.Int64
+	devices   atomic.Int64
+}
+
+// AudioEngineStats counts the contexts and devices that are currently open,
+// and the users of the current context, which are devices and enumerations
+type AudioEngineStats struct {
+	Contexts int64 `json:"contexts"`
+	Devices  int64 `json:"devices"`
+	Users    int   `json:"users"`
+}
+
+// NewAudioEngine creates a new AudioEngine without a context
+func NewAudioEngine() *AudioEngine {
+	e := &AudioEngine{}
+	e.released = sync.NewCond(&e.mu)
+	return e
+}
+
+// Start replaces the context with one on the named backend, or on the first
+// backend that works if name is empty. It waits for open devices to close.
+func (e *AudioEngine) Start(name string) error {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	e.drain()
+	defer e.released.Broadcast()
+
+	ctx, backend, err := initAudioContext(name)
+	if err != nil {
+		return err
+	}
+	e.contexts.Add(1)
+
+	e.context = ctx
+	e.backend = backend
+
+	return nil
+}
+
+// Stop releases the context, waiting for open devices to close
+func (e *AudioEngine) Stop() {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	e.drain()
+	e.released.Broadcast()
+}
+
+// drain waits for every user of the context to release it, then releases the
+// context. e.mu must be held.
+func (e *AudioEngine) drain() {
+	// Keep new users out while waiting, or a busy engine would never drain
+	for e.replacing {
+		e.released.Wait()
+	}
+	e.replacing = true
+	for e.users > 0 {
+		e.released.Wait()
+	}
+	e.replacing = false
+
+	if e.context == nil {
+		return
+	}
+
+	if err := e.context.Uninit(); err != nil {
+		log.Println(err)
+	}
+	e.context.Free()
+	e.contexts.Add(-1)
+
+	e.context = nil
+	e.backend = ""
+}
+
+// acquire returns the context, which stays valid until release is called
+func (e *AudioEngine) acquire() (malgo.Context, string, error) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	for e.replacing {
+		e.released.Wait()
+	}
+
+	if e.context == nil {
+		return malgo.Context{}, "", errNoAudioContext
+	}
+	e.users++
+
+	return e.context.Context, e.backend, nil
+}
+
+func (e *AudioEngine) release() {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	e.users--
+	e.released.Broadcast()
+}
+
+// Backend returns the name of the context's backend
+func (e *AudioEngine) Backend() (string, error) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	if e.context == nil {
+		return "", errNoAudioContext
+	}
+
+	return e.backend, nil
+}
+
+// WithContext calls fn with the context, which stays valid until fn returns
+func (e *AudioEngine) WithContext(fn func(ctx malgo.Context, backend string) error) error {
+	ctx, backend, err := e.acquire()
+	if err != nil {
+		return err
+	}
+	defer e.release()
+
+	return fn(ctx, backend)
+}
+
+// OpenDevice initializes a device on the context, which stays valid until the
+// device is closed
+func (e *AudioEngine) OpenDevice(config malgo.DeviceConfig, callbacks malgo.DeviceCallbacks) (*EngineDevice, error) {
+	ctx, _, err := e.acquire()
+	if err != nil {
+		return nil, err
+	}
+
+	device, err := malgo.InitDevice(ctx, config, callbacks)
+	if err != nil {
+		e.release()
+		return nil, err
+	}
+	e.devices.Add(1)
+
+	return &EngineDevice{Device: device, engine: e}, nil
+}
+
+// Stats counts the contexts and devices that are currently open
+func (e *AudioEngine) Stats() AudioEngineStats {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	return AudioEngineStats{
+		Contexts: e.contexts.Load(),
+		Devices:  e.devices.Load(),
+		Users:    e.users,
+	}
+}
+
+// EngineDevice is a device opened by an AudioEngine
+type EngineDevice struct {
+	*malgo.Device
+	engine *AudioEngine
+	once   sync.Once
+}
+
+// Close uninitializes the device and lets the engine replace its context
+func (d *EngineDevice) Close() {
+	d.once.Do(func() {
+		d.Device.Uninit()
+		d.engine.devices.Add(-1)
+		d.engine.release()
+	})
+}
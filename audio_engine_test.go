@@ -0,0 +1,52 @@
+package main
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+// waitFor polls until done reports true, failing the test after a few seconds
+func waitFor(t *testing.T, what string, done func() bool) {
+	t.Helper()
+
+	for deadline := time.Now().Add(5 * time.Second); !done(); {
+		if time.Now().After(deadline) {
+			t.Fatalf("timed out waiting for %s", what)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func TestAudioEngineNoLeaks(t *testing.T) {
+	a := newTestApp(t)
+	a.startup(context.Background())
+
+	// The loopback holds a capture and a playback device on the one context
+	loopbackRunning := func() bool {
+		stats := a.engine.Stats()
+		return stats.Contexts == 1 && stats.Devices == 2 && stats.Users == 2
+	}
+	waitFor(t, "the loopback to start", loopbackRunning)
+
+	captureDevices, err := a.ListCaptureDevices()
+	if err != nil || len(captureDevices) == 0 {
+		t.Fatal(captureDevices, err)
+	}
+
+	for i := 0; i < 10; i++ {
+		if err := a.SetCaptureDeviceID(captureDevices[0].DeviceID); err != nil {
+			t.Fatal(err)
+		}
+		if err := a.SetCaptureDeviceID(""); err != nil {
+			t.Fatal(err)
+		}
+	}
+	waitFor(t, "the loopback to restart", loopbackRunning)
+
+	a.shutdown(context.Background())
+
+	if stats := a.engine.Stats(); stats != (AudioEngineStats{}) {
+		t.Errorf("%+v left open after shutdown", stats)
+	}
+}
@@ -0,0 +1,108 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+
+	"github.com/gen2brain/malgo"
+	"github.com/hajimehoshi/go-mp3"
+	"github.com/youpy/go-wav"
+)
+
+// audioStream is a decoded audio file ready to be read by a playback device
+type audioStream struct {
+	io.Reader
+	file       *os.File
+	format     malgo.FormatType
+	channels   uint32
+	sampleRate uint32
+}
+
+// openAudioStream opens an audio file and reads its format
+func openAudioStream(audioFile string) (*audioStream, error) {
+	file, err := os.Open(audioFile)
+	if err != nil {
+		return nil, err
+	}
+
+	stream := &audioStream{file: file}
+
+	switch filepath.Ext(audioFile) {
+	case ".wav":
+		w := wav.NewReader(file)
+		f, err := w.Format()
+		if err != nil {
+			file.Close()
+			return nil, err
+		}
+
+		switch f.AudioFormat {
+		case 1:
+			switch f.BitsPerSample {
+			case 8:
+				stream.format = malgo.FormatU8
+			case 16:
+				stream.format = malgo.FormatS16
+			case 24:
+				stream.format = malgo.FormatS24
+			case 32:
+				stream.format = malgo.FormatS32
+			default:
+				file.Close()
+				return nil, fmt.Errorf("unsupported bits per sample: %d", f.BitsPerSample)
+			}
+		case 3:
+			switch f.BitsPerSample {
+			case 32:
+				stream.format = malgo.FormatF32
+			default:
+				file.Close()
+				return nil, fmt.Errorf("unsupported bits per sample: %d", f.BitsPerSample)
+			}
+		default:
+			file.Close()
+			return nil, fmt.Errorf("unsupported audio format: %d", f.AudioFormat)
+		}
+
+		stream.channels = uint32(f.NumChannels)
+		stream.Reader = w
+		stream.sampleRate = f.SampleRate
+	case ".mp3":
+		m, err := mp3.NewDecoder(file)
+		if err != nil {
+			file.Close()
+			return nil, err
+		}
+
+		stream.format = malgo.FormatS16
+		stream.channels = 2
+		stream.Reader = m
+		stream.sampleRate = uint32(m.SampleRate())
+	case ".flac":
+		f, err := NewFLACDecoder(file)
+		if err != nil {
+			file.Close()
+			return nil, err
+		}
+
+		stream.format = malgo.FormatS16
+		if f.SampleSize() == 4 {
+			stream.format = malgo.FormatS32
+		}
+		stream.channels = uint32(f.Channels())
+		stream.Reader = f
+		stream.sampleRate = f.SampleRate()
+	default:
+		file.Close()
+		return nil, fmt.Errorf("unsupported audio file format: %s", filepath.Ext(audioFile))
+	}
+
+	return stream, nil
+}
+
+// Close closes the underlying file
+func (s *audioStream) Close() error {
+	return s.file.Close()
+}
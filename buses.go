@@ -0,0 +1,225 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"slices"
+)
+
+// Built-in buses. The main bus plays on the playback device with the mic, and
+// the monitor bus on the monitor device while it is enabled.
+const (
+	mainBus    = "main"
+	monitorBus = "monitor"
+)
+
+// OutputBus is a named output that plays the clips routed to it on a playback
+// device of its own, at its own volume and with the mic if IncludeMic is set
+type OutputBus struct {
+	Name       string  `json:"name"`
+	Volume     float64 `json:"volume"`
+	IncludeMic bool    `json:"includeMic"`
+}
+
+// BusDevice is the device an output bus is using, where an empty ID is the
+// default device. A fallback means the selected device is missing and another
+// device is used until it returns.
+type BusDevice struct {
+	Bus      string `json:"bus"`
+	DeviceID string `json:"deviceId"`
+	Fallback bool   `json:"fallback"`
+}
+
+// busDeviceKey is the key of an output bus's device preference
+func busDeviceKey(bus string) string {
+	return "outputBusDevice:" + bus
+}
+
+// ListOutputBuses lists the output buses besides the main and monitor buses
+func (a *App) ListOutputBuses() ([]OutputBus, error) {
+	serializedOutputBuses, _ := a.fs.GetItem("outputBuses")
+	if serializedOutputBuses == "" {
+		return []OutputBus{}, nil
+	}
+
+	var outputBuses []OutputBus
+	if err := json.Unmarshal([]byte(serializedOutputBuses), &outputBuses); err != nil {
+		return nil, err
+	}
+
+	return outputBuses, nil
+}
+
+// updateOutputBuses updates the output buses with callback and hands them to
+// the mixer. Adding or removing a bus restarts the loopback.
+func (a *App) updateOutputBuses(callback func([]OutputBus) ([]OutputBus, error)) error {
+	var restart bool
+	if err := a.fs.UpdateItem("outputBuses", func(value string) (string, error) {
+		outputBuses := []OutputBus{}
+		if value != "" {
+			if err := json.Unmarshal([]byte(value), &outputBuses); err != nil {
+				return "", err
+			}
+		}
+
+		updated, err := callback(slices.Clone(outputBuses))
+		if err != nil {
+			return "", err
+		}
+		restart = len(updated) != len(outputBuses)
+
+		serializedOutputBuses, err := json.Marshal(updated)
+		if err != nil {
+			return "", err
+		}
+
+		return string(serializedOutputBuses), nil
+	}); err != nil {
+		return err
+	}
+
+	if err := a.applyOutputBuses(); err != nil {
+		return err
+	}
+
+	if restart {
+		return a.restartLoopbackAudio()
+	}
+
+	return nil
+}
+
+// SetOutputBus adds an output bus, or updates the one with the same name
+func (a *App) SetOutputBus(outputBus OutputBus) error {
+	if outputBus.Name == "" {
+		return errors.New("output buses need a name")
+	}
+
+	if outputBus.Name == mainBus || outputBus.Name == monitorBus {
+		return fmt.Errorf("the %s bus is built in", outputBus.Name)
+	}
+
+	if outputBus.Name == micTap {
+		return errors.New("output buses cannot be named after the mic")
+	}
+
+	outputBus.Volume = math.Max(0, math.Min(1, outputBus.Volume))
+
+	return a.updateOutputBuses(func(outputBuses []OutputBus) ([]OutputBus, error) {
+		for i, bus := range outputBuses {
+			if bus.Name == outputBus.Name {
+				outputBuses[i] = outputBus
+				return outputBuses, nil
+			}
+		}
+
+		return append(outputBuses, outputBus), nil
+	})
+}
+
+// RemoveOutputBus removes an output bus along with its device. Clips routed
+// to it are left on their other buses.
+func (a *App) RemoveOutputBus(name string) error {
+	if err := a.updateOutputBuses(func(outputBuses []OutputBus) ([]OutputBus, error) {
+		index := slices.IndexFunc(outputBuses, func(bus OutputBus) bool {
+			return bus.Name == name
+		})
+		if index < 0 {
+			return nil, fmt.Errorf("no output bus named %q", name)
+		}
+
+		return slices.Delete(outputBuses, index, index+1), nil
+	}); err != nil {
+		return err
+	}
+
+	return a.fs.RemoveItem(busDeviceKey(name))
+}
+
+// GetOutputBusDeviceID gets the current ID of the selected device of an
+// output bus
+func (a *App) GetOutputBusDeviceID(name string) (string, error) {
+	return a.getDeviceID(busDeviceKey(name), busDeviceKey(name)+"ID", "audiooutput")
+}
+
+// SetOutputBusDeviceID selects the device of an output bus by ID, remembering
+// it by name too
+func (a *App) SetOutputBusDeviceID(name string, deviceID string) error {
+	outputBuses, err := a.ListOutputBuses()
+	if err != nil {
+		return err
+	}
+
+	if !slices.ContainsFunc(outputBuses, func(bus OutputBus) bool {
+		return bus.Name == name
+	}) {
+		return fmt.Errorf("no output bus named %q", name)
+	}
+
+	return a.setDeviceID(busDeviceKey(name), busDeviceKey(name)+"ID", "audiooutput", deviceID)
+}
+
+// applyOutputBuses hands the output buses, including the monitor, to the mixer
+func (a *App) applyOutputBuses() error {
+	outputBuses, err := a.ListOutputBuses()
+	if err != nil {
+		return err
+	}
+
+	monitorSettings, err := a.GetMonitorSettings()
+	if err != nil {
+		return err
+	}
+
+	a.mixer.SetBuses(append(outputBuses, OutputBus{
+		Name:       monitorBus,
+		Volume:     monitorSettings.Volume,
+		IncludeMic: monitorSettings.IncludeMic,
+	}))
+
+	return nil
+}
+
+// ListAudioFileBuses lists the buses audio files are routed to. Audio files
+// that are not listed play on every bus.
+func (a *App) ListAudioFileBuses() (map[string][]string, error) {
+	serializedAudioFileBuses, _ := a.fs.GetItem("audioFileBuses")
+	if serializedAudioFileBuses == "" {
+		return map[string][]string{}, nil
+	}
+
+	var audioFileBuses map[string][]string
+	if err := json.Unmarshal([]byte(serializedAudioFileBuses), &audioFileBuses); err != nil {
+		return nil, err
+	}
+
+	return audioFileBuses, nil
+}
+
+// SetAudioFileBuses routes an audio file to the named buses, or to every bus
+// if there are none
+func (a *App) SetAudioFileBuses(audioFile string, buses []string) error {
+	return a.fs.UpdateItem("audioFileBuses", func(value string) (string, error) {
+		audioFileBuses := make(map[string][]string)
+		if value != "" {
+			if err := json.Unmarshal([]byte(value), &audioFileBuses); err != nil {
+				return "", err
+			}
+		}
+
+		if len(buses) > 0 {
+			audioFileBuses[audioFile] = buses
+		} else {
+			delete(audioFileBuses, audioFile)
+		}
+
+		serializedAudioFileBuses, err := json.Marshal(audioFileBuses)
+		if err != nil {
+			return "", err
+		}
+
+		return string(serializedAudioFileBuses), nil
+	})
+}
@@ -0,0 +1,106 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"syscall"
+	"text/tabwriter"
+)
+
+const cliUsage = `Usage:
+  various-yam                    start the soundboard
+  various-yam daemon             run the soundboard without a window
+  various-yam play <clip>        play a clip by ID, path or file name
+  various-yam stop <clip>        stop a clip
+  various-yam stop-all           stop all clips
+  various-yam clips list         list clips
+  various-yam clips add <file>   add an audio file as a clip
+  various-yam devices list       list audio devices
+`
+
+// runCLI runs the subcommand named by args, reporting false when args do not
+// name one so that the window starts instead
+func runCLI(args []string) (bool, error) {
+	if len(args) == 0 {
+		return false, nil
+	}
+
+	switch args[0] {
+	case "daemon":
+		return true, runDaemon()
+	case "play", "stop":
+		if len(args) != 2 {
+			return true, errors.New(cliUsage)
+		}
+		return true, sendControlRequest(ControlRequest{Command: args[0], Args: args[1:]}, nil)
+	case "stop-all":
+		return true, sendControlRequest(ControlRequest{Command: "stop-all"}, nil)
+	case "clips":
+		switch {
+		case len(args) == 2 && args[1] == "list":
+			var clips []Clip
+			if err := sendControlRequest(ControlRequest{Command: "clips"}, &clips); err != nil {
+				return true, err
+			}
+
+			w := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+			for _, clip := range clips {
+				fmt.Fprintf(w, "%d\t%s\t%s\n", clip.ID, clip.State, clip.AudioFile)
+			}
+			return true, w.Flush()
+		case len(args) == 3 && args[1] == "add":
+			audioFile, err := filepath.Abs(args[2])
+			if err != nil {
+				return true, err
+			}
+			return true, sendControlRequest(ControlRequest{Command: "add", Args: []string{audioFile}}, nil)
+		}
+		return true, errors.New(cliUsage)
+	case "devices":
+		if len(args) != 2 || args[1] != "list" {
+			return true, errors.New(cliUsage)
+		}
+
+		var devices []MediaDeviceInfo
+		if err := sendControlRequest(ControlRequest{Command: "devices"}, &devices); err != nil {
+			return true, err
+		}
+
+		w := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+		for _, device := range devices {
+			fmt.Fprintf(w, "%s\t%s\t%s\n", device.Kind, device.DeviceID, device.Label)
+		}
+		return true, w.Flush()
+	case "help", "-h", "--help":
+		fmt.Print(cliUsage)
+		return true, nil
+	}
+
+	return false, nil
+}
+
+// runDaemon runs the audio engine, hotkeys and storage without a window until
+// interrupted
+func runDaemon() error {
+	control, err := listenControl()
+	if err != nil {
+		return err
+	}
+
+	app := NewApp()
+	app.headless = true
+	app.control = control
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	app.startup(ctx)
+	<-ctx.Done()
+	app.shutdown(context.Background())
+
+	return nil
+}
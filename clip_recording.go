@@ -0,0 +1,150 @@
+package main
+
+import (
+	"errors"
+	"math"
+	"time"
+)
+
+// clipRecordingMaxDuration is how long a clip recording goes on for at most
+const clipRecordingMaxDuration = time.Minute
+
+// clipRecordingLevelInterval is how often the level is reported while
+// recording a clip
+const clipRecordingLevelInterval = 50 * time.Millisecond
+
+// Silence is trimmed off both ends of a recorded clip up to where the level
+// first and last rises above the threshold, keeping some padding
+const (
+	clipSilenceThresholdDB     = -45
+	clipSilencePadMilliseconds = 100
+)
+
+var errRecordingClip = errors.New("already recording a clip")
+
+// ClipRecordingLevel reports the level of a clip being recorded, as the peak
+// since the last report, along with how long it has been recording
+type ClipRecordingLevel struct {
+	PeakDB  float64 `json:"peakDb"`
+	Seconds float64 `json:"seconds"`
+}
+
+// RecordClip records the mic from the capture device the loopback is using,
+// as it sounds after noise suppression and effects, until StopClipRecording
+// is called or a minute has passed. The silence at either end is trimmed off
+// and the clip is saved to the clip library and added to the audio files.
+// The level is reported through "clipRecordingLevel" events as it goes.
+func (a *App) RecordClip() (string, error) {
+	a.loopbackMu.Lock()
+	running := a.loopbackDone != nil
+	a.loopbackMu.Unlock()
+
+	if !running {
+		return "", errors.New("the loopback is not running")
+	}
+
+	loopbackSettings, err := a.GetLoopbackSettings()
+	if err != nil {
+		return "", err
+	}
+
+	a.clipRecordingMu.Lock()
+	if a.stopClipRecording != nil {
+		a.clipRecordingMu.Unlock()
+		return "", errRecordingClip
+	}
+	stop := make(chan struct{})
+	a.stopClipRecording = stop
+	a.clipRecordingMu.Unlock()
+
+	defer func() {
+		a.clipRecordingMu.Lock()
+		a.stopClipRecording = nil
+		a.clipRecordingMu.Unlock()
+	}()
+
+	sampleRate, channels := loopbackSettings.SampleRate, int(loopbackSettings.Channels)
+	tap := NewMixerTap(micTap, sampleRate, channels, int(sampleRate)*channels)
+	a.mixer.AddTap(tap)
+	defer a.mixer.RemoveTap(tap)
+
+	maxSamples := int(clipRecordingMaxDuration.Seconds()) * int(sampleRate) * channels
+	samples := make([]float32, 0, maxSamples)
+	chunk := make([]float32, tap.queue.Cap())
+
+	ticker := time.NewTicker(clipRecordingLevelInterval)
+	defer ticker.Stop()
+
+	timeout := time.After(clipRecordingMaxDuration)
+	for recording := true; recording; {
+		select {
+		case <-stop:
+			recording = false
+		case <-timeout:
+			recording = false
+		case <-ticker.C:
+		}
+
+		n := tap.Read(chunk[:min(len(chunk), maxSamples-len(samples))])
+		samples = append(samples, chunk[:n-n%channels]...)
+
+		a.emit("clipRecordingLevel", ClipRecordingLevel{
+			PeakDB:  math.Round(linearToDB(framePeak(chunk[:n]))*10) / 10,
+			Seconds: float64(len(samples)/channels) / float64(sampleRate),
+		})
+	}
+
+	samples = trimSilence(samples, sampleRate, channels)
+	if len(samples) == 0 {
+		return "", errors.New("nothing was heard while recording the clip")
+	}
+
+	filePath, err := saveClip("clip", samples, sampleRate, channels)
+	if err != nil {
+		return "", err
+	}
+
+	return filePath, a.AddAudioFile(filePath)
+}
+
+// StopClipRecording stops recording the clip, which RecordClip then saves
+func (a *App) StopClipRecording() error {
+	a.clipRecordingMu.Lock()
+	defer a.clipRecordingMu.Unlock()
+
+	if a.stopClipRecording == nil {
+		return errors.New("not recording a clip")
+	}
+
+	select {
+	case <-a.stopClipRecording:
+	default:
+		close(a.stopClipRecording)
+	}
+
+	return nil
+}
+
+// trimSilence cuts interleaved samples down to where their level is above
+// the silence threshold, keeping a little padding on either side, and to
+// nothing if it never is
+func trimSilence(samples []float32, sampleRate uint32, channels int) []float32 {
+	threshold := dbToLinear(clipSilenceThresholdDB)
+
+	first, last := -1, -1
+	for i := 0; i+channels <= len(samples); i += channels {
+		if framePeak(samples[i:i+channels]) >= threshold {
+			if first < 0 {
+				first = i
+			}
+			last = i + channels
+		}
+	}
+	if first < 0 {
+		return samples[:0]
+	}
+
+	pad := int(sampleRate) * clipSilencePadMilliseconds / 1000 * channels
+
+	return samples[max(first-pad, 0):min(last+pad, len(samples))]
+}
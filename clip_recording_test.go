@@ -0,0 +1,62 @@
+package main
+
+import "testing"
+
+func TestTrimSilence(t *testing.T) {
+	// At 1 kHz the padding is 100 frames
+	const sampleRate = 1000
+
+	tests := []struct {
+		name          string
+		channels      int
+		frames        int
+		from, to      int
+		level         float32
+		rightOnly     bool
+		wantFrom      int
+		wantTo        int
+		wantNoSamples bool
+	}{
+		{"silence at the start", 1, 1000, 300, 1000, 0.5, false, 200, 1000, false},
+		{"silence at the end", 1, 1000, 0, 600, 0.5, false, 0, 700, false},
+		{"silence at both ends", 1, 1000, 300, 600, 0.5, false, 200, 700, false},
+		{"padding past the ends", 1, 1000, 50, 950, 0.5, false, 0, 1000, false},
+		{"no silence", 1, 1000, 0, 1000, 0.5, false, 0, 1000, false},
+		{"all silence", 1, 1000, 0, 0, 0, false, 0, 0, true},
+		{"all below the threshold", 1, 1000, 0, 1000, 0.001, false, 0, 0, true},
+		{"stereo", 2, 1000, 300, 600, 0.5, false, 200, 700, false},
+		{"stereo with one channel", 2, 1000, 300, 600, 0.5, true, 200, 700, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			samples := make([]float32, tt.frames*tt.channels)
+			for frame := tt.from; frame < tt.to; frame++ {
+				if tt.rightOnly {
+					samples[frame*tt.channels+1] = tt.level
+					continue
+				}
+				for c := 0; c < tt.channels; c++ {
+					samples[frame*tt.channels+c] = tt.level
+				}
+			}
+
+			trimmed := trimSilence(samples, sampleRate, tt.channels)
+
+			if tt.wantNoSamples {
+				if len(trimmed) != 0 {
+					t.Errorf("kept %d samples, want none", len(trimmed))
+				}
+				return
+			}
+
+			// The trimmed samples share the array, so the capacity they lost
+			// is where they start
+			from := (cap(samples) - cap(trimmed)) / tt.channels
+			to := from + len(trimmed)/tt.channels
+			if from != tt.wantFrom || to != tt.wantTo || len(trimmed)%tt.channels != 0 {
+				t.Errorf("kept %d samples from frame %d to %d, want frames %d to %d", len(trimmed), from, to, tt.wantFrom, tt.wantTo)
+			}
+		})
+	}
+}
@@ -0,0 +1,247 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/adrg/xdg"
+	"github.com/wailsapp/wails/v2/pkg/runtime"
+)
+
+// ControlRequest is a command sent to a running instance over the control socket
+type ControlRequest struct {
+	Command string   `json:"command"`
+	Args    []string `json:"args"`
+}
+
+// ControlResponse is the reply to a ControlRequest
+type ControlResponse struct {
+	Result json.RawMessage `json:"result,omitempty"`
+	Error  string          `json:"error,omitempty"`
+}
+
+// controlSocketPath returns the path of the control socket in the XDG runtime directory
+func controlSocketPath() string {
+	return filepath.Join(xdg.RuntimeDir, "various-yam.sock")
+}
+
+// errAlreadyRunning is returned by listenControl when another instance owns the control socket
+var errAlreadyRunning = errors.New("another instance is already running")
+
+// listenControl claims the control socket for this instance, replacing a
+// socket left behind by an instance that is no longer running
+func listenControl() (net.Listener, error) {
+	path := controlSocketPath()
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return nil, err
+	}
+
+	// The lock keeps two instances starting at once from both replacing the socket
+	if err := lockInstance(path + ".lock"); err != nil {
+		return nil, err
+	}
+
+	if conn, err := net.Dial("unix", path); err == nil {
+		conn.Close()
+		return nil, errAlreadyRunning
+	}
+	os.Remove(path)
+
+	listener, err := net.Listen("unix", path)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := os.Chmod(path, 0600); err != nil {
+		listener.Close()
+		return nil, err
+	}
+
+	return listener, nil
+}
+
+// launchRequests turns the arguments of a window launch into requests that add
+// each audio file as a clip
+func launchRequests(args []string) []ControlRequest {
+	var requests []ControlRequest
+	for _, arg := range args {
+		// Skip flags such as the process serial number macOS passes
+		if strings.HasPrefix(arg, "-") {
+			continue
+		}
+
+		audioFile, err := filepath.Abs(arg)
+		if err != nil {
+			continue
+		}
+
+		if info, err := os.Stat(audioFile); err != nil || info.IsDir() {
+			continue
+		}
+
+		requests = append(requests, ControlRequest{Command: "add", Args: []string{audioFile}})
+	}
+
+	return requests
+}
+
+// sendControlRequest sends a command to the running instance and decodes its result into result
+func sendControlRequest(request ControlRequest, result any) error {
+	conn, err := net.Dial("unix", controlSocketPath())
+	if err != nil {
+		return fmt.Errorf("no running instance: %w", err)
+	}
+	defer conn.Close()
+
+	if err := json.NewEncoder(conn).Encode(request); err != nil {
+		return err
+	}
+
+	var response ControlResponse
+	if err := json.NewDecoder(conn).Decode(&response); err != nil {
+		return err
+	}
+
+	if response.Error != "" {
+		return errors.New(response.Error)
+	}
+
+	if result == nil || response.Result == nil {
+		return nil
+	}
+
+	return json.Unmarshal(response.Result, result)
+}
+
+// serveControl serves commands from the CLI and later launches on the control
+// socket claimed by listenControl
+func (a *App) serveControl() {
+	listener := a.control
+	for {
+		conn, err := listener.Accept()
+		if err != nil {
+			if !errors.Is(err, net.ErrClosed) {
+				log.Println(err)
+			}
+			return
+		}
+
+		go a.serveControlConn(conn)
+	}
+}
+
+// stopControl closes the control socket
+func (a *App) stopControl() {
+	if a.control == nil {
+		return
+	}
+
+	// Closing a Unix listener also removes its socket file
+	if err := a.control.Close(); err != nil {
+		log.Println(err)
+	}
+	a.control = nil
+}
+
+func (a *App) serveControlConn(conn net.Conn) {
+	defer conn.Close()
+
+	scanner := bufio.NewScanner(conn)
+	encoder := json.NewEncoder(conn)
+	for scanner.Scan() {
+		var response ControlResponse
+
+		var request ControlRequest
+		if err := json.Unmarshal(scanner.Bytes(), &request); err != nil {
+			response.Error = err.Error()
+		} else if result, err := a.handleControlRequest(request); err != nil {
+			response.Error = err.Error()
+		} else if result != nil {
+			if response.Result, err = json.Marshal(result); err != nil {
+				response.Error = err.Error()
+			}
+		}
+
+		if err := encoder.Encode(response); err != nil {
+			log.Println(err)
+			return
+		}
+	}
+}
+
+// handleControlRequest runs a command from the CLI
+func (a *App) handleControlRequest(request ControlRequest) (any, error) {
+	arg := func(i int) (string, error) {
+		if i >= len(request.Args) {
+			return "", fmt.Errorf("%s: missing argument", request.Command)
+		}
+		return request.Args[i], nil
+	}
+
+	switch request.Command {
+	case "play", "stop":
+		clip, err := arg(0)
+		if err != nil {
+			return nil, err
+		}
+
+		audioFile, err := a.resolveClip(clip)
+		if err != nil {
+			return nil, err
+		}
+
+		if request.Command == "play" {
+			return nil, a.actions.Run(ActionPlayAudioFile, audioFile, 1)
+		}
+		return nil, a.actions.Run(ActionStopAudioFile, audioFile, 1)
+	case "stop-all":
+		return nil, a.actions.Run(ActionStopAll, "", 1)
+	case "devices":
+		captureDevices, err := a.ListCaptureDevices()
+		if err != nil {
+			return nil, err
+		}
+
+		playbackDevices, err := a.ListPlaybackDevices()
+		if err != nil {
+			return nil, err
+		}
+
+		return append(captureDevices, playbackDevices...), nil
+	case "clips":
+		return a.ListClips()
+	case "add":
+		audioFile, err := arg(0)
+		if err != nil {
+			return nil, err
+		}
+
+		audioFiles, err := a.ListAudioFiles()
+		if err != nil {
+			return nil, err
+		}
+
+		if slices.Contains(audioFiles, audioFile) {
+			return nil, nil
+		}
+
+		return nil, a.AddAudioFile(audioFile)
+	case "show":
+		if !a.headless {
+			runtime.WindowUnminimise(a.ctx)
+			runtime.WindowShow(a.ctx)
+		}
+
+		return nil, nil
+	default:
+		return nil, fmt.Errorf("unknown command %q", request.Command)
+	}
+}
@@ -0,0 +1,311 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"log"
+	"slices"
+	"time"
+
+	"github.com/gen2brain/malgo"
+)
+
+// deviceMonitorInterval is how often the device monitor enumerates devices
+const deviceMonitorInterval = 2 * time.Second
+
+// errDeviceStopped is returned by loopbackAudio when a device stops on its
+// own, usually because it was unplugged
+var errDeviceStopped = errors.New("audio device stopped")
+
+// DeviceMonitor enumerates audio devices periodically, and on demand when a
+// device stops unexpectedly
+type DeviceMonitor struct {
+	list      func() ([]MediaDeviceInfo, error)
+	onDevices func(devices []MediaDeviceInfo, changed bool)
+	poke      chan struct{}
+}
+
+// NewDeviceMonitor creates a new DeviceMonitor that passes every enumeration to
+// onDevices, along with whether it differs from the previous one
+func NewDeviceMonitor(list func() ([]MediaDeviceInfo, error), onDevices func(devices []MediaDeviceInfo, changed bool)) *DeviceMonitor {
+	return &DeviceMonitor{
+		list:      list,
+		onDevices: onDevices,
+		poke:      make(chan struct{}, 1),
+	}
+}
+
+// Poke asks the monitor to enumerate devices now
+func (m *DeviceMonitor) Poke() {
+	select {
+	case m.poke <- struct{}{}:
+	default:
+	}
+}
+
+// Run enumerates devices until ctx is done
+func (m *DeviceMonitor) Run(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	var previous []MediaDeviceInfo
+	first := true
+	for {
+		devices, err := m.list()
+		if err != nil {
+			log.Println(err)
+		} else {
+			changed := first || !slices.EqualFunc(devices, previous, sameDevice)
+			first = false
+			previous = devices
+
+			m.onDevices(devices, changed)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		case <-m.poke:
+		}
+	}
+}
+
+func sameDevice(a, b MediaDeviceInfo) bool {
+	return a.Kind == b.Kind && a.DeviceID == b.DeviceID && a.Label == b.Label
+}
+
+// filterDevices returns the devices of the given kind
+func filterDevices(devices []MediaDeviceInfo, kind string) []MediaDeviceInfo {
+	filtered := []MediaDeviceInfo{}
+	for _, device := range devices {
+		if device.Kind == kind {
+			filtered = append(filtered, device)
+		}
+	}
+	return filtered
+}
+
+// LoopbackDevices describes the devices the loopback is using, where an empty
+// ID is the default device. A fallback means the selected device is missing
+// and another device is used until it returns. Buses lists the devices of the
+// output buses besides the main bus, including the monitor while enabled.
+type LoopbackDevices struct {
+	CaptureDeviceID  string      `json:"captureDeviceId"`
+	PlaybackDeviceID string      `json:"playbackDeviceId"`
+	CaptureFallback  bool        `json:"captureFallback"`
+	PlaybackFallback bool        `json:"playbackFallback"`
+	Buses            []BusDevice `json:"buses"`
+
+	// captureDevice identifies the capture device beyond its ID, for the
+	// settings that have to follow it across reboots
+	captureDevice DeviceIdentity
+}
+
+// equal reports whether both describe the same devices
+func (d LoopbackDevices) equal(other LoopbackDevices) bool {
+	return d.CaptureDeviceID == other.CaptureDeviceID &&
+		d.PlaybackDeviceID == other.PlaybackDeviceID &&
+		d.CaptureFallback == other.CaptureFallback &&
+		d.PlaybackFallback == other.PlaybackFallback &&
+		slices.Equal(d.Buses, other.Buses)
+}
+
+// GetLoopbackDevices gets the devices the loopback is using
+func (a *App) GetLoopbackDevices() (LoopbackDevices, error) {
+	a.loopbackMu.Lock()
+	defer a.loopbackMu.Unlock()
+
+	return a.loopbackDevices, nil
+}
+
+// listDevices lists all capture and playback devices without their capabilities
+func (a *App) listDevices() ([]MediaDeviceInfo, error) {
+	captureDevices, err := a.listMediaDevices(malgo.Capture, false)
+	if err != nil {
+		return nil, err
+	}
+
+	playbackDevices, err := a.listMediaDevices(malgo.Playback, false)
+	if err != nil {
+		return nil, err
+	}
+
+	return append(captureDevices, playbackDevices...), nil
+}
+
+// startDeviceMonitor watches for added and removed devices, emitting the new
+// device lists and keeping the loopback on available devices
+func (a *App) startDeviceMonitor() {
+	ctx, cancel := context.WithCancel(a.ctx)
+	a.cancelDeviceMonitor = cancel
+
+	a.deviceMonitor = NewDeviceMonitor(a.listDevices, func(devices []MediaDeviceInfo, changed bool) {
+		if changed {
+			a.emitDevices()
+		}
+
+		a.reconcileLoopbackAudio(devices)
+	})
+
+	go a.deviceMonitor.Run(ctx, deviceMonitorInterval)
+}
+
+// emitDevices sends the device lists, with their capabilities, to the frontend
+func (a *App) emitDevices() {
+	if a.headless {
+		return
+	}
+
+	if captureDevices, err := a.ListCaptureDevices(); err != nil {
+		log.Println(err)
+	} else {
+		a.emit("captureDevices", captureDevices)
+	}
+
+	if playbackDevices, err := a.ListPlaybackDevices(); err != nil {
+		log.Println(err)
+	} else {
+		a.emit("playbackDevices", playbackDevices)
+	}
+}
+
+// restartLoopbackAudio moves the loopback onto the selected devices
+func (a *App) restartLoopbackAudio() error {
+	devices, err := a.listDevices()
+	if err != nil {
+		return err
+	}
+
+	a.reconcileLoopbackAudio(devices)
+
+	return nil
+}
+
+// reconcileLoopbackAudio restarts the loopback if it stopped or if it should
+// use different devices, falling back to a previously selected device or the
+// default device while the selected device is missing. It plays to the virtual
+// microphone's sink instead of the playback device while that is enabled.
+func (a *App) reconcileLoopbackAudio(devices []MediaDeviceInfo) {
+	var loopbackDevices LoopbackDevices
+
+	capturePreference, err := a.getDevicePreference("captureDevice", "captureDeviceID")
+	if err != nil {
+		log.Println(err)
+	}
+	if device, selected, ok := capturePreference.Match(filterDevices(devices, "audioinput")); ok {
+		loopbackDevices.CaptureDeviceID = device.DeviceID
+		loopbackDevices.CaptureFallback = !selected
+		loopbackDevices.captureDevice = DeviceIdentity{Backend: device.Backend, DeviceID: device.DeviceID, Name: device.Label}
+	} else {
+		loopbackDevices.CaptureFallback = capturePreference.DeviceID != ""
+	}
+
+	playbackPreference, err := a.getDevicePreference("playbackDevice", "playbackDeviceID")
+	if err != nil {
+		log.Println(err)
+	}
+	if device, ok := a.virtualMicSink(devices); ok {
+		loopbackDevices.PlaybackDeviceID = device.DeviceID
+	} else if device, selected, ok := playbackPreference.Match(filterDevices(devices, "audiooutput")); ok {
+		loopbackDevices.PlaybackDeviceID = device.DeviceID
+		loopbackDevices.PlaybackFallback = !selected
+	} else {
+		loopbackDevices.PlaybackFallback = playbackPreference.DeviceID != ""
+	}
+
+	monitorSettings, err := a.GetMonitorSettings()
+	if err != nil {
+		log.Println(err)
+	}
+	if monitorSettings.Enabled {
+		loopbackDevices.Buses = append(loopbackDevices.Buses, a.matchBusDevice(monitorBus, "monitorDevice", "monitorDeviceID", devices))
+	}
+
+	outputBuses, err := a.ListOutputBuses()
+	if err != nil {
+		log.Println(err)
+	}
+	for _, bus := range outputBuses {
+		loopbackDevices.Buses = append(loopbackDevices.Buses, a.matchBusDevice(bus.Name, busDeviceKey(bus.Name), busDeviceKey(bus.Name)+"ID", devices))
+	}
+
+	a.loopbackMu.Lock()
+	defer a.loopbackMu.Unlock()
+
+	if a.loopbackDone != nil && loopbackDevices.equal(a.loopbackDevices) {
+		return
+	}
+
+	a.startLoopbackAudio(loopbackDevices)
+}
+
+// matchBusDevice finds the device of an output bus from its preference
+func (a *App) matchBusDevice(bus string, key string, legacyKey string, devices []MediaDeviceInfo) BusDevice {
+	busDevice := BusDevice{Bus: bus}
+
+	preference, err := a.getDevicePreference(key, legacyKey)
+	if err != nil {
+		log.Println(err)
+	}
+	if device, selected, ok := preference.Match(filterDevices(devices, "audiooutput")); ok {
+		busDevice.DeviceID = device.DeviceID
+		busDevice.Fallback = !selected
+	} else {
+		busDevice.Fallback = preference.DeviceID != ""
+	}
+
+	return busDevice
+}
+
+// startLoopbackAudio stops the loopback and starts it on the given devices.
+// loopbackMu must be held.
+func (a *App) startLoopbackAudio(loopbackDevices LoopbackDevices) {
+	a.stopLoopbackAudio()
+
+	ctx, cancel := context.WithCancel(a.ctx)
+	done := make(chan struct{})
+	a.cancelLoopbackAudio = cancel
+	a.loopbackDone = done
+
+	if !loopbackDevices.equal(a.loopbackDevices) {
+		a.loopbackDevices = loopbackDevices
+		a.emit("loopbackDevices", loopbackDevices)
+	}
+
+	go func() {
+		err := a.loopbackAudio(ctx, loopbackDevices)
+		if err != nil {
+			log.Println(err)
+		}
+		close(done)
+
+		a.loopbackMu.Lock()
+		defer a.loopbackMu.Unlock()
+
+		// Unless it was stopped on purpose, the next enumeration restarts it
+		if ctx.Err() == nil {
+			a.cancelLoopbackAudio = nil
+			a.loopbackDone = nil
+			cancel()
+
+			if errors.Is(err, errDeviceStopped) && a.deviceMonitor != nil {
+				a.deviceMonitor.Poke()
+			}
+		}
+	}()
+}
+
+// stopLoopbackAudio stops the loopback and waits for it to release its
+// devices. loopbackMu must be held.
+func (a *App) stopLoopbackAudio() {
+	if a.cancelLoopbackAudio == nil {
+		return
+	}
+
+	a.cancelLoopbackAudio()
+	<-a.loopbackDone
+
+	a.cancelLoopbackAudio = nil
+	a.loopbackDone = nil
+}
@@ -0,0 +1,244 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+)
+
+// maxDeviceFallbacks limits how many previously selected devices are remembered
+const maxDeviceFallbacks = 4
+
+// DeviceIdentity identifies a device across restarts. Backend IDs such as
+// ALSA card numbers can change between reboots and USB ports, so a device is
+// matched by ID first and then by name.
+type DeviceIdentity struct {
+	Backend  string `json:"backend"`
+	DeviceID string `json:"deviceId"`
+	Name     string `json:"name"`
+}
+
+// DevicePreference is the selected device along with the previously selected
+// devices to use, in order, while it is missing. An empty device ID selects
+// the default device.
+type DevicePreference struct {
+	Backend   string           `json:"backend"`
+	DeviceID  string           `json:"deviceId"`
+	Name      string           `json:"name"`
+	Fallbacks []DeviceIdentity `json:"fallbacks"`
+}
+
+// identity returns the identity of the selected device
+func (p DevicePreference) identity() DeviceIdentity {
+	return DeviceIdentity{Backend: p.Backend, DeviceID: p.DeviceID, Name: p.Name}
+}
+
+// Match finds the first of the selected and fallback devices that is in
+// devices, reporting whether it is the selected device
+func (p DevicePreference) Match(devices []MediaDeviceInfo) (device MediaDeviceInfo, selected bool, ok bool) {
+	if p.DeviceID == "" {
+		return MediaDeviceInfo{}, false, false
+	}
+
+	for i, identity := range append([]DeviceIdentity{p.identity()}, p.Fallbacks...) {
+		if device, ok := identity.match(devices); ok {
+			return device, i == 0, true
+		}
+	}
+
+	return MediaDeviceInfo{}, false, false
+}
+
+// match finds the device with the identity's ID, or failing that its name
+func (i DeviceIdentity) match(devices []MediaDeviceInfo) (MediaDeviceInfo, bool) {
+	if i.DeviceID == "" {
+		return MediaDeviceInfo{}, false
+	}
+
+	for _, device := range devices {
+		// IDs from another backend are unrelated even when they look alike
+		if device.DeviceID == i.DeviceID && (i.Backend == "" || device.Backend == i.Backend) {
+			return device, true
+		}
+	}
+
+	if i.Name == "" {
+		return MediaDeviceInfo{}, false
+	}
+
+	for _, device := range devices {
+		if device.Label == i.Name {
+			return device, true
+		}
+	}
+
+	return MediaDeviceInfo{}, false
+}
+
+// selectDevice returns the preference for selecting device, keeping the
+// previously selected devices as fallbacks
+func (p DevicePreference) selectDevice(device MediaDeviceInfo) DevicePreference {
+	selected := DeviceIdentity{Backend: device.Backend, DeviceID: device.DeviceID, Name: device.Label}
+
+	var fallbacks []DeviceIdentity
+	for _, identity := range append([]DeviceIdentity{p.identity()}, p.Fallbacks...) {
+		if identity.DeviceID == "" || identity == selected {
+			continue
+		}
+		fallbacks = append(fallbacks, identity)
+	}
+	if len(fallbacks) > maxDeviceFallbacks {
+		fallbacks = fallbacks[:maxDeviceFallbacks]
+	}
+
+	return DevicePreference{
+		Backend:   selected.Backend,
+		DeviceID:  selected.DeviceID,
+		Name:      selected.Name,
+		Fallbacks: fallbacks,
+	}
+}
+
+// getDevicePreference gets a device preference, reading the raw device ID
+// stored under legacyKey by earlier versions if there is none
+func (a *App) getDevicePreference(key string, legacyKey string) (DevicePreference, error) {
+	serializedDevicePreference, _ := a.fs.GetItem(key)
+	if serializedDevicePreference == "" {
+		serializedDeviceID, _ := a.fs.GetItem(legacyKey)
+		if serializedDeviceID == "" {
+			return DevicePreference{}, nil
+		}
+
+		var deviceID string
+		if err := json.Unmarshal([]byte(serializedDeviceID), &deviceID); err != nil {
+			return DevicePreference{}, err
+		}
+
+		return DevicePreference{DeviceID: deviceID}, nil
+	}
+
+	var devicePreference DevicePreference
+	if err := json.Unmarshal([]byte(serializedDevicePreference), &devicePreference); err != nil {
+		return DevicePreference{}, err
+	}
+
+	return devicePreference, nil
+}
+
+// setDevicePreference sets a device preference, replacing the raw device ID
+// stored under legacyKey by earlier versions
+func (a *App) setDevicePreference(key string, legacyKey string, devicePreference DevicePreference) error {
+	serializedDevicePreference, err := json.Marshal(devicePreference)
+	if err != nil {
+		return err
+	}
+
+	if err := a.fs.SetItem(key, string(serializedDevicePreference)); err != nil {
+		return err
+	}
+
+	return a.fs.RemoveItem(legacyKey)
+}
+
+// migrateDevicePreference converts a raw device ID stored by earlier versions
+// into a preference, naming it after the device it currently refers to
+func (a *App) migrateDevicePreference(key string, legacyKey string, devices []MediaDeviceInfo) error {
+	if serializedDevicePreference, _ := a.fs.GetItem(key); serializedDevicePreference != "" {
+		return nil
+	}
+
+	devicePreference, err := a.getDevicePreference(key, legacyKey)
+	if err != nil {
+		return err
+	}
+
+	if devicePreference.DeviceID == "" {
+		return nil
+	}
+
+	// The old ID may already point elsewhere, but it is the best guess there is
+	if device, ok := devicePreference.identity().match(devices); ok {
+		devicePreference = DevicePreference{}.selectDevice(device)
+	}
+
+	return a.setDevicePreference(key, legacyKey, devicePreference)
+}
+
+// migrateDevicePreferences converts the capture and playback device IDs
+// stored by earlier versions into preferences
+func (a *App) migrateDevicePreferences() {
+	devices, err := a.listDevices()
+	if err != nil {
+		log.Println(err)
+		return
+	}
+
+	if err := a.migrateDevicePreference("captureDevice", "captureDeviceID", filterDevices(devices, "audioinput")); err != nil {
+		log.Println(err)
+	}
+
+	if err := a.migrateDevicePreference("playbackDevice", "playbackDeviceID", filterDevices(devices, "audiooutput")); err != nil {
+		log.Println(err)
+	}
+}
+
+// setDeviceID selects the device of the given kind by its current ID
+func (a *App) setDeviceID(key string, legacyKey string, kind string, deviceID string) error {
+	devicePreference, err := a.getDevicePreference(key, legacyKey)
+	if err != nil {
+		return err
+	}
+
+	if deviceID == "" {
+		// Selecting the default device keeps the selected device as a fallback
+		devicePreference = devicePreference.selectDevice(MediaDeviceInfo{})
+	} else {
+		devices, err := a.listDevices()
+		if err != nil {
+			return err
+		}
+
+		index := -1
+		for i, device := range devices {
+			if device.Kind == kind && device.DeviceID == deviceID {
+				index = i
+				break
+			}
+		}
+		if index < 0 {
+			return fmt.Errorf("no device with ID %q", deviceID)
+		}
+
+		devicePreference = devicePreference.selectDevice(devices[index])
+	}
+
+	if err := a.setDevicePreference(key, legacyKey, devicePreference); err != nil {
+		return err
+	}
+
+	return a.restartLoopbackAudio()
+}
+
+// getDeviceID gets the current ID of the selected device of the given kind,
+// which may differ from the stored ID once the device has moved
+func (a *App) getDeviceID(key string, legacyKey string, kind string) (string, error) {
+	devicePreference, err := a.getDevicePreference(key, legacyKey)
+	if err != nil {
+		return "", err
+	}
+
+	if devicePreference.DeviceID == "" {
+		return "", nil
+	}
+
+	devices, err := a.listDevices()
+	if err != nil {
+		return "", err
+	}
+
+	if device, ok := devicePreference.identity().match(filterDevices(devices, kind)); ok {
+		return device.DeviceID, nil
+	}
+
+	return devicePreference.DeviceID, nil
+}
@@ -0,0 +1,20 @@
+//go:build windows
+
+package main
+
+import "golang.org/x/sys/windows"
+
+// diskFreeBytes returns the space left for the user on the disk holding path
+func diskFreeBytes(path string) (uint64, error) {
+	directoryName, err := windows.UTF16PtrFromString(path)
+	if err != nil {
+		return 0, err
+	}
+
+	var freeBytes uint64
+	if err := windows.GetDiskFreeSpaceEx(directoryName, &freeBytes, nil, nil); err != nil {
+		return 0, err
+	}
+
+	return freeBytes, nil
+}
@@ -0,0 +1,275 @@
+package main
+
+import (
+	"math"
+)
+
+// Processor processes interleaved samples in place. Processors keep state
+// between calls, so each one belongs to a single stream.
+type Processor interface {
+	Process(samples []float32)
+}
+
+// EffectChain runs processors one after the other
+type EffectChain []Processor
+
+// Process runs every processor of the chain over samples
+func (c EffectChain) Process(samples []float32) {
+	for _, processor := range c {
+		processor.Process(samples)
+	}
+}
+
+func dbToLinear(db float64) float64 {
+	return math.Pow(10, db/20)
+}
+
+func linearToDB(linear float64) float64 {
+	return 20 * math.Log10(max(linear, 1e-9))
+}
+
+// smoothingCoefficient returns the per-sample coefficient of a one-pole
+// smoother reaching about 63% of a step after the given time
+func smoothingCoefficient(milliseconds float64, sampleRate uint32) float64 {
+	if milliseconds <= 0 {
+		return 0
+	}
+	return math.Exp(-1000 / (milliseconds * float64(sampleRate)))
+}
+
+// framePeak returns the largest absolute sample of a frame
+func framePeak(frame []float32) float64 {
+	peak := 0.0
+	for _, s := range frame {
+		peak = max(peak, math.Abs(float64(s)))
+	}
+	return peak
+}
+
+// biquad is a second order filter in transposed direct form II, with separate
+// state for every channel
+type biquad struct {
+	b0, b1, b2, a1, a2 float64
+	state              [][2]float64
+}
+
+// newBiquad normalizes the coefficients from the Audio EQ Cookbook by a0
+func newBiquad(channels int, b0, b1, b2, a0, a1, a2 float64) *biquad {
+	return &biquad{
+		b0:    b0 / a0,
+		b1:    b1 / a0,
+		b2:    b2 / a0,
+		a1:    a1 / a0,
+		a2:    a2 / a0,
+		state: make([][2]float64, channels),
+	}
+}
+
+// biquadAngle returns the normalized angular frequency of a filter, keeping it
+// below the Nyquist frequency
+func biquadAngle(frequency float64, sampleRate uint32) float64 {
+	frequency = min(frequency, 0.49*float64(sampleRate))
+	return 2 * math.Pi * frequency / float64(sampleRate)
+}
+
+func (f *biquad) Process(samples []float32) {
+	channels := len(f.state)
+	for i, s := range samples {
+		state := &f.state[i%channels]
+		x := float64(s)
+		y := f.b0*x + state[0]
+		state[0] = f.b1*x - f.a1*y + state[1]
+		state[1] = f.b2*x - f.a2*y
+		samples[i] = float32(y)
+	}
+}
+
+// NoiseGate silences the signal while it stays below a threshold, such as
+// background noise between words
+type NoiseGate struct {
+	channels  int
+	threshold float64
+	attack    float64
+	release   float64
+	hold      float64
+	level     float64
+	gain      float64
+}
+
+// noiseGateHoldMilliseconds is how long the gate's level detector holds a
+// peak, so that the gate does not chatter on every cycle of a low voice
+const noiseGateHoldMilliseconds = 50
+
+// NewNoiseGate creates a new NoiseGate opening at thresholdDB, fading in over
+// attack and out over release milliseconds
+func NewNoiseGate(sampleRate uint32, channels int, thresholdDB, attack, release float64) *NoiseGate {
+	return &NoiseGate{
+		channels:  channels,
+		threshold: dbToLinear(thresholdDB),
+		attack:    smoothingCoefficient(attack, sampleRate),
+		release:   smoothingCoefficient(release, sampleRate),
+		hold:      smoothingCoefficient(noiseGateHoldMilliseconds, sampleRate),
+	}
+}
+
+func (g *NoiseGate) Process(samples []float32) {
+	for i := 0; i+g.channels <= len(samples); i += g.channels {
+		frame := samples[i : i+g.channels]
+
+		g.level = max(framePeak(frame), g.level*g.hold)
+		target, coefficient := 0.0, g.release
+		if g.level >= g.threshold {
+			target, coefficient = 1, g.attack
+		}
+		g.gain = target + (g.gain-target)*coefficient
+
+		for c := range frame {
+			frame[c] *= float32(g.gain)
+		}
+	}
+}
+
+// HighPassFilter removes rumble and handling noise below a cutoff frequency
+type HighPassFilter struct {
+	*biquad
+}
+
+// NewHighPassFilter creates a new Butterworth HighPassFilter at frequency
+func NewHighPassFilter(sampleRate uint32, channels int, frequency float64) *HighPassFilter {
+	w := biquadAngle(frequency, sampleRate)
+	// A Q of 1/√2 gives the flattest passband
+	alpha := math.Sin(w) / math.Sqrt2
+	cos := math.Cos(w)
+	return &HighPassFilter{newBiquad(channels,
+		(1+cos)/2, -(1 + cos), (1+cos)/2,
+		1+alpha, -2*cos, 1-alpha,
+	)}
+}
+
+// Equalizer boosts or cuts bands of frequencies with peaking filters
+type Equalizer struct {
+	bands EffectChain
+}
+
+// NewEqualizer creates a new Equalizer with a peaking filter for every band
+func NewEqualizer(sampleRate uint32, channels int, bands []EqualizerBand) *Equalizer {
+	e := &Equalizer{}
+	for _, band := range bands {
+		if band.GainDB == 0 {
+			continue
+		}
+
+		w := biquadAngle(band.Frequency, sampleRate)
+		alpha := math.Sin(w) / (2 * band.Q)
+		cos := math.Cos(w)
+		amplitude := math.Pow(10, band.GainDB/40)
+		e.bands = append(e.bands, newBiquad(channels,
+			1+alpha*amplitude, -2*cos, 1-alpha*amplitude,
+			1+alpha/amplitude, -2*cos, 1-alpha/amplitude,
+		))
+	}
+	return e
+}
+
+func (e *Equalizer) Process(samples []float32) {
+	e.bands.Process(samples)
+}
+
+// Compressor evens out the level by turning down the signal above a threshold
+// by a ratio, then makes up for the lost level
+type Compressor struct {
+	channels  int
+	threshold float64
+	slope     float64
+	attack    float64
+	release   float64
+	makeup    float64
+	reduction float64
+}
+
+// NewCompressor creates a new Compressor. Channels are compressed together so
+// that the stereo image does not shift.
+func NewCompressor(sampleRate uint32, channels int, thresholdDB, ratio, attack, release, makeupGainDB float64) *Compressor {
+	return &Compressor{
+		channels:  channels,
+		threshold: thresholdDB,
+		slope:     1 - 1/max(ratio, 1),
+		attack:    smoothingCoefficient(attack, sampleRate),
+		release:   smoothingCoefficient(release, sampleRate),
+		makeup:    makeupGainDB,
+	}
+}
+
+func (c *Compressor) Process(samples []float32) {
+	for i := 0; i+c.channels <= len(samples); i += c.channels {
+		frame := samples[i : i+c.channels]
+
+		target := max(linearToDB(framePeak(frame))-c.threshold, 0) * c.slope
+		coefficient := c.release
+		if target > c.reduction {
+			coefficient = c.attack
+		}
+		c.reduction = target + (c.reduction-target)*coefficient
+
+		gain := float32(dbToLinear(c.makeup - c.reduction))
+		for ch := range frame {
+			frame[ch] *= gain
+		}
+	}
+}
+
+// Limiter keeps peaks from going over a ceiling, turning the signal down
+// instantly and back up over the release time
+type Limiter struct {
+	channels int
+	ceiling  float64
+	release  float64
+	gain     float64
+}
+
+// NewLimiter creates a new Limiter at ceilingDB
+func NewLimiter(sampleRate uint32, channels int, ceilingDB, release float64) *Limiter {
+	return &Limiter{
+		channels: channels,
+		ceiling:  dbToLinear(ceilingDB),
+		release:  smoothingCoefficient(release, sampleRate),
+		gain:     1,
+	}
+}
+
+func (l *Limiter) Process(samples []float32) {
+	for i := 0; i+l.channels <= len(samples); i += l.channels {
+		frame := samples[i : i+l.channels]
+
+		target := 1.0
+		if peak := framePeak(frame); peak > l.ceiling {
+			target = l.ceiling / peak
+		}
+
+		if target < l.gain {
+			l.gain = target
+		} else {
+			l.gain = target + (l.gain-target)*l.release
+		}
+
+		for c := range frame {
+			frame[c] *= float32(l.gain)
+		}
+	}
+}
+
+// Gain turns the signal up or down by a fixed amount
+type Gain struct {
+	gain float32
+}
+
+// NewGain creates a new Gain of gainDB
+func NewGain(gainDB float64) *Gain {
+	return &Gain{gain: float32(dbToLinear(gainDB))}
+}
+
+func (g *Gain) Process(samples []float32) {
+	for i := range samples {
+		samples[i] *= g.gain
+	}
+}
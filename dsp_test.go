@@ -0,0 +1,221 @@
+package main
+
+import (
+	"math"
+	"reflect"
+	"slices"
+	"sync/atomic"
+	"testing"
+)
+
+const testSampleRate = 48000
+
+// sine returns a second of a mono sine wave
+func sine(frequency float64, amplitude float64) []float32 {
+	samples := make([]float32, testSampleRate)
+	for i := range samples {
+		samples[i] = float32(amplitude * math.Sin(2*math.Pi*frequency*float64(i)/testSampleRate))
+	}
+	return samples
+}
+
+// settledPeak returns the peak of the second half of samples, once filters and
+// envelopes have settled
+func settledPeak(samples []float32) float64 {
+	return framePeak(samples[len(samples)/2:])
+}
+
+func TestNoiseGate(t *testing.T) {
+	quiet := sine(200, dbToLinear(-60))
+	NewNoiseGate(testSampleRate, 1, -40, 1, 20).Process(quiet)
+	if peak := linearToDB(settledPeak(quiet)); peak > -100 {
+		t.Errorf("signal below the threshold peaks at %.1f dB", peak)
+	}
+
+	loud := sine(200, 0.5)
+	NewNoiseGate(testSampleRate, 1, -40, 1, 20).Process(loud)
+	if peak := settledPeak(loud); math.Abs(peak-0.5) > 0.01 {
+		t.Errorf("signal above the threshold peaks at %.3f, want 0.5", peak)
+	}
+}
+
+func TestHighPassFilter(t *testing.T) {
+	dc := make([]float32, testSampleRate)
+	for i := range dc {
+		dc[i] = 0.5
+	}
+	NewHighPassFilter(testSampleRate, 1, 80).Process(dc)
+	if peak := settledPeak(dc); peak > 1e-4 {
+		t.Errorf("DC decays to %g, want 0", peak)
+	}
+
+	tone := sine(2000, 0.5)
+	NewHighPassFilter(testSampleRate, 1, 80).Process(tone)
+	if peak := settledPeak(tone); math.Abs(peak-0.5) > 0.01 {
+		t.Errorf("tone above the cutoff peaks at %.3f, want 0.5", peak)
+	}
+}
+
+func TestEqualizer(t *testing.T) {
+	tests := []struct {
+		gainDB float64
+		q      float64
+	}{
+		{6, 1},
+		{-9, 2},
+		{12, 0.7},
+	}
+
+	for _, tt := range tests {
+		samples := sine(1000, 0.1)
+		NewEqualizer(testSampleRate, 1, []EqualizerBand{{Frequency: 1000, GainDB: tt.gainDB, Q: tt.q}}).Process(samples)
+		if gainDB := linearToDB(settledPeak(samples) / 0.1); math.Abs(gainDB-tt.gainDB) > 0.1 {
+			t.Errorf("%+g dB band with Q %g has %+.2f dB at its center", tt.gainDB, tt.q, gainDB)
+		}
+	}
+}
+
+func TestCompressor(t *testing.T) {
+	// A full scale sine is 20 dB over the threshold, which a 4:1 ratio brings
+	// down to 5 dB over
+	samples := sine(500, 1)
+	NewCompressor(testSampleRate, 1, -20, 4, 1, 50, 0).Process(samples)
+	if peak := linearToDB(settledPeak(samples)); peak > -15+0.5 {
+		t.Errorf("full scale sine compressed to %.1f dB, want at most -15 dB", peak)
+	}
+
+	quiet := sine(500, 0.01)
+	NewCompressor(testSampleRate, 1, -20, 4, 1, 50, 6).Process(quiet)
+	if gainDB := linearToDB(settledPeak(quiet) / 0.01); math.Abs(gainDB-6) > 0.1 {
+		t.Errorf("signal below the threshold gained %.2f dB, want the 6 dB makeup gain", gainDB)
+	}
+}
+
+func TestLimiter(t *testing.T) {
+	samples := sine(300, 1)
+	NewGain(12).Process(samples)
+	NewLimiter(testSampleRate, 1, -1, 50).Process(samples)
+
+	// The ceiling holds from the very first sample
+	if peak := framePeak(samples); peak > dbToLinear(-1)+1e-6 {
+		t.Errorf("limited to %.2f dB, want at most -1 dB", linearToDB(peak))
+	}
+}
+
+func TestGain(t *testing.T) {
+	for _, gainDB := range []float64{-12, -6, 0, 3, 6, 20} {
+		samples := []float32{0.1, -0.25, 0.03}
+		NewGain(gainDB).Process(samples)
+
+		for i, s := range []float32{0.1, -0.25, 0.03} {
+			want := float64(s) * math.Pow(10, gainDB/20)
+			if math.Abs(float64(samples[i])-want) > 1e-6 {
+				t.Errorf("%+g dB turned %g into %g, want %g", gainDB, s, samples[i], want)
+			}
+		}
+	}
+}
+
+// appendProcessor appends a value to every sample, and its name to a log
+type appendProcessor struct {
+	name  string
+	value float32
+	log   *[]string
+}
+
+func (p appendProcessor) Process(samples []float32) {
+	*p.log = append(*p.log, p.name)
+	for i := range samples {
+		samples[i] = samples[i]*10 + p.value
+	}
+}
+
+func TestEffectChain(t *testing.T) {
+	var log []string
+	chain := EffectChain{
+		appendProcessor{"first", 1, &log},
+		appendProcessor{"second", 2, &log},
+		appendProcessor{"third", 3, &log},
+	}
+
+	samples := []float32{0}
+	chain.Process(samples)
+
+	if want := []string{"first", "second", "third"}; !reflect.DeepEqual(log, want) {
+		t.Errorf("ran %v, want %v", log, want)
+	}
+	if samples[0] != 123 {
+		t.Errorf("got %g, want 123 from running in order", samples[0])
+	}
+
+	// The chain built from settings follows the signal path
+	settings := defaultEffectSettings
+	settings.NoiseGate.Enabled = true
+	settings.HighPass.Enabled = true
+	settings.Compressor.Enabled = true
+	settings.Gain.Enabled = true
+	settings.Limiter.Enabled = true
+
+	var types []string
+	for _, processor := range NewEffectChain(settings, testSampleRate, 2) {
+		types = append(types, reflect.TypeOf(processor).Elem().Name())
+	}
+	if want := []string{"NoiseGate", "HighPassFilter", "Compressor", "Gain", "Limiter"}; !reflect.DeepEqual(types, want) {
+		t.Errorf("chain is %v, want %v", types, want)
+	}
+}
+
+func TestLiveEffectChain(t *testing.T) {
+	var effectSettings atomic.Pointer[EffectSettings]
+	effects := NewLiveEffectChain(testSampleRate, 2)
+
+	// Nothing is applied before the settings are known
+	effects.Update(&effectSettings)
+	samples := []float32{0.5, 0.5}
+	effects.Process(samples)
+	if samples[0] != 0.5 {
+		t.Errorf("got %g with no settings, want 0.5", samples[0])
+	}
+
+	settings := defaultEffectSettings
+	settings.Delay.Enabled = true
+	settings.Gain.Enabled = true
+	effectSettings.Store(&settings)
+
+	tests := []struct {
+		name       string
+		update     func(s *EffectSettings)
+		wantDelay  bool
+		wantGain   bool
+		wantLength int
+	}{
+		{"unchanged", func(s *EffectSettings) {}, true, true, 2},
+		{"gain changed", func(s *EffectSettings) { s.Gain.GainDB = 6 }, true, false, 2},
+		{"another effect enabled", func(s *EffectSettings) { s.Limiter.Enabled = true }, true, true, 3},
+		{"delay disabled", func(s *EffectSettings) { s.Delay.Enabled = false }, false, true, 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			effectSettings.Store(&settings)
+			effects.Update(&effectSettings)
+			before := *effects.chain.Load()
+
+			updated := settings
+			tt.update(&updated)
+			effectSettings.Store(&updated)
+			effects.Update(&effectSettings)
+			after := *effects.chain.Load()
+
+			if len(after) != tt.wantLength {
+				t.Fatalf("chain has %d processors, want %d", len(after), tt.wantLength)
+			}
+			if kept := slices.Contains(after, before[0]); kept != tt.wantDelay {
+				t.Errorf("delay kept: %t, want %t", kept, tt.wantDelay)
+			}
+			if kept := slices.Contains(after, before[1]); kept != tt.wantGain {
+				t.Errorf("gain kept: %t, want %t", kept, tt.wantGain)
+			}
+		})
+	}
+}
@@ -0,0 +1,154 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+)
+
+// DuckingRule configures how far one side of the mix is turned down while the
+// other side is above a threshold, and how quickly it goes down and back up
+type DuckingRule struct {
+	Enabled             bool    `json:"enabled"`
+	ThresholdDB         float64 `json:"thresholdDb"`
+	DepthDB             float64 `json:"depthDb"`
+	AttackMilliseconds  float64 `json:"attackMilliseconds"`
+	ReleaseMilliseconds float64 `json:"releaseMilliseconds"`
+}
+
+// DuckingSettings configures the sidechain ducking of the mixer. Mic ducks the
+// mic while clips play on the main bus, which is what the virtual mic carries,
+// so clips only played on other buses, like a monitor, leave the mic alone.
+// Clips ducks the clips on every bus while someone speaks.
+type DuckingSettings struct {
+	Mic   DuckingRule `json:"mic"`
+	Clips DuckingRule `json:"clips"`
+}
+
+// defaultDuckingSettings has ducking disabled, with rules that suit a voice
+// over music once enabled
+var defaultDuckingSettings = DuckingSettings{
+	Mic: DuckingRule{
+		ThresholdDB:         -50,
+		DepthDB:             12,
+		AttackMilliseconds:  10,
+		ReleaseMilliseconds: 300,
+	},
+	Clips: DuckingRule{
+		ThresholdDB:         -35,
+		DepthDB:             12,
+		AttackMilliseconds:  10,
+		ReleaseMilliseconds: 500,
+	},
+}
+
+// validate reports rules that would make the ducking misbehave
+func (s DuckingSettings) validate() error {
+	for _, rule := range []DuckingRule{s.Mic, s.Clips} {
+		if rule.DepthDB < 0 || rule.DepthDB > 60 {
+			return fmt.Errorf("unsupported ducking depth of %g dB", rule.DepthDB)
+		}
+
+		if rule.AttackMilliseconds < 0 || rule.ReleaseMilliseconds < 0 {
+			return errors.New("attack and release times cannot be negative")
+		}
+	}
+
+	return nil
+}
+
+// Ducker turns the signal down by a depth while a sidechain signal stays
+// above a threshold, fading down over the attack and back up over the release
+type Ducker struct {
+	channels  int
+	threshold float64
+	depth     float64
+	attack    float64
+	release   float64
+	hold      float64
+	level     float64
+	gain      float64
+}
+
+// NewDucker creates a new Ducker turning the signal down by depthDB while the
+// sidechain is above thresholdDB
+func NewDucker(sampleRate uint32, channels int, thresholdDB, depthDB, attack, release float64) *Ducker {
+	return &Ducker{
+		channels:  channels,
+		threshold: dbToLinear(thresholdDB),
+		depth:     dbToLinear(-depthDB),
+		attack:    smoothingCoefficient(attack, sampleRate),
+		release:   smoothingCoefficient(release, sampleRate),
+		hold:      smoothingCoefficient(noiseGateHoldMilliseconds, sampleRate),
+		gain:      1,
+	}
+}
+
+func newDucker(rule DuckingRule, sampleRate uint32, channels int) *Ducker {
+	return NewDucker(sampleRate, channels, rule.ThresholdDB, rule.DepthDB, rule.AttackMilliseconds, rule.ReleaseMilliseconds)
+}
+
+// Process ducks samples following sidechain, which has the same layout
+func (d *Ducker) Process(samples []float32, sidechain []float32) {
+	for i := 0; i+d.channels <= len(samples); i += d.channels {
+		// Like the noise gate, the level holds its peaks so that the gain does
+		// not pump on every cycle of the sidechain
+		d.level = max(framePeak(sidechain[i:i+d.channels]), d.level*d.hold)
+		target, coefficient := 1.0, d.release
+		if d.level >= d.threshold {
+			target, coefficient = d.depth, d.attack
+		}
+		d.gain = target + (d.gain-target)*coefficient
+
+		for c := i; c < i+d.channels; c++ {
+			samples[c] *= float32(d.gain)
+		}
+	}
+}
+
+// GetDuckingSettings gets the ducking rules of the mixer
+func (a *App) GetDuckingSettings() (DuckingSettings, error) {
+	serializedDuckingSettings, _ := a.fs.GetItem("duckingSettings")
+	if serializedDuckingSettings == "" {
+		return defaultDuckingSettings, nil
+	}
+
+	var duckingSettings DuckingSettings
+	if err := json.Unmarshal([]byte(serializedDuckingSettings), &duckingSettings); err != nil {
+		return DuckingSettings{}, err
+	}
+
+	return duckingSettings, nil
+}
+
+// SetDuckingSettings sets the ducking rules and applies them to the mixer
+func (a *App) SetDuckingSettings(duckingSettings DuckingSettings) error {
+	if err := duckingSettings.validate(); err != nil {
+		return err
+	}
+
+	serializedDuckingSettings, err := json.Marshal(duckingSettings)
+	if err != nil {
+		return err
+	}
+
+	if err := a.fs.SetItem("duckingSettings", string(serializedDuckingSettings)); err != nil {
+		return err
+	}
+
+	a.mixer.SetDucking(duckingSettings)
+
+	return nil
+}
+
+// applyDuckingSettings hands the stored ducking rules to the mixer
+func (a *App) applyDuckingSettings() error {
+	duckingSettings, err := a.GetDuckingSettings()
+	if err != nil {
+		return err
+	}
+
+	a.mixer.SetDucking(duckingSettings)
+
+	return nil
+}
@@ -0,0 +1,87 @@
+package main
+
+import (
+	"math"
+	"testing"
+)
+
+func TestDucker(t *testing.T) {
+	const thresholdDB, depthDB = -40, 20
+
+	tests := []struct {
+		name      string
+		sidechain []float32
+		wantGain  float64
+	}{
+		{"quiet sidechain", sine(440, 0.001), 1},
+		{"silent sidechain", make([]float32, testSampleRate), 1},
+		{"loud sidechain", sine(440, 0.5), dbToLinear(-depthDB)},
+		// A low tone crosses zero slowly, which the level holds through
+		// rather than pumping the gain on every cycle
+		{"low tone", sine(40, 0.5), dbToLinear(-depthDB)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			samples := make([]float32, len(tt.sidechain))
+			for i := range samples {
+				samples[i] = 1
+			}
+			NewDucker(testSampleRate, 1, thresholdDB, depthDB, 10, 100).Process(samples, tt.sidechain)
+
+			// The second half has settled
+			for i, s := range samples[len(samples)/2:] {
+				if math.Abs(float64(s)-tt.wantGain) > 1e-3 {
+					t.Fatalf("gain is %g at sample %d, want %g", s, len(samples)/2+i, tt.wantGain)
+				}
+			}
+		})
+	}
+}
+
+func TestDuckerTiming(t *testing.T) {
+	// The sidechain is loud for the first half second and silent for the
+	// second and a half after
+	sidechain := make([]float32, 2*2*testSampleRate)
+	for i := 0; i < testSampleRate; i += 2 {
+		sidechain[i], sidechain[i+1] = 0.5, 0.5
+	}
+	samples := make([]float32, len(sidechain))
+	for i := range samples {
+		samples[i] = 1
+	}
+	NewDucker(testSampleRate, 2, -40, 20, 10, 100).Process(samples, sidechain)
+
+	// frame returns the gain a number of milliseconds in
+	frame := func(milliseconds int) float64 {
+		return float64(samples[2*(milliseconds*testSampleRate/1000)])
+	}
+
+	depth := dbToLinear(-20)
+	tests := []struct {
+		name         string
+		milliseconds int
+		min, max     float64
+	}{
+		{"attacking", 5, depth, 0.9},
+		{"ducked", 400, depth - 1e-3, depth + 1e-3},
+		{"held", 550, depth - 1e-3, depth + 1e-3},
+		{"releasing", 800, depth + 0.01, 0.9},
+		{"released", 1900, 0.999, 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if gain := frame(tt.milliseconds); gain < tt.min || gain > tt.max {
+				t.Errorf("gain is %g after %d ms, want %g to %g", gain, tt.milliseconds, tt.min, tt.max)
+			}
+		})
+	}
+
+	// Both channels get the same gain
+	for i := 0; i < len(samples); i += 2 {
+		if samples[i] != samples[i+1] {
+			t.Fatalf("channels are %g and %g at frame %d", samples[i], samples[i+1], i/2)
+		}
+	}
+}
@@ -0,0 +1,642 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"reflect"
+	"slices"
+	"strings"
+	"sync"
+	"sync/atomic"
+)
+
+// NoiseGateSettings configures the noise gate, which mutes the mic between words
+type NoiseGateSettings struct {
+	Enabled             bool    `json:"enabled"`
+	ThresholdDB         float64 `json:"thresholdDb"`
+	AttackMilliseconds  float64 `json:"attackMilliseconds"`
+	ReleaseMilliseconds float64 `json:"releaseMilliseconds"`
+}
+
+// HighPassSettings configures the high-pass filter, which removes rumble
+type HighPassSettings struct {
+	Enabled   bool    `json:"enabled"`
+	Frequency float64 `json:"frequency"`
+}
+
+// EqualizerBand is a peaking filter boosting or cutting around a frequency. A
+// higher Q narrows the band.
+type EqualizerBand struct {
+	Frequency float64 `json:"frequency"`
+	GainDB    float64 `json:"gainDb"`
+	Q         float64 `json:"q"`
+}
+
+// EqualizerSettings configures the parametric equalizer
+type EqualizerSettings struct {
+	Enabled bool            `json:"enabled"`
+	Bands   []EqualizerBand `json:"bands"`
+}
+
+// CompressorSettings configures the compressor, which evens out the level
+type CompressorSettings struct {
+	Enabled             bool    `json:"enabled"`
+	ThresholdDB         float64 `json:"thresholdDb"`
+	Ratio               float64 `json:"ratio"`
+	AttackMilliseconds  float64 `json:"attackMilliseconds"`
+	ReleaseMilliseconds float64 `json:"releaseMilliseconds"`
+	MakeupGainDB        float64 `json:"makeupGainDb"`
+}
+
+// PitchShiftSettings configures the pitch shifter
+type PitchShiftSettings struct {
+	Enabled   bool    `json:"enabled"`
+	Semitones float64 `json:"semitones"`
+}
+
+// RobotSettings configures the ring modulator giving a robot voice
+type RobotSettings struct {
+	Enabled   bool    `json:"enabled"`
+	Frequency float64 `json:"frequency"`
+	Mix       float64 `json:"mix"`
+}
+
+// DelaySettings configures the echo
+type DelaySettings struct {
+	Enabled           bool    `json:"enabled"`
+	DelayMilliseconds float64 `json:"delayMilliseconds"`
+	Feedback          float64 `json:"feedback"`
+	Mix               float64 `json:"mix"`
+}
+
+// ReverbSettings configures the reverb
+type ReverbSettings struct {
+	Enabled  bool    `json:"enabled"`
+	RoomSize float64 `json:"roomSize"`
+	Damping  float64 `json:"damping"`
+	Mix      float64 `json:"mix"`
+}
+
+// GainSettings configures a fixed gain
+type GainSettings struct {
+	Enabled bool    `json:"enabled"`
+	GainDB  float64 `json:"gainDb"`
+}
+
+// LimiterSettings configures the limiter, which keeps peaks under a ceiling
+type LimiterSettings struct {
+	Enabled             bool    `json:"enabled"`
+	CeilingDB           float64 `json:"ceilingDb"`
+	ReleaseMilliseconds float64 `json:"releaseMilliseconds"`
+}
+
+// EffectSettings configures the effects applied to the mic on the loopback,
+// in the order of the fields
+type EffectSettings struct {
+	NoiseGate  NoiseGateSettings  `json:"noiseGate"`
+	HighPass   HighPassSettings   `json:"highPass"`
+	Equalizer  EqualizerSettings  `json:"equalizer"`
+	Compressor CompressorSettings `json:"compressor"`
+	PitchShift PitchShiftSettings `json:"pitchShift"`
+	Robot      RobotSettings      `json:"robot"`
+	Delay      DelaySettings      `json:"delay"`
+	Reverb     ReverbSettings     `json:"reverb"`
+	Gain       GainSettings       `json:"gain"`
+	Limiter    LimiterSettings    `json:"limiter"`
+}
+
+// defaultEffectSettings has every effect disabled, with settings that suit a
+// voice once enabled
+var defaultEffectSettings = EffectSettings{
+	NoiseGate: NoiseGateSettings{
+		ThresholdDB:         -50,
+		AttackMilliseconds:  1,
+		ReleaseMilliseconds: 100,
+	},
+	HighPass: HighPassSettings{
+		Frequency: 80,
+	},
+	Equalizer: EqualizerSettings{
+		Bands: []EqualizerBand{
+			{Frequency: 200, Q: 1},
+			{Frequency: 1000, Q: 1},
+			{Frequency: 5000, Q: 1},
+		},
+	},
+	Compressor: CompressorSettings{
+		ThresholdDB:         -18,
+		Ratio:               3,
+		AttackMilliseconds:  5,
+		ReleaseMilliseconds: 100,
+		MakeupGainDB:        3,
+	},
+	PitchShift: PitchShiftSettings{
+		Semitones: 4,
+	},
+	Robot: RobotSettings{
+		Frequency: 50,
+		Mix:       1,
+	},
+	Delay: DelaySettings{
+		DelayMilliseconds: 250,
+		Feedback:          0.35,
+		Mix:               0.3,
+	},
+	Reverb: ReverbSettings{
+		RoomSize: 0.5,
+		Damping:  0.5,
+		Mix:      0.3,
+	},
+	Limiter: LimiterSettings{
+		CeilingDB:           -1,
+		ReleaseMilliseconds: 50,
+	},
+}
+
+// defaultEffectProfile is the profile used until another one is selected
+const defaultEffectProfile = "Default"
+
+// effects lists the settings of every effect, in the order they are applied
+func (s EffectSettings) effects() []any {
+	return []any{s.NoiseGate, s.HighPass, s.Equalizer, s.Compressor, s.PitchShift, s.Robot, s.Delay, s.Reverb, s.Gain, s.Limiter}
+}
+
+// newEffect creates the processor for the settings of an effect, or nil if
+// it is disabled
+func newEffect(settings any, sampleRate uint32, channels int) Processor {
+	switch s := settings.(type) {
+	case NoiseGateSettings:
+		if s.Enabled {
+			return NewNoiseGate(sampleRate, channels, s.ThresholdDB, s.AttackMilliseconds, s.ReleaseMilliseconds)
+		}
+	case HighPassSettings:
+		if s.Enabled {
+			return NewHighPassFilter(sampleRate, channels, s.Frequency)
+		}
+	case EqualizerSettings:
+		if s.Enabled {
+			return NewEqualizer(sampleRate, channels, s.Bands)
+		}
+	case CompressorSettings:
+		if s.Enabled {
+			return NewCompressor(sampleRate, channels, s.ThresholdDB, s.Ratio, s.AttackMilliseconds, s.ReleaseMilliseconds, s.MakeupGainDB)
+		}
+	case PitchShiftSettings:
+		if s.Enabled {
+			return NewPitchShifter(channels, s.Semitones)
+		}
+	case RobotSettings:
+		if s.Enabled {
+			return NewRingModulator(sampleRate, channels, s.Frequency, s.Mix)
+		}
+	case DelaySettings:
+		if s.Enabled {
+			return NewDelay(sampleRate, channels, s.DelayMilliseconds, s.Feedback, s.Mix)
+		}
+	case ReverbSettings:
+		if s.Enabled {
+			return NewReverb(sampleRate, channels, s.RoomSize, s.Damping, s.Mix)
+		}
+	case GainSettings:
+		if s.Enabled {
+			return NewGain(s.GainDB)
+		}
+	case LimiterSettings:
+		if s.Enabled {
+			return NewLimiter(sampleRate, channels, s.CeilingDB, s.ReleaseMilliseconds)
+		}
+	}
+
+	return nil
+}
+
+// NewEffectChain creates the processors for the enabled effects
+func NewEffectChain(effectSettings EffectSettings, sampleRate uint32, channels int) EffectChain {
+	var chain EffectChain
+	for _, settings := range effectSettings.effects() {
+		if processor := newEffect(settings, sampleRate, channels); processor != nil {
+			chain = append(chain, processor)
+		}
+	}
+
+	return chain
+}
+
+// LiveEffectChain is the effect chain of the running loopback. Its processors
+// are built by Update, away from the audio thread, which only picks up the
+// latest chain in Process. Effects whose settings did not change keep their
+// processors, and with them their state.
+type LiveEffectChain struct {
+	sampleRate uint32
+	channels   int
+	settings   []any
+	processors []Processor
+	mu         sync.Mutex
+	chain      atomic.Pointer[EffectChain]
+}
+
+// NewLiveEffectChain creates a new LiveEffectChain with no effects yet
+func NewLiveEffectChain(sampleRate uint32, channels int) *LiveEffectChain {
+	c := &LiveEffectChain{
+		sampleRate: sampleRate,
+		channels:   channels,
+	}
+	c.chain.Store(&EffectChain{})
+
+	return c
+}
+
+// Update rebuilds the chain for the latest effect settings, which are loaded
+// under the lock so that concurrent updates end with the latest of them
+func (c *LiveEffectChain) Update(effectSettings *atomic.Pointer[EffectSettings]) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	var settings []any
+	if s := effectSettings.Load(); s != nil {
+		settings = s.effects()
+	}
+
+	processors := make([]Processor, len(settings))
+	var chain EffectChain
+	for i, s := range settings {
+		if i < len(c.settings) && reflect.DeepEqual(s, c.settings[i]) {
+			processors[i] = c.processors[i]
+		} else {
+			processors[i] = newEffect(s, c.sampleRate, c.channels)
+		}
+
+		if processors[i] != nil {
+			chain = append(chain, processors[i])
+		}
+	}
+
+	c.settings, c.processors = settings, processors
+	c.chain.Store(&chain)
+}
+
+// Process runs the latest chain over samples
+func (c *LiveEffectChain) Process(samples []float32) {
+	c.chain.Load().Process(samples)
+}
+
+// validate reports settings that would make an enabled effect misbehave
+func (s EffectSettings) validate() error {
+	if s.HighPass.Enabled && s.HighPass.Frequency <= 0 {
+		return fmt.Errorf("unsupported high-pass frequency %g", s.HighPass.Frequency)
+	}
+
+	if s.Equalizer.Enabled {
+		for _, band := range s.Equalizer.Bands {
+			if band.Frequency <= 0 {
+				return fmt.Errorf("unsupported equalizer frequency %g", band.Frequency)
+			}
+			if band.Q <= 0 {
+				return fmt.Errorf("unsupported equalizer Q %g", band.Q)
+			}
+		}
+	}
+
+	if s.Compressor.Enabled && s.Compressor.Ratio < 1 {
+		return fmt.Errorf("unsupported compressor ratio %g", s.Compressor.Ratio)
+	}
+
+	if s.NoiseGate.AttackMilliseconds < 0 || s.NoiseGate.ReleaseMilliseconds < 0 ||
+		s.Compressor.AttackMilliseconds < 0 || s.Compressor.ReleaseMilliseconds < 0 ||
+		s.Limiter.ReleaseMilliseconds < 0 {
+		return errors.New("attack and release times cannot be negative")
+	}
+
+	if s.PitchShift.Enabled && math.Abs(s.PitchShift.Semitones) > 24 {
+		return fmt.Errorf("unsupported pitch shift of %g semitones", s.PitchShift.Semitones)
+	}
+
+	if s.Robot.Enabled && s.Robot.Frequency <= 0 {
+		return fmt.Errorf("unsupported robot frequency %g", s.Robot.Frequency)
+	}
+
+	if s.Delay.Enabled && (s.Delay.DelayMilliseconds <= 0 || s.Delay.DelayMilliseconds > 2000) {
+		return fmt.Errorf("unsupported delay of %g ms", s.Delay.DelayMilliseconds)
+	}
+
+	if s.Delay.Enabled && (s.Delay.Feedback < 0 || s.Delay.Feedback > 0.95) {
+		return fmt.Errorf("unsupported delay feedback %g", s.Delay.Feedback)
+	}
+
+	if s.Reverb.Enabled && (s.Reverb.RoomSize < 0 || s.Reverb.RoomSize > 1 || s.Reverb.Damping < 0 || s.Reverb.Damping > 1) {
+		return errors.New("reverb room size and damping go from 0 to 1")
+	}
+
+	for _, mix := range []float64{s.Robot.Mix, s.Delay.Mix, s.Reverb.Mix} {
+		if mix < 0 || mix > 1 {
+			return fmt.Errorf("unsupported mix %g", mix)
+		}
+	}
+
+	return nil
+}
+
+// enabled returns where the named effect is switched on or off
+func (s *EffectSettings) enabled(effect string) (*bool, error) {
+	switch effect {
+	case "noiseGate":
+		return &s.NoiseGate.Enabled, nil
+	case "highPass":
+		return &s.HighPass.Enabled, nil
+	case "equalizer":
+		return &s.Equalizer.Enabled, nil
+	case "compressor":
+		return &s.Compressor.Enabled, nil
+	case "pitchShift":
+		return &s.PitchShift.Enabled, nil
+	case "robot":
+		return &s.Robot.Enabled, nil
+	case "delay":
+		return &s.Delay.Enabled, nil
+	case "reverb":
+		return &s.Reverb.Enabled, nil
+	case "gain":
+		return &s.Gain.Enabled, nil
+	case "limiter":
+		return &s.Limiter.Enabled, nil
+	default:
+		return nil, fmt.Errorf("unknown effect %q", effect)
+	}
+}
+
+// listEffectProfiles reads the effect settings of every profile
+func (a *App) listEffectProfiles() (map[string]EffectSettings, error) {
+	serializedEffectProfiles, _ := a.fs.GetItem("effectProfiles")
+	if serializedEffectProfiles == "" {
+		return map[string]EffectSettings{}, nil
+	}
+
+	var effectProfiles map[string]EffectSettings
+	if err := json.Unmarshal([]byte(serializedEffectProfiles), &effectProfiles); err != nil {
+		return nil, err
+	}
+
+	return effectProfiles, nil
+}
+
+// updateEffectProfiles updates the effect settings of every profile with callback
+func (a *App) updateEffectProfiles(callback func(map[string]EffectSettings)) error {
+	return a.fs.UpdateItem("effectProfiles", func(value string) (string, error) {
+		effectProfiles := make(map[string]EffectSettings)
+		if value != "" {
+			if err := json.Unmarshal([]byte(value), &effectProfiles); err != nil {
+				return "", err
+			}
+		}
+
+		callback(effectProfiles)
+
+		serializedEffectProfiles, err := json.Marshal(effectProfiles)
+		if err != nil {
+			return "", err
+		}
+
+		return string(serializedEffectProfiles), nil
+	})
+}
+
+// ListEffectProfiles lists the names of the effect profiles
+func (a *App) ListEffectProfiles() ([]string, error) {
+	effectProfiles, err := a.listEffectProfiles()
+	if err != nil {
+		return nil, err
+	}
+
+	effectProfile, err := a.GetEffectProfile()
+	if err != nil {
+		return nil, err
+	}
+
+	names := []string{effectProfile}
+	for name := range effectProfiles {
+		if name != effectProfile {
+			names = append(names, name)
+		}
+	}
+	slices.Sort(names)
+
+	return names, nil
+}
+
+// GetEffectProfile gets the name of the selected effect profile
+func (a *App) GetEffectProfile() (string, error) {
+	serializedEffectProfile, _ := a.fs.GetItem("effectProfile")
+	if serializedEffectProfile == "" {
+		return defaultEffectProfile, nil
+	}
+
+	var effectProfile string
+	if err := json.Unmarshal([]byte(serializedEffectProfile), &effectProfile); err != nil {
+		return "", err
+	}
+
+	return effectProfile, nil
+}
+
+// SetEffectProfile selects an effect profile and applies its effects. A new
+// profile starts from the settings of the selected one.
+func (a *App) SetEffectProfile(effectProfile string) error {
+	effectProfile = strings.TrimSpace(effectProfile)
+	if effectProfile == "" {
+		return errors.New("effect profile name cannot be empty")
+	}
+
+	effectSettings, err := a.GetEffectSettings()
+	if err != nil {
+		return err
+	}
+
+	effectProfiles, err := a.listEffectProfiles()
+	if err != nil {
+		return err
+	}
+
+	if _, ok := effectProfiles[effectProfile]; !ok {
+		if err := a.updateEffectProfiles(func(effectProfiles map[string]EffectSettings) {
+			effectProfiles[effectProfile] = effectSettings
+		}); err != nil {
+			return err
+		}
+	}
+
+	serializedEffectProfile, err := json.Marshal(effectProfile)
+	if err != nil {
+		return err
+	}
+
+	if err := a.fs.SetItem("effectProfile", string(serializedEffectProfile)); err != nil {
+		return err
+	}
+
+	return a.applyEffectSettings()
+}
+
+// DeleteEffectProfile deletes an effect profile, going back to the default
+// profile if it was selected
+func (a *App) DeleteEffectProfile(effectProfile string) error {
+	if err := a.updateEffectProfiles(func(effectProfiles map[string]EffectSettings) {
+		delete(effectProfiles, effectProfile)
+	}); err != nil {
+		return err
+	}
+
+	selected, err := a.GetEffectProfile()
+	if err != nil {
+		return err
+	}
+
+	if selected != effectProfile {
+		return nil
+	}
+
+	if err := a.fs.RemoveItem("effectProfile"); err != nil {
+		return err
+	}
+
+	return a.applyEffectSettings()
+}
+
+// GetEffectSettings gets the effect settings of the selected profile
+func (a *App) GetEffectSettings() (EffectSettings, error) {
+	effectProfile, err := a.GetEffectProfile()
+	if err != nil {
+		return EffectSettings{}, err
+	}
+
+	effectProfiles, err := a.listEffectProfiles()
+	if err != nil {
+		return EffectSettings{}, err
+	}
+
+	effectSettings, ok := effectProfiles[effectProfile]
+	if !ok {
+		return defaultEffectSettings, nil
+	}
+
+	return effectSettings, nil
+}
+
+// SetEffectSettings sets the effect settings of the selected profile and
+// applies them to the running loopback
+func (a *App) SetEffectSettings(effectSettings EffectSettings) error {
+	if err := effectSettings.validate(); err != nil {
+		return err
+	}
+
+	effectProfile, err := a.GetEffectProfile()
+	if err != nil {
+		return err
+	}
+
+	if err := a.updateEffectProfiles(func(effectProfiles map[string]EffectSettings) {
+		effectProfiles[effectProfile] = effectSettings
+	}); err != nil {
+		return err
+	}
+
+	a.effectSettings.Store(&effectSettings)
+	a.updateEffectChain()
+
+	return nil
+}
+
+// applyEffectSettings applies the effect settings of the selected profile to
+// the running loopback
+func (a *App) applyEffectSettings() error {
+	effectSettings, err := a.GetEffectSettings()
+	if err != nil {
+		return err
+	}
+
+	a.effectSettings.Store(&effectSettings)
+	a.updateEffectChain()
+
+	return nil
+}
+
+// updateEffectChain rebuilds the effect chain of the running loopback, if
+// any, for the latest effect settings
+func (a *App) updateEffectChain() {
+	if effects := a.effectChain.Load(); effects != nil {
+		effects.Update(&a.effectSettings)
+	}
+}
+
+// ToggleEffect switches an effect of the selected profile on or off, emitting
+// the new settings through the "effectSettings" event
+func (a *App) ToggleEffect(effect string) error {
+	effectSettings, err := a.GetEffectSettings()
+	if err != nil {
+		return err
+	}
+
+	enabled, err := effectSettings.enabled(effect)
+	if err != nil {
+		return err
+	}
+	*enabled = !*enabled
+
+	if err := a.SetEffectSettings(effectSettings); err != nil {
+		return err
+	}
+
+	a.emit("effectSettings", effectSettings)
+
+	return nil
+}
+
+// ListEffectKeybindings lists the keybindings that toggle effects
+func (a *App) ListEffectKeybindings() (map[string]string, error) {
+	serializedEffectKeybindings, _ := a.fs.GetItem("effectKeybindings")
+	if serializedEffectKeybindings == "" {
+		return map[string]string{}, nil
+	}
+
+	var effectKeybindings map[string]string
+	if err := json.Unmarshal([]byte(serializedEffectKeybindings), &effectKeybindings); err != nil {
+		return nil, err
+	}
+
+	return effectKeybindings, nil
+}
+
+// SetEffectKeybinding sets the keybinding that toggles an effect, removing it
+// if empty
+func (a *App) SetEffectKeybinding(effect string, keybinding string) error {
+	if _, err := (&EffectSettings{}).enabled(effect); err != nil {
+		return err
+	}
+
+	if err := a.fs.UpdateItem("effectKeybindings", func(value string) (string, error) {
+		effectKeybindings := make(map[string]string)
+		if value != "" {
+			if err := json.Unmarshal([]byte(value), &effectKeybindings); err != nil {
+				return "", err
+			}
+		}
+
+		if keybinding == "" {
+			delete(effectKeybindings, effect)
+		} else {
+			effectKeybindings[effect] = keybinding
+		}
+
+		serializedEffectKeybindings, err := json.Marshal(effectKeybindings)
+		if err != nil {
+			return "", err
+		}
+
+		return string(serializedEffectKeybindings), nil
+	}); err != nil {
+		return err
+	}
+
+	return a.registerKeybindings()
+}
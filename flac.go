@@ -0,0 +1,231 @@
+package main
+
+import (
+	"io"
+	"math/bits"
+
+	"github.com/mewkiz/flac"
+	"github.com/mewkiz/flac/frame"
+	"github.com/mewkiz/flac/meta"
+)
+
+// flacBlockSize is the number of frames in every FLAC frame but the last one
+const flacBlockSize = 4096
+
+// flacBitsPerSample is the sample size FLAC recordings are written at
+const flacBitsPerSample = 16
+
+// flacMaxFixedOrder is the highest order of the fixed predictors
+const flacMaxFixedOrder = 4
+
+// FLACEncoder writes 16-bit FLAC files with github.com/mewkiz/flac, which
+// leaves it to its callers to pick how every subframe is coded. Each channel
+// of a block is stored as a constant, as the residual of the fixed predictor
+// that compresses it best, or verbatim when nothing does.
+type FLACEncoder struct {
+	w            io.WriteSeeker
+	encoder      *flac.Encoder
+	sampleRate   uint32
+	block        [][]int32
+	totalSamples uint64
+	residual     []int32
+}
+
+// flacWriteSeeker hides Close from the encoder, which would otherwise close
+// the file it writes to
+type flacWriteSeeker struct {
+	io.WriteSeeker
+}
+
+// NewFLACEncoder creates a new FLACEncoder, writing a header with no audio yet
+func NewFLACEncoder(w io.WriteSeeker, sampleRate uint32, channels int) (*FLACEncoder, error) {
+	encoder, err := flac.NewEncoder(flacWriteSeeker{w}, &meta.StreamInfo{
+		BlockSizeMin:  flacBlockSize,
+		BlockSizeMax:  flacBlockSize,
+		SampleRate:    sampleRate,
+		NChannels:     uint8(channels),
+		BitsPerSample: flacBitsPerSample,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	e := &FLACEncoder{
+		w:          w,
+		encoder:    encoder,
+		sampleRate: sampleRate,
+		block:      make([][]int32, channels),
+		residual:   make([]int32, flacBlockSize),
+	}
+	for ch := range e.block {
+		e.block[ch] = make([]int32, 0, flacBlockSize)
+	}
+
+	return e, nil
+}
+
+// Write implements AudioEncoder
+func (e *FLACEncoder) Write(samples []float32) error {
+	channels := len(e.block)
+	if e.totalSamples+uint64(len(samples)/channels) >= 1<<36 {
+		return errRecordingTooLong
+	}
+
+	for i := 0; i+channels <= len(samples); i += channels {
+		for ch := 0; ch < channels; ch++ {
+			e.block[ch] = append(e.block[ch], int32(quantizeSample(samples[i+ch])))
+		}
+
+		if len(e.block[0]) == flacBlockSize {
+			if err := e.writeFrame(); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+// Close implements AudioEncoder
+func (e *FLACEncoder) Close() error {
+	if len(e.block[0]) > 0 {
+		if err := e.writeFrame(); err != nil {
+			return err
+		}
+	}
+
+	// The encoder seeks back to complete STREAMINFO
+	if err := e.encoder.Close(); err != nil {
+		return err
+	}
+
+	_, err := e.w.Seek(0, io.SeekEnd)
+
+	return err
+}
+
+// writeFrame encodes the block as a frame of independent channels and starts
+// the next block
+func (e *FLACEncoder) writeFrame() error {
+	blockSize := len(e.block[0])
+
+	f := &frame.Frame{
+		Header: frame.Header{
+			HasFixedBlockSize: true,
+			BlockSize:         uint16(blockSize),
+			SampleRate:        e.sampleRate,
+			Channels:          frame.Channels(len(e.block) - 1),
+			BitsPerSample:     flacBitsPerSample,
+		},
+		Subframes: make([]*frame.Subframe, len(e.block)),
+	}
+	for ch, samples := range e.block {
+		f.Subframes[ch] = e.subframe(samples)
+	}
+
+	if err := e.encoder.WriteFrame(f); err != nil {
+		return err
+	}
+
+	e.totalSamples += uint64(blockSize)
+	for ch := range e.block {
+		e.block[ch] = e.block[ch][:0]
+	}
+
+	return nil
+}
+
+// subframe codes one channel of the block in whichever way is smallest
+func (e *FLACEncoder) subframe(samples []int32) *frame.Subframe {
+	subframe := &frame.Subframe{
+		SubHeader: frame.SubHeader{Pred: frame.PredConstant},
+		Samples:   samples,
+		NSamples:  len(samples),
+	}
+
+	constant := true
+	for _, s := range samples[1:] {
+		if s != samples[0] {
+			constant = false
+			break
+		}
+	}
+	if constant {
+		return subframe
+	}
+
+	bestOrder, bestParameter := -1, 0
+	bestSize := uint64(len(samples)) * flacBitsPerSample
+	for order := 0; order <= flacMaxFixedOrder && order < len(samples); order++ {
+		residual := fixedResidual(e.residual, samples, order)
+		riceParameter, riceSize := bestRiceParameter(residual)
+		if size := uint64(order*flacBitsPerSample) + 2 + 4 + 5 + riceSize; size < bestSize {
+			bestOrder, bestParameter, bestSize = order, riceParameter, size
+		}
+	}
+
+	if bestOrder < 0 {
+		subframe.Pred = frame.PredVerbatim
+		return subframe
+	}
+
+	// A single partition, with a 5-bit Rice parameter
+	subframe.Pred = frame.PredFixed
+	subframe.Order = bestOrder
+	subframe.ResidualCodingMethod = frame.ResidualCodingMethodRice2
+	subframe.RiceSubframe = &frame.RiceSubframe{
+		Partitions: []frame.RicePartition{{Param: uint(bestParameter)}},
+	}
+
+	return subframe
+}
+
+// fixedResidual computes what the fixed predictor of the order leaves over of
+// the samples after its warm-up, which is their order-th difference
+func fixedResidual(residual []int32, samples []int32, order int) []int32 {
+	residual = residual[:len(samples)-order]
+	for i := range residual {
+		x := samples[i : i+order+1]
+		switch order {
+		case 0:
+			residual[i] = x[0]
+		case 1:
+			residual[i] = x[1] - x[0]
+		case 2:
+			residual[i] = x[2] - 2*x[1] + x[0]
+		case 3:
+			residual[i] = x[3] - 3*x[2] + 3*x[1] - x[0]
+		case 4:
+			residual[i] = x[4] - 4*x[3] + 6*x[2] - 4*x[1] + x[0]
+		}
+	}
+	return residual
+}
+
+// bestRiceParameter picks the Rice parameter around the mean of the residual
+// that codes it in the fewest bits, returning it with that number of bits
+func bestRiceParameter(residual []int32) (int, uint64) {
+	var sum uint64
+	for _, r := range residual {
+		sum += uint64(zigzag(r))
+	}
+	estimate := bits.Len64(sum / uint64(max(len(residual), 1)))
+
+	bestParameter, bestSize := 0, uint64(0)
+	for parameter := max(estimate-1, 0); parameter <= min(estimate+1, 30); parameter++ {
+		size := uint64(len(residual)) * uint64(parameter+1)
+		for _, r := range residual {
+			size += uint64(zigzag(r) >> parameter)
+		}
+		if bestSize == 0 || size < bestSize {
+			bestParameter, bestSize = parameter, size
+		}
+	}
+
+	return bestParameter, bestSize
+}
+
+// zigzag folds a signed value into an unsigned one, alternating the signs
+func zigzag(v int32) uint32 {
+	return uint32(v<<1) ^ uint32(v>>31)
+}
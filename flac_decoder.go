@@ -0,0 +1,108 @@
+package main
+
+import (
+	"encoding/binary"
+	"fmt"
+	"io"
+
+	"github.com/mewkiz/flac"
+)
+
+// flacMaxBitsPerSample is the largest sample size FLAC files are read at
+const flacMaxBitsPerSample = 24
+
+// FLACDecoder reads FLAC files with github.com/mewkiz/flac as interleaved
+// little-endian PCM, 16-bit for files of up to 16 bits per sample and 32-bit
+// for deeper ones
+type FLACDecoder struct {
+	stream     *flac.Stream
+	channels   int
+	sampleSize int
+	shift      int
+	pcm        []byte
+	offset     int
+}
+
+// NewFLACDecoder creates a new FLACDecoder, reading the metadata up to the
+// first frame
+func NewFLACDecoder(r io.Reader) (*FLACDecoder, error) {
+	stream, err := flac.New(r)
+	if err != nil {
+		return nil, err
+	}
+
+	bitsPerSample := int(stream.Info.BitsPerSample)
+	if bitsPerSample > flacMaxBitsPerSample {
+		return nil, fmt.Errorf("unsupported FLAC bits per sample: %d", bitsPerSample)
+	}
+
+	d := &FLACDecoder{
+		stream:     stream,
+		channels:   int(stream.Info.NChannels),
+		sampleSize: 2,
+	}
+	if bitsPerSample > 16 {
+		d.sampleSize = 4
+	}
+	d.shift = d.sampleSize*8 - bitsPerSample
+
+	return d, nil
+}
+
+// SampleRate returns the sample rate of the file
+func (d *FLACDecoder) SampleRate() uint32 {
+	return d.stream.Info.SampleRate
+}
+
+// Channels returns the number of channels of the file
+func (d *FLACDecoder) Channels() int {
+	return d.channels
+}
+
+// SampleSize returns the size in bytes of the samples Read returns
+func (d *FLACDecoder) SampleSize() int {
+	return d.sampleSize
+}
+
+// Read implements io.Reader
+func (d *FLACDecoder) Read(p []byte) (int, error) {
+	for d.offset == len(d.pcm) {
+		if err := d.readFrame(); err != nil {
+			return 0, err
+		}
+	}
+
+	n := copy(p, d.pcm[d.offset:])
+	d.offset += n
+
+	return n, nil
+}
+
+// readFrame decodes the next frame into little-endian PCM, shifting the
+// samples up to the size they are read at, and returns io.EOF after the last
+func (d *FLACDecoder) readFrame() error {
+	f, err := d.stream.ParseNext()
+	if err != nil {
+		return err
+	}
+
+	blockSize := int(f.BlockSize)
+	size := blockSize * d.channels * d.sampleSize
+	if cap(d.pcm) < size {
+		d.pcm = make([]byte, size)
+	}
+	d.pcm, d.offset = d.pcm[:size], 0
+
+	for i := 0; i < blockSize; i++ {
+		for ch, subframe := range f.Subframes {
+			o := (i*d.channels + ch) * d.sampleSize
+			if d.sampleSize == 2 {
+				binary.LittleEndian.PutUint16(d.pcm[o:], uint16(subframe.Samples[i]<<d.shift))
+			} else {
+				binary.LittleEndian.PutUint32(d.pcm[o:], uint32(subframe.Samples[i]<<d.shift))
+			}
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,166 @@
+package main
+
+import (
+	"bytes"
+	"encoding/binary"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/gen2brain/malgo"
+	"github.com/mewkiz/flac"
+	"github.com/mewkiz/flac/frame"
+	"github.com/mewkiz/flac/meta"
+)
+
+func TestFLACDecoder(t *testing.T) {
+	tests := []struct {
+		name     string
+		channels int
+		frames   int
+		silent   bool
+	}{
+		{"mono", 1, 100, false},
+		{"stereo over several blocks", 2, 2*flacBlockSize + 17, false},
+		{"silence", 2, flacBlockSize, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			samples := make([]float32, tt.frames*tt.channels)
+			if !tt.silent {
+				tone := sine(440, 0.5)
+				for i := range samples {
+					samples[i] = tone[i/tt.channels%len(tone)] * float32(1+i%tt.channels) / 2
+				}
+			}
+
+			filePath := filepath.Join(t.TempDir(), "recording.flac")
+			file, err := os.Create(filePath)
+			if err != nil {
+				t.Fatal(err)
+			}
+			encoder, err := NewFLACEncoder(file, testSampleRate, tt.channels)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if err := encoder.Write(samples); err != nil {
+				t.Fatal(err)
+			}
+			if err := encoder.Close(); err != nil {
+				t.Fatal(err)
+			}
+			file.Close()
+
+			stream, err := openAudioStream(filePath)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer stream.Close()
+
+			if stream.format != malgo.FormatS16 || stream.sampleRate != testSampleRate || stream.channels != uint32(tt.channels) {
+				t.Fatalf("got format %v at %d Hz with %d channels", stream.format, stream.sampleRate, stream.channels)
+			}
+
+			pcm, err := io.ReadAll(stream)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(pcm) != len(samples)*2 {
+				t.Fatalf("got %d samples, want %d", len(pcm)/2, len(samples))
+			}
+			for i, s := range samples {
+				if got, want := int16(binary.LittleEndian.Uint16(pcm[i*2:])), quantizeSample(s); got != want {
+					t.Fatalf("sample %d is %d, want %d", i, got, want)
+				}
+			}
+		})
+	}
+}
+
+// flacTestStream writes a stereo 24-bit FLAC stream of a single frame in the
+// ways FLACEncoder never does: mid and side channels at a sample size that is
+// read as 32-bit
+func flacTestStream(t *testing.T, left []int32, right []int32) []byte {
+	t.Helper()
+
+	var stream bytes.Buffer
+	encoder, err := flac.NewEncoder(&stream, &meta.StreamInfo{
+		BlockSizeMin:  uint16(len(left)),
+		BlockSizeMax:  uint16(len(left)),
+		SampleRate:    testSampleRate,
+		NChannels:     2,
+		BitsPerSample: 24,
+		NSamples:      uint64(len(left)),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	f := &frame.Frame{
+		Header: frame.Header{
+			HasFixedBlockSize: true,
+			BlockSize:         uint16(len(left)),
+			SampleRate:        testSampleRate,
+			Channels:          frame.ChannelsMidSide,
+			BitsPerSample:     24,
+		},
+	}
+	for _, samples := range [][]int32{left, right} {
+		f.Subframes = append(f.Subframes, &frame.Subframe{
+			SubHeader: frame.SubHeader{Pred: frame.PredVerbatim},
+			Samples:   samples,
+			NSamples:  len(samples),
+		})
+	}
+	if err := encoder.WriteFrame(f); err != nil {
+		t.Fatal(err)
+	}
+	if err := encoder.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	return stream.Bytes()
+}
+
+func TestFLACDecoderMidSide(t *testing.T) {
+	left := make([]int32, 16)
+	right := make([]int32, 16)
+	for i := range left {
+		left[i], right[i] = int32(i*100000-800000), int32(50000-i*i*1000)
+	}
+	stream := flacTestStream(t, left, right)
+
+	decoder, err := NewFLACDecoder(bytes.NewReader(stream))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if decoder.SampleSize() != 4 {
+		t.Fatalf("got %d byte samples, want 4", decoder.SampleSize())
+	}
+	pcm, err := io.ReadAll(decoder)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(pcm) != len(left)*8 {
+		t.Fatalf("got %d frames, want %d", len(pcm)/8, len(left))
+	}
+	for i := range left {
+		l, r := int32(binary.LittleEndian.Uint32(pcm[i*8:])), int32(binary.LittleEndian.Uint32(pcm[i*8+4:]))
+		if l != left[i]<<8 || r != right[i]<<8 {
+			t.Errorf("frame %d is %d, %d, want %d, %d", i, l, r, left[i]<<8, right[i]<<8)
+		}
+	}
+
+	// A damaged frame fails its CRC
+	stream[len(stream)-5] ^= 0x10
+	decoder, err = NewFLACDecoder(bytes.NewReader(stream))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := io.ReadAll(decoder); err == nil {
+		t.Error("read a damaged frame without an error")
+	}
+}
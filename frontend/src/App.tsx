@@ -1,20 +1,99 @@
 import { type Component, createSignal, For, Show } from 'solid-js'
 import { OpenMultipleFilesDialog } from '../wailsjs/go/main/App'
 import { main } from '../wailsjs/go/models'
+import { useAudioBackend } from './useAudioBackend'
+import { useAudioBackends } from './useAudioBackends'
+import { useAudioFileBuses } from './useAudioFileBuses'
 import { useAudioFileKeybindings } from './useAudioFileKeybindings'
+import { useAudioFileLoops } from './useAudioFileLoops'
 import { useAudioFiles } from './useAudioFiles'
 import { useCaptureDeviceID } from './useCaptureDeviceID'
 import { useCaptureDevices } from './useCaptureDevices'
+import { useClipRecording } from './useClipRecording'
+import { useDuckingSettings } from './useDuckingSettings'
+import { useEffectKeybindings } from './useEffectKeybindings'
+import { useEffectProfile } from './useEffectProfile'
+import { useEffectProfiles } from './useEffectProfiles'
+import { useEffectSettings } from './useEffectSettings'
+import { useHotkeyCapture } from './useHotkeyCapture'
+import { useLoopbackDevices } from './useLoopbackDevices'
+import { useLoopbackSettings } from './useLoopbackSettings'
+import { useLoopbackStats } from './useLoopbackStats'
+import { useMIDIDevices } from './useMIDIDevices'
+import { useMIDIInputDeviceID } from './useMIDIInputDeviceID'
+import { useMIDILearn } from './useMIDILearn'
+import { useMonitorDeviceID } from './useMonitorDeviceID'
+import { useMonitorSettings } from './useMonitorSettings'
+import { useNoiseSuppressionSettings } from './useNoiseSuppressionSettings'
+import { useOutputBuses } from './useOutputBuses'
+import { useOSCSettings } from './useOSCSettings'
 import { usePlaybackDeviceID } from './usePlaybackDeviceID'
 import { usePlaybackDevices } from './usePlaybackDevices'
+import { useRecording } from './useRecording'
+import { useReplayBufferSettings } from './useReplayBufferSettings'
+import { useRemoteControlSettings } from './useRemoteControlSettings'
+import { useVirtualMicSettings } from './useVirtualMicSettings'
+
+// voiceEffects are the effects toggled from the audio dialog and by hotkey
+const voiceEffects = [
+  { effect: 'pitchShift', label: 'Pitch shift' },
+  { effect: 'robot', label: 'Robot' },
+  { effect: 'delay', label: 'Echo' },
+  { effect: 'reverb', label: 'Reverb' },
+] as const
+
+// duckingRules are the sides of the mix that can be ducked under the other one
+const duckingRules = [
+  { rule: 'mic', name: 'duckMic', label: 'Duck the mic while clips play on the main output' },
+  { rule: 'clips', name: 'duckClips', label: 'Duck clips while I speak' },
+] as const
 
 const App: Component = () => {
   const { audioFileKeybindings, setAudioFileKeybinding, removeAudioFileKeybinding } = useAudioFileKeybindings()
+  const { audioFileLoops, setAudioFileLoop } = useAudioFileLoops()
+  const { audioFileBuses, setAudioFileBuses } = useAudioFileBuses()
   const { audioFiles, addAudioFile, removeAudioFile, playAudioFile, stopAudioFile } = useAudioFiles()
-  const { captureDeviceID, setCaptureDeviceID } = useCaptureDeviceID()
+  const { audioBackends } = useAudioBackends()
+  const { audioBackend, setAudioBackend } = useAudioBackend()
+  const { captureDeviceID, refetchCaptureDeviceID, setCaptureDeviceID } = useCaptureDeviceID()
   const { captureDevices, refetchCaptureDevices } = useCaptureDevices()
-  const { playbackDeviceID, setPlaybackDeviceID } = usePlaybackDeviceID()
+  const { playbackDeviceID, refetchPlaybackDeviceID, setPlaybackDeviceID } = usePlaybackDeviceID()
   const { playbackDevices, refetchPlaybackDevices } = usePlaybackDevices()
+  const { loopbackDevices } = useLoopbackDevices()
+  const { loopbackSettings, setLoopbackSettings } = useLoopbackSettings()
+  const { loopbackStats, isMeasuringLatency, measureLatency } = useLoopbackStats()
+  const { effectProfiles, refetchEffectProfiles, deleteEffectProfile } = useEffectProfiles()
+  const { effectProfile, refetchEffectProfile, setEffectProfile } = useEffectProfile()
+  const { effectSettings, refetchEffectSettings, setEffectSettings, toggleEffect } = useEffectSettings()
+  const { duckingSettings, setDuckingSettings } = useDuckingSettings()
+  const { monitorSettings, setMonitorSettings } = useMonitorSettings()
+  const { monitorDeviceID, setMonitorDeviceID } = useMonitorDeviceID()
+  const { virtualMicSettings, setVirtualMicSettings } = useVirtualMicSettings()
+  const { outputBuses, setOutputBus, removeOutputBus, setOutputBusDeviceID } = useOutputBuses()
+  const { effectKeybindings, setEffectKeybinding } = useEffectKeybindings()
+  const { noiseSuppressionSettings, setNoiseSuppressionSettings, isLearningNoiseFloor, learnNoiseFloor } = useNoiseSuppressionSettings()
+  const { midiInputDeviceID, setMIDIInputDeviceID } = useMIDIInputDeviceID()
+  const { midiDevices, refetchMIDIDevices } = useMIDIDevices()
+  const { remoteControlSettings, setRemoteControlSettings } = useRemoteControlSettings()
+  const { oscSettings, setOSCSettings } = useOSCSettings()
+  const { recording, startRecording, stopRecording } = useRecording()
+  const { isRecordingClip, clipRecordingLevel, recordClip, stopClipRecording } = useClipRecording()
+  const { replayBufferSettings, setReplayBufferSettings, saveReplay } = useReplayBufferSettings()
+  const {
+    capturedKeybinding: capturedReplayKeybinding,
+    setCapturedKeybinding: setCapturedReplayKeybinding,
+    beginHotkeyCapture: beginReplayKeybindingCapture,
+    endHotkeyCapture: endReplayKeybindingCapture,
+  } = useHotkeyCapture()
+  const [isCapturingReplayKeybinding, setIsCapturingReplayKeybinding] = createSignal(false)
+
+  // Clips can be routed to the built-in buses and to every added bus
+  const busNames = () => ['main', 'monitor', ...outputBuses().map(bus => bus.name)]
+  const busDevice = (bus: string) => loopbackDevices()?.buses?.find(device => device.bus === bus)
+
+  let remoteControlDialog: HTMLDialogElement | undefined
+  let audioSettingsDialog: HTMLDialogElement | undefined
+  let recordingDialog: HTMLDialogElement | undefined
 
   const handleCaptureDeviceIDChange = async (event: Event & { currentTarget: HTMLSelectElement, target: HTMLSelectElement }) => {
     await setCaptureDeviceID(event.currentTarget.value)
@@ -24,6 +103,30 @@ const App: Component = () => {
     await setPlaybackDeviceID(event.currentTarget.value)
   }
 
+  const handleMIDIInputDeviceIDChange = async (event: Event & { currentTarget: HTMLSelectElement, target: HTMLSelectElement }) => {
+    await setMIDIInputDeviceID(event.currentTarget.value)
+  }
+
+  const handleEffectProfileChange = async (effectProfile: string) => {
+    await setEffectProfile(effectProfile)
+    await Promise.all([refetchEffectProfiles(), refetchEffectSettings()])
+  }
+
+  const handleEffectProfileDelete = async () => {
+    const name = effectProfile()
+    if (!name) {
+      return
+    }
+    await deleteEffectProfile(name)
+    await Promise.all([refetchEffectProfile(), refetchEffectSettings()])
+  }
+
+  const handleAudioBackendChange = async (event: Event & { currentTarget: HTMLSelectElement, target: HTMLSelectElement }) => {
+    await setAudioBackend(event.currentTarget.value)
+    // Devices are matched by name on the new backend
+    await Promise.all([refetchCaptureDeviceID(), refetchPlaybackDeviceID()])
+  }
+
   const handleCaptureDevicesFocus = async () => {
     await refetchCaptureDevices()
   }
@@ -32,10 +135,30 @@ const App: Component = () => {
     await refetchPlaybackDevices()
   }
 
+  const handleMIDIDevicesFocus = async () => {
+    await refetchMIDIDevices()
+  }
+
+  const handleReplayKeybindingCapture = async () => {
+    if (!isCapturingReplayKeybinding()) {
+      setIsCapturingReplayKeybinding(true)
+      await beginReplayKeybindingCapture()
+      return
+    }
+
+    setIsCapturingReplayKeybinding(false)
+    await endReplayKeybindingCapture()
+    const settings = replayBufferSettings()
+    if (capturedReplayKeybinding() !== '' && settings) {
+      await setReplayBufferSettings(main.ReplayBufferSettings.createFrom({ ...settings, keybinding: capturedReplayKeybinding() }))
+      setCapturedReplayKeybinding('')
+    }
+  }
+
   const handleOpenMultipleFilesDialog = async () => {
     const files = await OpenMultipleFilesDialog({
       title: 'Select audio files',
-      filters: [{ displayName: 'Audio files', pattern: '*.mp3;*.wav;*.ogg' }],
+      filters: [{ displayName: 'Audio files', pattern: '*.mp3;*.wav;*.flac;*.ogg' }],
     } as main.OpenDialogOptions)
 
     files.forEach((file) => {
@@ -68,8 +191,1128 @@ const App: Component = () => {
                 📂
               </button>
             </li>
+            <li>
+              <button
+                class="outline"
+                onClick={() => {
+                  remoteControlDialog?.show()
+                }}
+              >
+                📡
+              </button>
+            </li>
+            <li>
+              <button
+                class="outline"
+                onClick={() => {
+                  audioSettingsDialog?.show()
+                }}
+              >
+                🎚️
+              </button>
+            </li>
+            <li>
+              <button
+                class={recording()?.recording ? undefined : 'outline'}
+                onClick={() => {
+                  recordingDialog?.show()
+                }}
+              >
+                ⏺️
+              </button>
+            </li>
           </ul>
         </nav>
+        <dialog ref={remoteControlDialog}>
+          <article>
+            <header>
+              Remote control
+            </header>
+            <form
+              onSubmit={(event) => {
+                event.preventDefault()
+                const form = new FormData(event.currentTarget)
+                setRemoteControlSettings({
+                  enabled: form.get('enabled') === 'on',
+                  address: form.get('address') as string,
+                  token: form.get('token') as string,
+                }).catch((err: unknown) => {
+                  console.error(err)
+                })
+              }}
+            >
+              <label>
+                <input
+                  type="checkbox"
+                  role="switch"
+                  name="enabled"
+                  checked={remoteControlSettings()?.enabled}
+                />
+                HTTP
+              </label>
+              <label>
+                Address
+                <input
+                  type="text"
+                  name="address"
+                  value={remoteControlSettings()?.address ?? ''}
+                />
+              </label>
+              <label>
+                Token
+                <input
+                  type="text"
+                  name="token"
+                  value={remoteControlSettings()?.token ?? ''}
+                />
+              </label>
+              <button type="submit">
+                Save
+              </button>
+            </form>
+            <form
+              onSubmit={(event) => {
+                event.preventDefault()
+                const form = new FormData(event.currentTarget)
+                setOSCSettings({
+                  enabled: form.get('enabled') === 'on',
+                  address: form.get('address') as string,
+                }).catch((err: unknown) => {
+                  console.error(err)
+                })
+              }}
+            >
+              <label>
+                <input
+                  type="checkbox"
+                  role="switch"
+                  name="enabled"
+                  checked={oscSettings()?.enabled}
+                />
+                OSC
+              </label>
+              <label>
+                Address
+                <input
+                  type="text"
+                  name="address"
+                  value={oscSettings()?.address ?? ''}
+                />
+              </label>
+              <button type="submit">
+                Save
+              </button>
+            </form>
+            <footer>
+              <button
+                onClick={() => {
+                  remoteControlDialog?.close()
+                }}
+              >
+                Close
+              </button>
+            </footer>
+          </article>
+        </dialog>
+        <dialog ref={recordingDialog}>
+          <article>
+            <header>
+              Recording
+            </header>
+            <form
+              onSubmit={(event) => {
+                event.preventDefault()
+                if (recording()?.recording) {
+                  stopRecording().catch((err: unknown) => {
+                    console.error(err)
+                  })
+                  return
+                }
+                const form = new FormData(event.currentTarget)
+                startRecording(form.get('format') as string, form.get('bus') as string).catch((err: unknown) => {
+                  console.error(err)
+                })
+              }}
+            >
+              <div class="grid">
+                <label>
+                  Format
+                  <select name="format" disabled={recording()?.recording}>
+                    <option value="wav">WAV</option>
+                    <option value="flac">FLAC</option>
+                  </select>
+                </label>
+                <label>
+                  Bus
+                  <select name="bus" disabled={recording()?.recording}>
+                    <For each={busNames()}>
+                      {bus => (
+                        <option value={bus}>
+                          {bus === 'main' ? 'Mix' : bus}
+                        </option>
+                      )}
+                    </For>
+                  </select>
+                </label>
+              </div>
+              <Show when={recording()?.path}>
+                <p>
+                  <small>
+                    {recording()?.path}
+                    {' · '}
+                    {new Date((recording()?.seconds ?? 0) * 1000).toISOString().substring(11, 19)}
+                    {' · '}
+                    {((recording()?.bytes ?? 0) / 1048576).toFixed(1)}
+                    {' MB'}
+                  </small>
+                </p>
+              </Show>
+              <Show when={recording()?.error}>
+                <p>
+                  <small>
+                    {recording()?.error}
+                  </small>
+                </p>
+              </Show>
+              <button type="submit">
+                {recording()?.recording ? 'Stop' : 'Record'}
+              </button>
+            </form>
+            <hr />
+            <label>
+              Clip level
+              <meter
+                min="-60"
+                max="0"
+                low="-20"
+                high="-6"
+                optimum="-12"
+                value={clipRecordingLevel()?.peakDb ?? -60}
+              />
+            </label>
+            <button
+              class={isRecordingClip() ? undefined : 'outline'}
+              title="Silence at either end is trimmed off"
+              onClick={() => {
+                if (isRecordingClip()) {
+                  stopClipRecording().catch((err: unknown) => {
+                    console.error(err)
+                  })
+                  return
+                }
+                recordClip().catch((err: unknown) => {
+                  console.error(err)
+                })
+              }}
+            >
+              {isRecordingClip() ? `Stop clip (${(clipRecordingLevel()?.seconds ?? 0).toFixed(1)} s)` : 'Record clip'}
+            </button>
+            <hr />
+            <form
+              onSubmit={(event) => {
+                event.preventDefault()
+                const form = new FormData(event.currentTarget)
+                setReplayBufferSettings(main.ReplayBufferSettings.createFrom({
+                  enabled: form.get('replayBuffer') === 'on',
+                  seconds: Number(form.get('replaySeconds')),
+                  source: form.get('replaySource') as string,
+                  keybinding: replayBufferSettings()?.keybinding ?? '',
+                })).catch((err: unknown) => {
+                  console.error(err)
+                })
+              }}
+            >
+              <label>
+                <input
+                  type="checkbox"
+                  role="switch"
+                  name="replayBuffer"
+                  checked={replayBufferSettings()?.enabled}
+                />
+                Replay buffer
+              </label>
+              <div class="grid">
+                <label>
+                  Seconds
+                  <input
+                    type="number"
+                    name="replaySeconds"
+                    min="30"
+                    max="120"
+                    value={replayBufferSettings()?.seconds ?? 30}
+                  />
+                </label>
+                <label>
+                  Source
+                  <select name="replaySource">
+                    <option value="mic" selected={replayBufferSettings()?.source === 'mic'}>Mic</option>
+                    <option value="main" selected={replayBufferSettings()?.source === 'main'}>Mix</option>
+                  </select>
+                </label>
+              </div>
+              <fieldset role="group">
+                <input
+                  type="text"
+                  readOnly
+                  aria-label="Save replay hotkey"
+                  placeholder="No hotkey"
+                  value={
+                    capturedReplayKeybinding() !== ''
+                      ? capturedReplayKeybinding()
+                      : replayBufferSettings()?.keybinding ?? ''
+                  }
+                />
+                <button
+                  type="button"
+                  class="outline"
+                  aria-busy={isCapturingReplayKeybinding()}
+                  onClick={() => {
+                    handleReplayKeybindingCapture().catch((err: unknown) => {
+                      console.error(err)
+                    })
+                  }}
+                >
+                  ⌨️
+                </button>
+                <button
+                  type="button"
+                  class="outline"
+                  disabled={!replayBufferSettings()?.enabled}
+                  onClick={() => {
+                    saveReplay().catch((err: unknown) => {
+                      console.error(err)
+                    })
+                  }}
+                >
+                  💾
+                </button>
+              </fieldset>
+              <button type="submit">
+                Save replay buffer
+              </button>
+            </form>
+            <footer>
+              <button
+                onClick={() => {
+                  recordingDialog?.close()
+                }}
+              >
+                Close
+              </button>
+            </footer>
+          </article>
+        </dialog>
+        <dialog ref={audioSettingsDialog}>
+          <article>
+            <header>
+              Audio
+            </header>
+            <form
+              onSubmit={(event) => {
+                event.preventDefault()
+                const form = new FormData(event.currentTarget)
+                setLoopbackSettings({
+                  sampleRate: Number(form.get('sampleRate')),
+                  channels: Number(form.get('channels')),
+                  periodSizeInMilliseconds: Number(form.get('periodSizeInMilliseconds')),
+                  periods: Number(form.get('periods')),
+                  lowLatency: form.get('lowLatency') === 'on',
+                  targetFillMilliseconds: Number(form.get('targetFillMilliseconds')),
+                }).catch((err: unknown) => {
+                  console.error(err)
+                })
+              }}
+            >
+              <label>
+                Sample rate
+                <select name="sampleRate">
+                  <For each={[44100, 48000, 96000]}>
+                    {sampleRate => (
+                      <option
+                        selected={sampleRate === loopbackSettings()?.sampleRate}
+                        value={sampleRate}
+                      >
+                        {sampleRate}
+                        {' '}
+                        Hz
+                      </option>
+                    )}
+                  </For>
+                </select>
+              </label>
+              <label>
+                Channels
+                <select name="channels">
+                  <option selected={loopbackSettings()?.channels === 1} value="1">Mono</option>
+                  <option selected={loopbackSettings()?.channels === 2} value="2">Stereo</option>
+                </select>
+              </label>
+              <label>
+                Period size (ms, 0 for the backend default)
+                <input
+                  type="number"
+                  min="0"
+                  max="100"
+                  name="periodSizeInMilliseconds"
+                  value={loopbackSettings()?.periodSizeInMilliseconds ?? 0}
+                />
+              </label>
+              <label>
+                Periods (0 for the backend default)
+                <input
+                  type="number"
+                  min="0"
+                  max="16"
+                  name="periods"
+                  value={loopbackSettings()?.periods ?? 0}
+                />
+              </label>
+              <label>
+                Target fill (ms, 0 for the default)
+                <input
+                  type="number"
+                  min="0"
+                  max="1000"
+                  name="targetFillMilliseconds"
+                  value={loopbackSettings()?.targetFillMilliseconds ?? 0}
+                />
+              </label>
+              <label>
+                <input
+                  type="checkbox"
+                  role="switch"
+                  name="lowLatency"
+                  checked={loopbackSettings()?.lowLatency}
+                />
+                Low latency
+              </label>
+              <Show when={loopbackStats()?.estimatedLatencyMilliseconds}>
+                <p>
+                  <small>
+                    Estimated latency:
+                    {' '}
+                    {loopbackStats()?.estimatedLatencyMilliseconds}
+                    {' '}
+                    ms, underruns:
+                    {' '}
+                    {loopbackStats()?.underruns}
+                    , overruns:
+                    {' '}
+                    {loopbackStats()?.overruns}
+                    , dropped frames:
+                    {' '}
+                    {loopbackStats()?.droppedFrames}
+                    , clock drift:
+                    {' '}
+                    {loopbackStats()?.driftPpm}
+                    {' '}
+                    ppm
+                  </small>
+                </p>
+              </Show>
+              <Show when={loopbackStats()?.measuredLatencyMilliseconds}>
+                <p>
+                  <small>
+                    Measured latency:
+                    {' '}
+                    {loopbackStats()?.measuredLatencyMilliseconds}
+                    {' '}
+                    ms, round trip:
+                    {' '}
+                    {loopbackStats()?.roundTripMilliseconds}
+                    {' '}
+                    ms
+                  </small>
+                </p>
+              </Show>
+              <button
+                type="button"
+                class="secondary"
+                aria-busy={isMeasuringLatency()}
+                title="Plays a click, loop the output back to the input first"
+                onClick={() => {
+                  measureLatency().catch((err: unknown) => {
+                    console.error(err)
+                  })
+                }}
+              >
+                Measure latency
+              </button>
+              <button type="submit">
+                Save
+              </button>
+            </form>
+            <label>
+              <input
+                type="checkbox"
+                role="switch"
+                checked={virtualMicSettings()?.enabled}
+                onChange={(event) => {
+                  setVirtualMicSettings(main.VirtualMicSettings.createFrom({
+                    enabled: event.currentTarget.checked,
+                  })).catch((err: unknown) => {
+                    console.error(err)
+                  })
+                }}
+              />
+              Create the "various-yam Virtual Mic" for other apps and play to it (Linux)
+            </label>
+            <hr />
+            <form
+              onSubmit={(event) => {
+                event.preventDefault()
+                const form = new FormData(event.currentTarget)
+                setNoiseSuppressionSettings(main.NoiseSuppressionSettings.createFrom({
+                  enabled: form.get('noiseSuppression') === 'on',
+                  reductionDb: Number(form.get('noiseReductionDb')),
+                })).catch((err: unknown) => {
+                  console.error(err)
+                })
+              }}
+            >
+              <label>
+                <input
+                  type="checkbox"
+                  role="switch"
+                  name="noiseSuppression"
+                  checked={noiseSuppressionSettings()?.enabled}
+                />
+                Noise suppression for this microphone
+              </label>
+              <label>
+                Reduction (dB)
+                <input
+                  type="number"
+                  min="0"
+                  max="60"
+                  name="noiseReductionDb"
+                  value={noiseSuppressionSettings()?.reductionDb ?? 0}
+                />
+              </label>
+              <p>
+                <small>
+                  {noiseSuppressionSettings()?.noiseFloor?.length
+                    ? 'Noise floor learned.'
+                    : 'The noise floor is estimated as you go until you learn it.'}
+                </small>
+              </p>
+              <div class="grid">
+                <button type="submit">
+                  Save
+                </button>
+                <button
+                  type="button"
+                  class="secondary"
+                  aria-busy={isLearningNoiseFloor()}
+                  title="Stay quiet for a couple of seconds"
+                  onClick={() => {
+                    learnNoiseFloor().catch((err: unknown) => {
+                      console.error(err)
+                    })
+                  }}
+                >
+                  Learn noise floor
+                </button>
+              </div>
+            </form>
+            <hr />
+            <form
+              onSubmit={(event) => {
+                event.preventDefault()
+                const form = new FormData(event.currentTarget)
+                const newEffectProfile = String(form.get('effectProfile'))
+                event.currentTarget.reset()
+                handleEffectProfileChange(newEffectProfile).catch((err: unknown) => {
+                  console.error(err)
+                })
+              }}
+            >
+              <fieldset role="group">
+                <select
+                  aria-label="Effect profile"
+                  onChange={(event) => {
+                    handleEffectProfileChange(event.currentTarget.value).catch((err: unknown) => {
+                      console.error(err)
+                    })
+                  }}
+                >
+                  <For each={effectProfiles()}>
+                    {name => (
+                      <option selected={name === effectProfile()} value={name}>
+                        {name}
+                      </option>
+                    )}
+                  </For>
+                </select>
+                <input name="effectProfile" placeholder="New profile" required />
+                <button type="submit">
+                  Add
+                </button>
+                <button
+                  type="button"
+                  class="secondary"
+                  onClick={() => {
+                    handleEffectProfileDelete().catch((err: unknown) => {
+                      console.error(err)
+                    })
+                  }}
+                >
+                  Delete
+                </button>
+              </fieldset>
+            </form>
+            <For each={voiceEffects}>
+              {({ effect, label }) => {
+                const { capturedKeybinding, setCapturedKeybinding, beginHotkeyCapture, endHotkeyCapture } = useHotkeyCapture()
+                const [isCapturing, setIsCapturing] = createSignal(false)
+
+                const handleCapture = async () => {
+                  if (!isCapturing()) {
+                    setIsCapturing(true)
+                    await beginHotkeyCapture()
+                    return
+                  }
+
+                  setIsCapturing(false)
+                  await endHotkeyCapture()
+                  if (capturedKeybinding() !== '') {
+                    await setEffectKeybinding(effect, capturedKeybinding())
+                    setCapturedKeybinding('')
+                  }
+                }
+
+                return (
+                  <fieldset role="group">
+                    <button
+                      class={effectSettings()?.[effect].enabled ? undefined : 'outline'}
+                      onClick={() => {
+                        toggleEffect(effect).catch((err: unknown) => {
+                          console.error(err)
+                        })
+                      }}
+                    >
+                      {label}
+                    </button>
+                    <input
+                      type="text"
+                      readOnly
+                      aria-label={`${label} hotkey`}
+                      placeholder="No hotkey"
+                      value={
+                        capturedKeybinding() !== ''
+                          ? capturedKeybinding()
+                          : effectKeybindings()?.[effect] ?? ''
+                      }
+                    />
+                    <button
+                      class="outline"
+                      aria-busy={isCapturing()}
+                      onClick={() => {
+                        handleCapture().catch((err: unknown) => {
+                          console.error(err)
+                        })
+                      }}
+                    >
+                      ⌨️
+                    </button>
+                    <button
+                      class="outline"
+                      onClick={() => {
+                        setEffectKeybinding(effect, '').catch((err: unknown) => {
+                          console.error(err)
+                        })
+                      }}
+                    >
+                      🗑️
+                    </button>
+                  </fieldset>
+                )
+              }}
+            </For>
+            <form
+              onSubmit={(event) => {
+                event.preventDefault()
+                const form = new FormData(event.currentTarget)
+                const enabled = (name: string) => form.get(name) === 'on'
+                const number = (name: string) => Number(form.get(name))
+                setEffectSettings(main.EffectSettings.createFrom({
+                  noiseGate: {
+                    enabled: enabled('noiseGate'),
+                    thresholdDb: number('noiseGateThresholdDb'),
+                    attackMilliseconds: number('noiseGateAttackMilliseconds'),
+                    releaseMilliseconds: number('noiseGateReleaseMilliseconds'),
+                  },
+                  highPass: {
+                    enabled: enabled('highPass'),
+                    frequency: number('highPassFrequency'),
+                  },
+                  equalizer: {
+                    enabled: enabled('equalizer'),
+                    bands: effectSettings()?.equalizer.bands.map((_, i) => ({
+                      frequency: number(`equalizerBand${i}Frequency`),
+                      gainDb: number(`equalizerBand${i}GainDb`),
+                      q: number(`equalizerBand${i}Q`),
+                    })),
+                  },
+                  compressor: {
+                    enabled: enabled('compressor'),
+                    thresholdDb: number('compressorThresholdDb'),
+                    ratio: number('compressorRatio'),
+                    attackMilliseconds: number('compressorAttackMilliseconds'),
+                    releaseMilliseconds: number('compressorReleaseMilliseconds'),
+                    makeupGainDb: number('compressorMakeupGainDb'),
+                  },
+                  pitchShift: {
+                    enabled: enabled('pitchShift'),
+                    semitones: number('pitchShiftSemitones'),
+                  },
+                  robot: {
+                    enabled: enabled('robot'),
+                    frequency: number('robotFrequency'),
+                    mix: number('robotMix'),
+                  },
+                  delay: {
+                    enabled: enabled('delay'),
+                    delayMilliseconds: number('delayMilliseconds'),
+                    feedback: number('delayFeedback'),
+                    mix: number('delayMix'),
+                  },
+                  reverb: {
+                    enabled: enabled('reverb'),
+                    roomSize: number('reverbRoomSize'),
+                    damping: number('reverbDamping'),
+                    mix: number('reverbMix'),
+                  },
+                  gain: {
+                    enabled: enabled('gain'),
+                    gainDb: number('gainDb'),
+                  },
+                  limiter: {
+                    enabled: enabled('limiter'),
+                    ceilingDb: number('limiterCeilingDb'),
+                    releaseMilliseconds: number('limiterReleaseMilliseconds'),
+                  },
+                })).catch((err: unknown) => {
+                  console.error(err)
+                })
+              }}
+            >
+              <fieldset>
+                <label>
+                  <input type="checkbox" role="switch" name="noiseGate" checked={effectSettings()?.noiseGate.enabled} />
+                  Noise gate
+                </label>
+                <div class="grid">
+                  <label>
+                    Threshold (dB)
+                    <input type="number" step="any" name="noiseGateThresholdDb" value={effectSettings()?.noiseGate.thresholdDb ?? 0} />
+                  </label>
+                  <label>
+                    Attack (ms)
+                    <input type="number" step="any" min="0" name="noiseGateAttackMilliseconds" value={effectSettings()?.noiseGate.attackMilliseconds ?? 0} />
+                  </label>
+                  <label>
+                    Release (ms)
+                    <input type="number" step="any" min="0" name="noiseGateReleaseMilliseconds" value={effectSettings()?.noiseGate.releaseMilliseconds ?? 0} />
+                  </label>
+                </div>
+              </fieldset>
+              <fieldset>
+                <label>
+                  <input type="checkbox" role="switch" name="highPass" checked={effectSettings()?.highPass.enabled} />
+                  High-pass filter
+                </label>
+                <label>
+                  Frequency (Hz)
+                  <input type="number" step="any" min="1" name="highPassFrequency" value={effectSettings()?.highPass.frequency ?? 0} />
+                </label>
+              </fieldset>
+              <fieldset>
+                <label>
+                  <input type="checkbox" role="switch" name="equalizer" checked={effectSettings()?.equalizer.enabled} />
+                  Equalizer
+                </label>
+                <For each={effectSettings()?.equalizer.bands}>
+                  {(band, i) => (
+                    <div class="grid">
+                      <label>
+                        Frequency (Hz)
+                        <input type="number" step="any" min="1" name={`equalizerBand${i()}Frequency`} value={band.frequency} />
+                      </label>
+                      <label>
+                        Gain (dB)
+                        <input type="number" step="any" name={`equalizerBand${i()}GainDb`} value={band.gainDb} />
+                      </label>
+                      <label>
+                        Q
+                        <input type="number" step="any" min="0.1" name={`equalizerBand${i()}Q`} value={band.q} />
+                      </label>
+                    </div>
+                  )}
+                </For>
+              </fieldset>
+              <fieldset>
+                <label>
+                  <input type="checkbox" role="switch" name="compressor" checked={effectSettings()?.compressor.enabled} />
+                  Compressor
+                </label>
+                <div class="grid">
+                  <label>
+                    Threshold (dB)
+                    <input type="number" step="any" name="compressorThresholdDb" value={effectSettings()?.compressor.thresholdDb ?? 0} />
+                  </label>
+                  <label>
+                    Ratio
+                    <input type="number" step="any" min="1" name="compressorRatio" value={effectSettings()?.compressor.ratio ?? 1} />
+                  </label>
+                  <label>
+                    Makeup gain (dB)
+                    <input type="number" step="any" name="compressorMakeupGainDb" value={effectSettings()?.compressor.makeupGainDb ?? 0} />
+                  </label>
+                </div>
+                <div class="grid">
+                  <label>
+                    Attack (ms)
+                    <input type="number" step="any" min="0" name="compressorAttackMilliseconds" value={effectSettings()?.compressor.attackMilliseconds ?? 0} />
+                  </label>
+                  <label>
+                    Release (ms)
+                    <input type="number" step="any" min="0" name="compressorReleaseMilliseconds" value={effectSettings()?.compressor.releaseMilliseconds ?? 0} />
+                  </label>
+                </div>
+              </fieldset>
+              <fieldset>
+                <label>
+                  <input type="checkbox" role="switch" name="pitchShift" checked={effectSettings()?.pitchShift.enabled} />
+                  Pitch shift
+                </label>
+                <label>
+                  Semitones
+                  <input type="number" step="any" min="-24" max="24" name="pitchShiftSemitones" value={effectSettings()?.pitchShift.semitones ?? 0} />
+                </label>
+              </fieldset>
+              <fieldset>
+                <label>
+                  <input type="checkbox" role="switch" name="robot" checked={effectSettings()?.robot.enabled} />
+                  Robot
+                </label>
+                <div class="grid">
+                  <label>
+                    Frequency (Hz)
+                    <input type="number" step="any" min="1" name="robotFrequency" value={effectSettings()?.robot.frequency ?? 0} />
+                  </label>
+                  <label>
+                    Mix
+                    <input type="number" step="any" min="0" max="1" name="robotMix" value={effectSettings()?.robot.mix ?? 0} />
+                  </label>
+                </div>
+              </fieldset>
+              <fieldset>
+                <label>
+                  <input type="checkbox" role="switch" name="delay" checked={effectSettings()?.delay.enabled} />
+                  Echo
+                </label>
+                <div class="grid">
+                  <label>
+                    Delay (ms)
+                    <input type="number" step="any" min="1" max="2000" name="delayMilliseconds" value={effectSettings()?.delay.delayMilliseconds ?? 0} />
+                  </label>
+                  <label>
+                    Feedback
+                    <input type="number" step="any" min="0" max="0.95" name="delayFeedback" value={effectSettings()?.delay.feedback ?? 0} />
+                  </label>
+                  <label>
+                    Mix
+                    <input type="number" step="any" min="0" max="1" name="delayMix" value={effectSettings()?.delay.mix ?? 0} />
+                  </label>
+                </div>
+              </fieldset>
+              <fieldset>
+                <label>
+                  <input type="checkbox" role="switch" name="reverb" checked={effectSettings()?.reverb.enabled} />
+                  Reverb
+                </label>
+                <div class="grid">
+                  <label>
+                    Room size
+                    <input type="number" step="any" min="0" max="1" name="reverbRoomSize" value={effectSettings()?.reverb.roomSize ?? 0} />
+                  </label>
+                  <label>
+                    Damping
+                    <input type="number" step="any" min="0" max="1" name="reverbDamping" value={effectSettings()?.reverb.damping ?? 0} />
+                  </label>
+                  <label>
+                    Mix
+                    <input type="number" step="any" min="0" max="1" name="reverbMix" value={effectSettings()?.reverb.mix ?? 0} />
+                  </label>
+                </div>
+              </fieldset>
+              <fieldset>
+                <label>
+                  <input type="checkbox" role="switch" name="gain" checked={effectSettings()?.gain.enabled} />
+                  Gain
+                </label>
+                <label>
+                  Gain (dB)
+                  <input type="number" step="any" name="gainDb" value={effectSettings()?.gain.gainDb ?? 0} />
+                </label>
+              </fieldset>
+              <fieldset>
+                <label>
+                  <input type="checkbox" role="switch" name="limiter" checked={effectSettings()?.limiter.enabled} />
+                  Limiter
+                </label>
+                <div class="grid">
+                  <label>
+                    Ceiling (dB)
+                    <input type="number" step="any" name="limiterCeilingDb" value={effectSettings()?.limiter.ceilingDb ?? 0} />
+                  </label>
+                  <label>
+                    Release (ms)
+                    <input type="number" step="any" min="0" name="limiterReleaseMilliseconds" value={effectSettings()?.limiter.releaseMilliseconds ?? 0} />
+                  </label>
+                </div>
+              </fieldset>
+              <button type="submit">
+                Save effects
+              </button>
+            </form>
+            <hr />
+            <form
+              onSubmit={(event) => {
+                event.preventDefault()
+                const form = new FormData(event.currentTarget)
+                const rule = (name: string) => ({
+                  enabled: form.get(name) === 'on',
+                  thresholdDb: Number(form.get(`${name}ThresholdDb`)),
+                  depthDb: Number(form.get(`${name}DepthDb`)),
+                  attackMilliseconds: Number(form.get(`${name}AttackMilliseconds`)),
+                  releaseMilliseconds: Number(form.get(`${name}ReleaseMilliseconds`)),
+                })
+                setDuckingSettings(main.DuckingSettings.createFrom({
+                  mic: rule('duckMic'),
+                  clips: rule('duckClips'),
+                })).catch((err: unknown) => {
+                  console.error(err)
+                })
+              }}
+            >
+              <For each={duckingRules}>
+                {({ rule, name, label }) => {
+                  const settings = () => duckingSettings()?.[rule]
+
+                  return (
+                    <fieldset>
+                      <label>
+                        <input type="checkbox" role="switch" name={name} checked={settings()?.enabled} />
+                        {label}
+                      </label>
+                      <div class="grid">
+                        <label>
+                          Threshold (dB)
+                          <input type="number" step="any" name={`${name}ThresholdDb`} value={settings()?.thresholdDb ?? 0} />
+                        </label>
+                        <label>
+                          Depth (dB)
+                          <input type="number" step="any" min="0" max="60" name={`${name}DepthDb`} value={settings()?.depthDb ?? 0} />
+                        </label>
+                        <label>
+                          Attack (ms)
+                          <input type="number" step="any" min="0" name={`${name}AttackMilliseconds`} value={settings()?.attackMilliseconds ?? 0} />
+                        </label>
+                        <label>
+                          Release (ms)
+                          <input type="number" step="any" min="0" name={`${name}ReleaseMilliseconds`} value={settings()?.releaseMilliseconds ?? 0} />
+                        </label>
+                      </div>
+                    </fieldset>
+                  )
+                }}
+              </For>
+              <button type="submit">
+                Save ducking
+              </button>
+            </form>
+            <hr />
+            <form
+              onSubmit={(event) => {
+                event.preventDefault()
+                const form = new FormData(event.currentTarget)
+                // Both restart the loopback, so they go one after the other
+                setMonitorDeviceID(String(form.get('monitorDeviceId')))
+                  .then(async () => {
+                    await setMonitorSettings(main.MonitorSettings.createFrom({
+                      enabled: form.get('monitor') === 'on',
+                      volume: Number(form.get('monitorVolume')),
+                      includeMic: form.get('monitorMic') === 'on',
+                    }))
+                  })
+                  .catch((err: unknown) => {
+                    console.error(err)
+                  })
+              }}
+            >
+              <label>
+                <input
+                  type="checkbox"
+                  role="switch"
+                  name="monitor"
+                  checked={monitorSettings()?.enabled}
+                />
+                Monitor clips on a second device
+              </label>
+              <select
+                name="monitorDeviceId"
+                aria-invalid={busDevice('monitor')?.fallback ? 'true' : undefined}
+                title={busDevice('monitor')?.fallback ? 'Unavailable, using another device' : undefined}
+                onFocus={() => {
+                  handlePlaybackDevicesFocus().catch((err: unknown) => {
+                    console.error(err)
+                  })
+                }}
+              >
+                <For each={playbackDevices()}>
+                  {device => (
+                    <option
+                      selected={device.deviceId === monitorDeviceID()}
+                      value={device.deviceId}
+                    >
+                      {device.isDefault ? `${device.label} (default)` : device.label}
+                    </option>
+                  )}
+                </For>
+              </select>
+              <label>
+                Monitor volume
+                <input
+                  type="range"
+                  min="0"
+                  max="1"
+                  step="0.01"
+                  name="monitorVolume"
+                  value={monitorSettings()?.volume ?? 1}
+                />
+              </label>
+              <label>
+                <input
+                  type="checkbox"
+                  role="switch"
+                  name="monitorMic"
+                  checked={monitorSettings()?.includeMic}
+                />
+                Monitor the mic too
+              </label>
+              <button type="submit">
+                Save monitor
+              </button>
+            </form>
+            <hr />
+            <For each={outputBuses()}>
+              {bus => (
+                <fieldset>
+                  <legend>
+                    {bus.name}
+                  </legend>
+                  <select
+                    aria-invalid={busDevice(bus.name)?.fallback ? 'true' : undefined}
+                    title={busDevice(bus.name)?.fallback ? 'Unavailable, using another device' : undefined}
+                    onChange={(event) => {
+                      setOutputBusDeviceID(bus.name, event.currentTarget.value).catch((err: unknown) => {
+                        console.error(err)
+                      })
+                    }}
+                    onFocus={() => {
+                      handlePlaybackDevicesFocus().catch((err: unknown) => {
+                        console.error(err)
+                      })
+                    }}
+                  >
+                    <For each={playbackDevices()}>
+                      {device => (
+                        <option
+                          selected={device.deviceId === busDevice(bus.name)?.deviceId}
+                          value={device.deviceId}
+                        >
+                          {device.isDefault ? `${device.label} (default)` : device.label}
+                        </option>
+                      )}
+                    </For>
+                  </select>
+                  <label>
+                    Volume
+                    <input
+                      type="range"
+                      min="0"
+                      max="1"
+                      step="0.01"
+                      value={bus.volume}
+                      onChange={(event) => {
+                        setOutputBus(main.OutputBus.createFrom({ ...bus, volume: Number(event.currentTarget.value) })).catch((err: unknown) => {
+                          console.error(err)
+                        })
+                      }}
+                    />
+                  </label>
+                  <div class="grid">
+                    <label>
+                      <input
+                        type="checkbox"
+                        role="switch"
+                        checked={bus.includeMic}
+                        onChange={(event) => {
+                          setOutputBus(main.OutputBus.createFrom({ ...bus, includeMic: event.currentTarget.checked })).catch((err: unknown) => {
+                            console.error(err)
+                          })
+                        }}
+                      />
+                      Include the mic
+                    </label>
+                    <button
+                      class="outline"
+                      onClick={() => {
+                        removeOutputBus(bus.name).catch((err: unknown) => {
+                          console.error(err)
+                        })
+                      }}
+                    >
+                      🗑️
+                    </button>
+                  </div>
+                </fieldset>
+              )}
+            </For>
+            <form
+              onSubmit={(event) => {
+                event.preventDefault()
+                const form = new FormData(event.currentTarget)
+                setOutputBus(main.OutputBus.createFrom({
+                  name: String(form.get('outputBus')),
+                  volume: 1,
+                  includeMic: false,
+                })).catch((err: unknown) => {
+                  console.error(err)
+                })
+                event.currentTarget.reset()
+              }}
+            >
+              <fieldset role="group">
+                <input type="text" name="outputBus" placeholder="New output bus" required />
+                <button type="submit">
+                  Add bus
+                </button>
+              </fieldset>
+            </form>
+            <footer>
+              <button
+                onClick={() => {
+                  audioSettingsDialog?.close()
+                }}
+              >
+                Close
+              </button>
+            </footer>
+          </article>
+        </dialog>
       </header>
       <main
         class="container-fluid"
@@ -79,6 +1322,32 @@ const App: Component = () => {
         }}
       >
         <select
+          onChange={(event) => {
+            handleAudioBackendChange(event).catch((err: unknown) => {
+              console.error(err)
+            })
+          }}
+        >
+          <option
+            selected={audioBackend() === ''}
+            value=""
+          >
+            Automatic backend
+          </option>
+          <For each={audioBackends()}>
+            {backend => (
+              <option
+                selected={backend === audioBackend()}
+                value={backend}
+              >
+                {backend}
+              </option>
+            )}
+          </For>
+        </select>
+        <select
+          aria-invalid={loopbackDevices()?.captureFallback ? 'true' : undefined}
+          title={loopbackDevices()?.captureFallback ? 'Unavailable, using another device' : undefined}
           onChange={(event) => {
             handleCaptureDeviceIDChange(event).catch((err: unknown) => {
               console.error(err)
@@ -95,13 +1364,16 @@ const App: Component = () => {
               <option
                 selected={device.deviceId === captureDeviceID()}
                 value={device.deviceId}
+                title={device.maxSampleRate > 0 ? `${device.minChannels}–${device.maxChannels} channels, ${device.minSampleRate}–${device.maxSampleRate} Hz` : undefined}
               >
-                {device.label}
+                {device.isDefault ? `${device.label} (default)` : device.label}
               </option>
             )}
           </For>
         </select>
         <select
+          aria-invalid={loopbackDevices()?.playbackFallback ? 'true' : undefined}
+          title={loopbackDevices()?.playbackFallback ? 'Unavailable, using another device' : undefined}
           onChange={(event) => {
             handlePlaybackDeviceIDChange(event).catch((err: unknown) => {
               console.error(err)
@@ -118,6 +1390,36 @@ const App: Component = () => {
               <option
                 selected={device.deviceId === playbackDeviceID()}
                 value={device.deviceId}
+                title={device.maxSampleRate > 0 ? `${device.minChannels}–${device.maxChannels} channels, ${device.minSampleRate}–${device.maxSampleRate} Hz` : undefined}
+              >
+                {device.isDefault ? `${device.label} (default)` : device.label}
+              </option>
+            )}
+          </For>
+        </select>
+        <select
+          onChange={(event) => {
+            handleMIDIInputDeviceIDChange(event).catch((err: unknown) => {
+              console.error(err)
+            })
+          }}
+          onFocus={() => {
+            handleMIDIDevicesFocus().catch((err: unknown) => {
+              console.error(err)
+            })
+          }}
+        >
+          <option
+            selected={midiInputDeviceID() === ''}
+            value=""
+          >
+            No MIDI input
+          </option>
+          <For each={midiDevices()}>
+            {device => (
+              <option
+                selected={device.deviceId === midiInputDeviceID()}
+                value={device.deviceId}
               >
                 {device.label}
               </option>
@@ -127,36 +1429,18 @@ const App: Component = () => {
         <ul>
           <For each={audioFiles()}>
             {(audioFile) => {
-              const [heldKeys, setHeldKeys] = createSignal<string[]>([])
-              const [isRecording, setIsRecording] = createSignal(false)
+              const { capturedKeybinding, setCapturedKeybinding, beginHotkeyCapture, endHotkeyCapture } = useHotkeyCapture()
+              const { isLearningMIDI, beginMIDILearn, endMIDILearn } = useMIDILearn()
 
               let dialog: HTMLDialogElement | undefined
 
-              const handleKeyDown = (event: KeyboardEvent) => {
-                event.preventDefault()
-                if (!isRecording()) {
-                  setHeldKeys([])
-                  setIsRecording(true)
-                }
-                if (!heldKeys().includes(event.key)) {
-                  setHeldKeys([...heldKeys(), event.key])
-                }
-              }
-
-              const handleKeyUp = () => {
-                if (isRecording()) {
-                  setIsRecording(false)
-                }
-              }
-
               const handleSave = async () => {
-                if (heldKeys().length === 0) {
+                if (capturedKeybinding() === '') {
                   return
                 }
 
-                await setAudioFileKeybinding(audioFile, heldKeys().join(' + '))
-                setHeldKeys([])
-                setIsRecording(false)
+                await setAudioFileKeybinding(audioFile, capturedKeybinding())
+                setCapturedKeybinding('')
               }
 
               return (
@@ -177,10 +1461,24 @@ const App: Component = () => {
                     class="outline"
                     onClick={() => {
                       dialog?.show()
+                      beginHotkeyCapture().catch((err: unknown) => {
+                        console.error(err)
+                      })
                     }}
                   >
                     ⌨️
                   </button>
+                  <button
+                    class="outline"
+                    aria-busy={isLearningMIDI()}
+                    onClick={() => {
+                      (isLearningMIDI() ? endMIDILearn() : beginMIDILearn('playAudioFile', audioFile)).catch((err: unknown) => {
+                        console.error(err)
+                      })
+                    }}
+                  >
+                    🎹
+                  </button>
                   <button
                     class="outline"
                     onClick={() => {
@@ -191,6 +1489,45 @@ const App: Component = () => {
                   >
                     ▶️
                   </button>
+                  <details class="dropdown">
+                    <summary title="Buses">
+                      🔀
+                    </summary>
+                    <ul>
+                      <For each={busNames()}>
+                        {bus => (
+                          <li>
+                            <label>
+                              <input
+                                type="checkbox"
+                                checked={audioFileBuses()?.[audioFile]?.includes(bus) ?? true}
+                                onChange={(event) => {
+                                  // Clips without buses play on all of them
+                                  const buses = audioFileBuses()?.[audioFile] ?? busNames()
+                                  const checked = event.currentTarget.checked
+                                  const next = checked ? [...buses, bus] : buses.filter(name => name !== bus)
+                                  setAudioFileBuses(audioFile, next.length === busNames().length ? [] : next).catch((err: unknown) => {
+                                    console.error(err)
+                                  })
+                                }}
+                              />
+                              {bus}
+                            </label>
+                          </li>
+                        )}
+                      </For>
+                    </ul>
+                  </details>
+                  <button
+                    class={audioFileLoops()?.[audioFile] ? undefined : 'outline'}
+                    onClick={() => {
+                      setAudioFileLoop(audioFile, !audioFileLoops()?.[audioFile]).catch((err: unknown) => {
+                        console.error(err)
+                      })
+                    }}
+                  >
+                    🔁
+                  </button>
                   <button
                     class="outline"
                     onClick={() => {
@@ -211,7 +1548,14 @@ const App: Component = () => {
                   >
                     🗑️
                   </button>
-                  <dialog ref={dialog}>
+                  <dialog
+                    ref={dialog}
+                    onClose={() => {
+                      endHotkeyCapture().catch((err: unknown) => {
+                        console.error(err)
+                      })
+                    }}
+                  >
                     <article>
                       <header>
                         {audioFile}
@@ -219,18 +1563,16 @@ const App: Component = () => {
                       <fieldset role="group">
                         <input
                           type="text"
+                          readOnly
                           value={
-                            heldKeys().length > 0
-                              ? heldKeys().join(' + ')
+                            capturedKeybinding() !== ''
+                              ? capturedKeybinding()
                               : audioFileKeybindings()?.[audioFile] ?? ''
                           }
-                          onKeyDown={handleKeyDown}
-                          onKeyUp={handleKeyUp}
                         />
                         <button
                           onClick={() => {
-                            setHeldKeys([])
-                            setIsRecording(false)
+                            setCapturedKeybinding('')
                             removeAudioFileKeybinding(audioFile).catch((err: unknown) => {
                               console.error(err)
                             })
@@ -0,0 +1,25 @@
+import { createResource } from 'solid-js'
+import { GetAudioBackend, SetAudioBackend } from '../wailsjs/go/main/App'
+
+export const useAudioBackend = () => {
+  // eslint-disable-next-line solid/reactivity
+  const [data, { refetch }] = createResource(async () => {
+    try {
+      return await GetAudioBackend()
+    }
+    catch (e) {
+      console.error(e)
+    }
+  }, { initialValue: '' })
+
+  const set = async (backend: string) => {
+    await SetAudioBackend(backend)
+    await refetch()
+  }
+
+  return {
+    audioBackend: data,
+    refetchAudioBackend: refetch,
+    setAudioBackend: set,
+  }
+}
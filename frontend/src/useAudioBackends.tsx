@@ -0,0 +1,19 @@
+import { createResource } from 'solid-js'
+import { ListAudioBackends } from '../wailsjs/go/main/App'
+
+export const useAudioBackends = () => {
+  // eslint-disable-next-line solid/reactivity
+  const [data, { refetch }] = createResource(async () => {
+    try {
+      return await ListAudioBackends()
+    }
+    catch (e) {
+      console.error(e)
+    }
+  }, { initialValue: [] })
+
+  return {
+    audioBackends: data,
+    refetchAudioBackends: refetch,
+  }
+}
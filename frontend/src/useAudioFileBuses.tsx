@@ -0,0 +1,25 @@
+import { createResource } from 'solid-js'
+import { ListAudioFileBuses, SetAudioFileBuses } from '../wailsjs/go/main/App'
+
+export const useAudioFileBuses = () => {
+  // eslint-disable-next-line solid/reactivity
+  const [data, { refetch }] = createResource(async () => {
+    try {
+      return await ListAudioFileBuses()
+    }
+    catch (err: unknown) {
+      console.error(err)
+    }
+  }, { initialValue: {} })
+
+  const set = async (audioFile: string, buses: string[]) => {
+    await SetAudioFileBuses(audioFile, buses)
+    await refetch()
+  }
+
+  return {
+    audioFileBuses: data,
+    refetchAudioFileBuses: refetch,
+    setAudioFileBuses: set,
+  }
+}
@@ -0,0 +1,25 @@
+import { createResource } from 'solid-js'
+import { ListAudioFileLoops, SetAudioFileLoop } from '../wailsjs/go/main/App'
+
+export const useAudioFileLoops = () => {
+  // eslint-disable-next-line solid/reactivity
+  const [data, { refetch }] = createResource(async () => {
+    try {
+      return await ListAudioFileLoops()
+    }
+    catch (err: unknown) {
+      console.error(err)
+    }
+  }, { initialValue: {} })
+
+  const set = async (audioFile: string, loop: boolean) => {
+    await SetAudioFileLoop(audioFile, loop)
+    await refetch()
+  }
+
+  return {
+    audioFileLoops: data,
+    refetchAudioFileLoops: refetch,
+    setAudioFileLoop: set,
+  }
+}
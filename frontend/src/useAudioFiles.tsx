@@ -1,5 +1,6 @@
-import { createResource } from 'solid-js'
+import { createResource, onCleanup } from 'solid-js'
 import { AddAudioFile, ListAudioFiles, PlayAudioFile, RemoveAudioFile, StopAudioFile } from '../wailsjs/go/main/App'
+import { EventsOn } from '../wailsjs/runtime/runtime'
 
 export const useAudioFiles = () => {
   // eslint-disable-next-line solid/reactivity
@@ -12,6 +13,12 @@ export const useAudioFiles = () => {
     }
   }, { initialValue: [] })
 
+  // Audio files can also be added from the command line
+  const off = EventsOn('audioFilesChanged', () => {
+    refetch()
+  })
+  onCleanup(off)
+
   const add = async (file: string) => {
     await AddAudioFile(file)
     await refetch()
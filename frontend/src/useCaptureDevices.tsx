@@ -1,9 +1,11 @@
-import { createResource } from 'solid-js'
+import { createResource, onCleanup } from 'solid-js'
 import { ListCaptureDevices } from '../wailsjs/go/main/App'
+import { main } from '../wailsjs/go/models'
+import { EventsOn } from '../wailsjs/runtime/runtime'
 
 export const useCaptureDevices = () => {
   // eslint-disable-next-line solid/reactivity
-  const [data, { refetch }] = createResource(async () => {
+  const [data, { refetch, mutate }] = createResource(async () => {
     try {
       return await ListCaptureDevices()
     }
@@ -12,6 +14,12 @@ export const useCaptureDevices = () => {
     }
   }, { initialValue: [] })
 
+  // The device monitor sends the new list whenever devices are added or removed
+  const off = EventsOn('captureDevices', (devices: main.MediaDeviceInfo[]) => {
+    mutate(devices)
+  })
+  onCleanup(off)
+
   return {
     captureDevices: data,
     refetchCaptureDevices: refetch,
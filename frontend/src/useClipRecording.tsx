@@ -0,0 +1,41 @@
+import { createSignal, onCleanup } from 'solid-js'
+import { RecordClip, StopClipRecording } from '../wailsjs/go/main/App'
+import { EventsOn } from '../wailsjs/runtime/runtime'
+
+type ClipRecordingLevel = {
+  peakDb: number
+  seconds: number
+}
+
+export const useClipRecording = () => {
+  const [isRecording, setIsRecording] = createSignal(false)
+  const [level, setLevel] = createSignal<ClipRecordingLevel>()
+
+  const off = EventsOn('clipRecordingLevel', (level: ClipRecordingLevel) => {
+    setLevel(level)
+  })
+  onCleanup(off)
+
+  // Resolves once the clip is stopped and saved
+  const record = async () => {
+    setIsRecording(true)
+    try {
+      await RecordClip()
+    }
+    finally {
+      setIsRecording(false)
+      setLevel(undefined)
+    }
+  }
+
+  const stop = async () => {
+    await StopClipRecording()
+  }
+
+  return {
+    isRecordingClip: isRecording,
+    clipRecordingLevel: level,
+    recordClip: record,
+    stopClipRecording: stop,
+  }
+}
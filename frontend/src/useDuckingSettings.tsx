@@ -0,0 +1,26 @@
+import { createResource } from 'solid-js'
+import { GetDuckingSettings, SetDuckingSettings } from '../wailsjs/go/main/App'
+import { main } from '../wailsjs/go/models'
+
+export const useDuckingSettings = () => {
+  // eslint-disable-next-line solid/reactivity
+  const [data, { refetch }] = createResource(async () => {
+    try {
+      return await GetDuckingSettings()
+    }
+    catch (err: unknown) {
+      console.error(err)
+    }
+  })
+
+  const set = async (settings: main.DuckingSettings) => {
+    await SetDuckingSettings(settings)
+    await refetch()
+  }
+
+  return {
+    duckingSettings: data,
+    refetchDuckingSettings: refetch,
+    setDuckingSettings: set,
+  }
+}
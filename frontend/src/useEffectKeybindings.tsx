@@ -0,0 +1,25 @@
+import { createResource } from 'solid-js'
+import { ListEffectKeybindings, SetEffectKeybinding } from '../wailsjs/go/main/App'
+
+export const useEffectKeybindings = () => {
+  // eslint-disable-next-line solid/reactivity
+  const [data, { refetch }] = createResource(async () => {
+    try {
+      return await ListEffectKeybindings()
+    }
+    catch (err: unknown) {
+      console.error(err)
+    }
+  }, { initialValue: {} })
+
+  const set = async (effect: string, keybinding: string) => {
+    await SetEffectKeybinding(effect, keybinding)
+    await refetch()
+  }
+
+  return {
+    effectKeybindings: data,
+    refetchEffectKeybindings: refetch,
+    setEffectKeybinding: set,
+  }
+}
@@ -0,0 +1,25 @@
+import { createResource } from 'solid-js'
+import { GetEffectProfile, SetEffectProfile } from '../wailsjs/go/main/App'
+
+export const useEffectProfile = () => {
+  // eslint-disable-next-line solid/reactivity
+  const [data, { refetch }] = createResource(async () => {
+    try {
+      return await GetEffectProfile()
+    }
+    catch (e) {
+      console.error(e)
+    }
+  }, { initialValue: '' })
+
+  const set = async (effectProfile: string) => {
+    await SetEffectProfile(effectProfile)
+    await refetch()
+  }
+
+  return {
+    effectProfile: data,
+    refetchEffectProfile: refetch,
+    setEffectProfile: set,
+  }
+}
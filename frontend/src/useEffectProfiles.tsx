@@ -0,0 +1,25 @@
+import { createResource } from 'solid-js'
+import { DeleteEffectProfile, ListEffectProfiles } from '../wailsjs/go/main/App'
+
+export const useEffectProfiles = () => {
+  // eslint-disable-next-line solid/reactivity
+  const [data, { refetch }] = createResource(async () => {
+    try {
+      return await ListEffectProfiles()
+    }
+    catch (e) {
+      console.error(e)
+    }
+  }, { initialValue: [] })
+
+  const remove = async (effectProfile: string) => {
+    await DeleteEffectProfile(effectProfile)
+    await refetch()
+  }
+
+  return {
+    effectProfiles: data,
+    refetchEffectProfiles: refetch,
+    deleteEffectProfile: remove,
+  }
+}
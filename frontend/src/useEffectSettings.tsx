@@ -0,0 +1,38 @@
+import { createResource, onCleanup } from 'solid-js'
+import { GetEffectSettings, SetEffectSettings, ToggleEffect } from '../wailsjs/go/main/App'
+import { main } from '../wailsjs/go/models'
+import { EventsOn } from '../wailsjs/runtime/runtime'
+
+export const useEffectSettings = () => {
+  // eslint-disable-next-line solid/reactivity
+  const [data, { refetch, mutate }] = createResource(async () => {
+    try {
+      return await GetEffectSettings()
+    }
+    catch (err: unknown) {
+      console.error(err)
+    }
+  })
+
+  // Effects toggled by hotkey send their new settings
+  const off = EventsOn('effectSettings', (settings: main.EffectSettings) => {
+    mutate(settings)
+  })
+  onCleanup(off)
+
+  const set = async (settings: main.EffectSettings) => {
+    await SetEffectSettings(settings)
+    await refetch()
+  }
+
+  const toggle = async (effect: string) => {
+    await ToggleEffect(effect)
+  }
+
+  return {
+    effectSettings: data,
+    refetchEffectSettings: refetch,
+    setEffectSettings: set,
+    toggleEffect: toggle,
+  }
+}
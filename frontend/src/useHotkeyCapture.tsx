@@ -0,0 +1,39 @@
+import { createSignal, onCleanup } from 'solid-js'
+import { BeginHotkeyCapture, EndHotkeyCapture } from '../wailsjs/go/main/App'
+import { EventsOn } from '../wailsjs/runtime/runtime'
+
+export const useHotkeyCapture = () => {
+  const [data, setData] = createSignal('')
+
+  let off: (() => void) | undefined
+
+  const end = async () => {
+    off?.()
+    off = undefined
+    await EndHotkeyCapture()
+  }
+
+  const begin = async () => {
+    off?.()
+    setData('')
+    off = EventsOn('hotkeyCapture', (keybinding: string) => {
+      setData(keybinding)
+    })
+    await BeginHotkeyCapture()
+  }
+
+  onCleanup(() => {
+    if (off !== undefined) {
+      end().catch((err: unknown) => {
+        console.error(err)
+      })
+    }
+  })
+
+  return {
+    capturedKeybinding: data,
+    setCapturedKeybinding: setData,
+    beginHotkeyCapture: begin,
+    endHotkeyCapture: end,
+  }
+}
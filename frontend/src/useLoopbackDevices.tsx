@@ -0,0 +1,26 @@
+import { createResource, onCleanup } from 'solid-js'
+import { GetLoopbackDevices } from '../wailsjs/go/main/App'
+import { main } from '../wailsjs/go/models'
+import { EventsOn } from '../wailsjs/runtime/runtime'
+
+export const useLoopbackDevices = () => {
+  // eslint-disable-next-line solid/reactivity
+  const [data, { refetch, mutate }] = createResource(async () => {
+    try {
+      return await GetLoopbackDevices()
+    }
+    catch (err: unknown) {
+      console.error(err)
+    }
+  })
+
+  const off = EventsOn('loopbackDevices', (devices: main.LoopbackDevices) => {
+    mutate(devices)
+  })
+  onCleanup(off)
+
+  return {
+    loopbackDevices: data,
+    refetchLoopbackDevices: refetch,
+  }
+}
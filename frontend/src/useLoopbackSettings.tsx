@@ -0,0 +1,26 @@
+import { createResource } from 'solid-js'
+import { GetLoopbackSettings, SetLoopbackSettings } from '../wailsjs/go/main/App'
+import { main } from '../wailsjs/go/models'
+
+export const useLoopbackSettings = () => {
+  // eslint-disable-next-line solid/reactivity
+  const [data, { refetch }] = createResource(async () => {
+    try {
+      return await GetLoopbackSettings()
+    }
+    catch (err: unknown) {
+      console.error(err)
+    }
+  })
+
+  const set = async (settings: main.LoopbackSettings) => {
+    await SetLoopbackSettings(settings)
+    await refetch()
+  }
+
+  return {
+    loopbackSettings: data,
+    refetchLoopbackSettings: refetch,
+    setLoopbackSettings: set,
+  }
+}
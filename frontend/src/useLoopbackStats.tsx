@@ -0,0 +1,40 @@
+import { createResource, createSignal, onCleanup } from 'solid-js'
+import { GetLoopbackStats, MeasureLoopbackLatency } from '../wailsjs/go/main/App'
+import { main } from '../wailsjs/go/models'
+import { EventsOn } from '../wailsjs/runtime/runtime'
+
+export const useLoopbackStats = () => {
+  // eslint-disable-next-line solid/reactivity
+  const [data, { refetch, mutate }] = createResource(async () => {
+    try {
+      return await GetLoopbackStats()
+    }
+    catch (err: unknown) {
+      console.error(err)
+    }
+  })
+
+  const off = EventsOn('loopbackStats', (stats: main.LoopbackStats) => {
+    mutate(stats)
+  })
+  onCleanup(off)
+
+  const [isMeasuring, setIsMeasuring] = createSignal(false)
+
+  const measure = async () => {
+    setIsMeasuring(true)
+    try {
+      await MeasureLoopbackLatency()
+    }
+    finally {
+      setIsMeasuring(false)
+    }
+  }
+
+  return {
+    loopbackStats: data,
+    refetchLoopbackStats: refetch,
+    isMeasuringLatency: isMeasuring,
+    measureLatency: measure,
+  }
+}
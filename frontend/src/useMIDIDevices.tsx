@@ -0,0 +1,19 @@
+import { createResource } from 'solid-js'
+import { ListMIDIDevices } from '../wailsjs/go/main/App'
+
+export const useMIDIDevices = () => {
+  // eslint-disable-next-line solid/reactivity
+  const [data, { refetch }] = createResource(async () => {
+    try {
+      return await ListMIDIDevices()
+    }
+    catch (e) {
+      console.error(e)
+    }
+  }, { initialValue: [] })
+
+  return {
+    midiDevices: data,
+    refetchMIDIDevices: refetch,
+  }
+}
@@ -0,0 +1,25 @@
+import { createResource } from 'solid-js'
+import { GetMIDIInputDeviceID, SetMIDIInputDeviceID } from '../wailsjs/go/main/App'
+
+export const useMIDIInputDeviceID = () => {
+  // eslint-disable-next-line solid/reactivity
+  const [data, { refetch }] = createResource(async () => {
+    try {
+      return await GetMIDIInputDeviceID()
+    }
+    catch (e) {
+      console.error(e)
+    }
+  }, { initialValue: '' })
+
+  const set = async (id: string) => {
+    await SetMIDIInputDeviceID(id)
+    await refetch()
+  }
+
+  return {
+    midiInputDeviceID: data,
+    refetchMIDIInputDeviceID: refetch,
+    setMIDIInputDeviceID: set,
+  }
+}
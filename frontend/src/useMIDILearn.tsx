@@ -0,0 +1,41 @@
+import { createSignal, onCleanup } from 'solid-js'
+import { BeginMIDILearn, EndMIDILearn } from '../wailsjs/go/main/App'
+import { EventsOn } from '../wailsjs/runtime/runtime'
+
+export const useMIDILearn = () => {
+  const [data, setData] = createSignal(false)
+
+  let off: (() => void) | undefined
+
+  const end = async () => {
+    off?.()
+    off = undefined
+    setData(false)
+    await EndMIDILearn()
+  }
+
+  const begin = async (action: string, arg: string) => {
+    off?.()
+    setData(true)
+    off = EventsOn('midiLearn', () => {
+      off?.()
+      off = undefined
+      setData(false)
+    })
+    await BeginMIDILearn(action, arg)
+  }
+
+  onCleanup(() => {
+    if (off !== undefined) {
+      end().catch((err: unknown) => {
+        console.error(err)
+      })
+    }
+  })
+
+  return {
+    isLearningMIDI: data,
+    beginMIDILearn: begin,
+    endMIDILearn: end,
+  }
+}
@@ -0,0 +1,25 @@
+import { createResource } from 'solid-js'
+import { GetMonitorDeviceID, SetMonitorDeviceID } from '../wailsjs/go/main/App'
+
+export const useMonitorDeviceID = () => {
+  // eslint-disable-next-line solid/reactivity
+  const [data, { refetch }] = createResource(async () => {
+    try {
+      return await GetMonitorDeviceID()
+    }
+    catch (e) {
+      console.error(e)
+    }
+  }, { initialValue: '' })
+
+  const set = async (id: string) => {
+    await SetMonitorDeviceID(id)
+    await refetch()
+  }
+
+  return {
+    monitorDeviceID: data,
+    refetchMonitorDeviceID: refetch,
+    setMonitorDeviceID: set,
+  }
+}
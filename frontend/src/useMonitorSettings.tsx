@@ -0,0 +1,26 @@
+import { createResource } from 'solid-js'
+import { GetMonitorSettings, SetMonitorSettings } from '../wailsjs/go/main/App'
+import { main } from '../wailsjs/go/models'
+
+export const useMonitorSettings = () => {
+  // eslint-disable-next-line solid/reactivity
+  const [data, { refetch }] = createResource(async () => {
+    try {
+      return await GetMonitorSettings()
+    }
+    catch (err: unknown) {
+      console.error(err)
+    }
+  })
+
+  const set = async (settings: main.MonitorSettings) => {
+    await SetMonitorSettings(settings)
+    await refetch()
+  }
+
+  return {
+    monitorSettings: data,
+    refetchMonitorSettings: refetch,
+    setMonitorSettings: set,
+  }
+}
@@ -0,0 +1,53 @@
+import { createResource, createSignal, onCleanup } from 'solid-js'
+import { GetNoiseSuppressionSettings, LearnNoiseFloor, SetNoiseSuppressionSettings } from '../wailsjs/go/main/App'
+import { main } from '../wailsjs/go/models'
+import { EventsOn } from '../wailsjs/runtime/runtime'
+
+export const useNoiseSuppressionSettings = () => {
+  // eslint-disable-next-line solid/reactivity
+  const [data, { refetch, mutate }] = createResource(async () => {
+    try {
+      return await GetNoiseSuppressionSettings()
+    }
+    catch (err: unknown) {
+      console.error(err)
+    }
+  })
+  const [isLearning, setIsLearning] = createSignal(false)
+
+  // Noise floors learned by hotkey send the new settings
+  const offSettings = EventsOn('noiseSuppressionSettings', (settings: main.NoiseSuppressionSettings) => {
+    mutate(settings)
+  })
+  onCleanup(offSettings)
+
+  // Settings belong to the capture device the loopback is using
+  const offDevices = EventsOn('loopbackDevices', () => {
+    refetch()
+  })
+  onCleanup(offDevices)
+
+  const set = async (settings: main.NoiseSuppressionSettings) => {
+    await SetNoiseSuppressionSettings(settings)
+    await refetch()
+  }
+
+  const learn = async () => {
+    setIsLearning(true)
+    try {
+      await LearnNoiseFloor()
+      await refetch()
+    }
+    finally {
+      setIsLearning(false)
+    }
+  }
+
+  return {
+    noiseSuppressionSettings: data,
+    refetchNoiseSuppressionSettings: refetch,
+    setNoiseSuppressionSettings: set,
+    isLearningNoiseFloor: isLearning,
+    learnNoiseFloor: learn,
+  }
+}
@@ -0,0 +1,26 @@
+import { createResource } from 'solid-js'
+import { GetOSCSettings, SetOSCSettings } from '../wailsjs/go/main/App'
+import { main } from '../wailsjs/go/models'
+
+export const useOSCSettings = () => {
+  // eslint-disable-next-line solid/reactivity
+  const [data, { refetch }] = createResource(async () => {
+    try {
+      return await GetOSCSettings()
+    }
+    catch (err: unknown) {
+      console.error(err)
+    }
+  })
+
+  const set = async (settings: main.OSCSettings) => {
+    await SetOSCSettings(settings)
+    await refetch()
+  }
+
+  return {
+    oscSettings: data,
+    refetchOSCSettings: refetch,
+    setOSCSettings: set,
+  }
+}
@@ -0,0 +1,37 @@
+import { createResource } from 'solid-js'
+import { ListOutputBuses, RemoveOutputBus, SetOutputBus, SetOutputBusDeviceID } from '../wailsjs/go/main/App'
+import { main } from '../wailsjs/go/models'
+
+export const useOutputBuses = () => {
+  // eslint-disable-next-line solid/reactivity
+  const [data, { refetch }] = createResource(async () => {
+    try {
+      return await ListOutputBuses()
+    }
+    catch (err: unknown) {
+      console.error(err)
+    }
+  }, { initialValue: [] })
+
+  const set = async (outputBus: main.OutputBus) => {
+    await SetOutputBus(outputBus)
+    await refetch()
+  }
+
+  const remove = async (name: string) => {
+    await RemoveOutputBus(name)
+    await refetch()
+  }
+
+  const setDeviceID = async (name: string, deviceID: string) => {
+    await SetOutputBusDeviceID(name, deviceID)
+  }
+
+  return {
+    outputBuses: data,
+    refetchOutputBuses: refetch,
+    setOutputBus: set,
+    removeOutputBus: remove,
+    setOutputBusDeviceID: setDeviceID,
+  }
+}
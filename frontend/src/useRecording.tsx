@@ -0,0 +1,38 @@
+import { createResource, onCleanup } from 'solid-js'
+import { GetRecordingState, StartBusRecording, StopRecording } from '../wailsjs/go/main/App'
+import { main } from '../wailsjs/go/models'
+import { EventsOn } from '../wailsjs/runtime/runtime'
+
+export const useRecording = () => {
+  // eslint-disable-next-line solid/reactivity
+  const [data, { refetch, mutate }] = createResource(async () => {
+    try {
+      return await GetRecordingState()
+    }
+    catch (err: unknown) {
+      console.error(err)
+    }
+  })
+
+  const off = EventsOn('recording', (state: main.RecordingState) => {
+    mutate(state)
+  })
+  onCleanup(off)
+
+  const start = async (format: string, bus: string) => {
+    // An empty path records into the default folder, and an empty bus
+    // records the final mix
+    await StartBusRecording('', format, bus)
+  }
+
+  const stop = async () => {
+    await StopRecording()
+  }
+
+  return {
+    recording: data,
+    refetchRecording: refetch,
+    startRecording: start,
+    stopRecording: stop,
+  }
+}
@@ -0,0 +1,25 @@
+import { createResource } from 'solid-js'
+import { GetRemoteControlSettings, SetRemoteControlSettings } from '../wailsjs/go/main/App'
+import { main } from '../wailsjs/go/models'
+
+export const useRemoteControlSettings = () => {
+  // eslint-disable-next-line solid/reactivity
+  const [data, { mutate, refetch }] = createResource(async () => {
+    try {
+      return await GetRemoteControlSettings()
+    }
+    catch (err: unknown) {
+      console.error(err)
+    }
+  })
+
+  const set = async (settings: main.RemoteControlSettings) => {
+    mutate(await SetRemoteControlSettings(settings))
+  }
+
+  return {
+    remoteControlSettings: data,
+    refetchRemoteControlSettings: refetch,
+    setRemoteControlSettings: set,
+  }
+}
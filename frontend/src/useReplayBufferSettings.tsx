@@ -0,0 +1,31 @@
+import { createResource } from 'solid-js'
+import { GetReplayBufferSettings, SaveReplay, SetReplayBufferSettings } from '../wailsjs/go/main/App'
+import { main } from '../wailsjs/go/models'
+
+export const useReplayBufferSettings = () => {
+  // eslint-disable-next-line solid/reactivity
+  const [data, { refetch }] = createResource(async () => {
+    try {
+      return await GetReplayBufferSettings()
+    }
+    catch (err: unknown) {
+      console.error(err)
+    }
+  })
+
+  const set = async (settings: main.ReplayBufferSettings) => {
+    await SetReplayBufferSettings(settings)
+    await refetch()
+  }
+
+  const save = async () => {
+    await SaveReplay()
+  }
+
+  return {
+    replayBufferSettings: data,
+    refetchReplayBufferSettings: refetch,
+    setReplayBufferSettings: set,
+    saveReplay: save,
+  }
+}
@@ -0,0 +1,26 @@
+import { createResource } from 'solid-js'
+import { GetVirtualMicSettings, SetVirtualMicSettings } from '../wailsjs/go/main/App'
+import { main } from '../wailsjs/go/models'
+
+export const useVirtualMicSettings = () => {
+  // eslint-disable-next-line solid/reactivity
+  const [data, { refetch }] = createResource(async () => {
+    try {
+      return await GetVirtualMicSettings()
+    }
+    catch (err: unknown) {
+      console.error(err)
+    }
+  })
+
+  const set = async (settings: main.VirtualMicSettings) => {
+    await SetVirtualMicSettings(settings)
+    await refetch()
+  }
+
+  return {
+    virtualMicSettings: data,
+    refetchVirtualMicSettings: refetch,
+    setVirtualMicSettings: set,
+  }
+}
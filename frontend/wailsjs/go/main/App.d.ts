@@ -1,36 +1,175 @@
 // Cynhyrchwyd y ffeil hon yn awtomatig. PEIDIWCH Â MODIWL
 // This file is automatically generated. DO NOT EDIT
 import {main} from '../models';
-import {context} from '../models';
 
 export function AddAudioFile(arg1:string):Promise<void>;
 
+export function BeginHotkeyCapture():Promise<void>;
+
+export function BeginMIDILearn(arg1:string,arg2:string):Promise<void>;
+
+export function DeleteEffectProfile(arg1:string):Promise<void>;
+
+export function EndHotkeyCapture():Promise<void>;
+
+export function EndMIDILearn():Promise<void>;
+
+export function GetActiveAudioBackend():Promise<string>;
+
+export function GetAudioBackend():Promise<string>;
+
+export function GetAudioFileState(arg1:string):Promise<string>;
+
 export function GetCaptureDeviceID():Promise<string>;
 
+export function GetDuckingSettings():Promise<main.DuckingSettings>;
+
+export function GetEffectProfile():Promise<string>;
+
+export function GetEffectSettings():Promise<main.EffectSettings>;
+
+export function GetLoopbackDevices():Promise<main.LoopbackDevices>;
+
+export function GetLoopbackSettings():Promise<main.LoopbackSettings>;
+
+export function GetLoopbackStats():Promise<main.LoopbackStats>;
+
+export function GetMIDIFeedbackProfile():Promise<main.MIDIFeedbackProfile>;
+
+export function GetMIDIInputDeviceID():Promise<string>;
+
+export function GetMicGain():Promise<number>;
+
+export function GetMonitorDeviceID():Promise<string>;
+
+export function GetMonitorSettings():Promise<main.MonitorSettings>;
+
+export function GetNoiseSuppressionSettings():Promise<main.NoiseSuppressionSettings>;
+
+export function GetOSCSettings():Promise<main.OSCSettings>;
+
+export function GetOutputBusDeviceID(arg1:string):Promise<string>;
+
 export function GetPlaybackDeviceID():Promise<string>;
 
+export function GetRecordingState():Promise<main.RecordingState>;
+
+export function GetRemoteControlSettings():Promise<main.RemoteControlSettings>;
+
+export function GetReplayBufferSettings():Promise<main.ReplayBufferSettings>;
+
+export function GetVirtualMicSettings():Promise<main.VirtualMicSettings>;
+
+export function GetVolume():Promise<number>;
+
+export function LearnNoiseFloor():Promise<void>;
+
+export function ListAudioBackends():Promise<Array<string>>;
+
+export function ListAudioFileBuses():Promise<{[key: string]: Array<string>}>;
+
 export function ListAudioFileKeybindings():Promise<{[key: string]: string}>;
 
+export function ListAudioFileLoops():Promise<{[key: string]: boolean}>;
+
 export function ListAudioFiles():Promise<Array<string>>;
 
 export function ListCaptureDevices():Promise<Array<main.MediaDeviceInfo>>;
 
+export function ListClips():Promise<Array<main.Clip>>;
+
+export function ListEffectKeybindings():Promise<{[key: string]: string}>;
+
+export function ListEffectProfiles():Promise<Array<string>>;
+
+export function ListMIDIBindings():Promise<Array<main.MIDIBinding>>;
+
+export function ListMIDIDevices():Promise<Array<main.MIDIDeviceInfo>>;
+
+export function ListMIDIFeedbackProfiles():Promise<Array<main.MIDIFeedbackProfile>>;
+
+export function ListOutputBuses():Promise<Array<main.OutputBus>>;
+
 export function ListPlaybackDevices():Promise<Array<main.MediaDeviceInfo>>;
 
-export function LoopbackAudio(arg1:context.Context):Promise<void>;
+export function MeasureLoopbackLatency():Promise<void>;
 
 export function OpenMultipleFilesDialog(arg1:main.OpenDialogOptions):Promise<Array<string>>;
 
 export function PlayAudioFile(arg1:string):Promise<void>;
 
+export function RecordClip():Promise<string>;
+
 export function RemoveAudioFile(arg1:string):Promise<void>;
 
 export function RemoveAudioFileKeybinding(arg1:string):Promise<void>;
 
+export function RemoveMIDIBinding(arg1:main.MIDIBinding):Promise<void>;
+
+export function RemoveOutputBus(arg1:string):Promise<void>;
+
+export function SaveReplay():Promise<string>;
+
+export function SetAudioBackend(arg1:string):Promise<void>;
+
+export function SetAudioFileBuses(arg1:string,arg2:Array<string>):Promise<void>;
+
 export function SetAudioFileKeybinding(arg1:string,arg2:string):Promise<void>;
 
+export function SetAudioFileLoop(arg1:string,arg2:boolean):Promise<void>;
+
 export function SetCaptureDeviceID(arg1:string):Promise<void>;
 
+export function SetDuckingSettings(arg1:main.DuckingSettings):Promise<void>;
+
+export function SetEffectKeybinding(arg1:string,arg2:string):Promise<void>;
+
+export function SetEffectProfile(arg1:string):Promise<void>;
+
+export function SetEffectSettings(arg1:main.EffectSettings):Promise<void>;
+
+export function SetLoopbackSettings(arg1:main.LoopbackSettings):Promise<void>;
+
+export function SetMIDIBinding(arg1:main.MIDIBinding):Promise<void>;
+
+export function SetMIDIFeedbackProfile(arg1:main.MIDIFeedbackProfile):Promise<void>;
+
+export function SetMIDIInputDeviceID(arg1:string):Promise<void>;
+
+export function SetMicGain(arg1:number):Promise<void>;
+
+export function SetMonitorDeviceID(arg1:string):Promise<void>;
+
+export function SetMonitorSettings(arg1:main.MonitorSettings):Promise<void>;
+
+export function SetNoiseSuppressionSettings(arg1:main.NoiseSuppressionSettings):Promise<void>;
+
+export function SetOSCSettings(arg1:main.OSCSettings):Promise<void>;
+
+export function SetOutputBus(arg1:main.OutputBus):Promise<void>;
+
+export function SetOutputBusDeviceID(arg1:string,arg2:string):Promise<void>;
+
 export function SetPlaybackDeviceID(arg1:string):Promise<void>;
 
+export function SetRemoteControlSettings(arg1:main.RemoteControlSettings):Promise<main.RemoteControlSettings>;
+
+export function SetReplayBufferSettings(arg1:main.ReplayBufferSettings):Promise<void>;
+
+export function SetVirtualMicSettings(arg1:main.VirtualMicSettings):Promise<void>;
+
+export function SetVolume(arg1:number):Promise<void>;
+
+export function StartBusRecording(arg1:string,arg2:string,arg3:string):Promise<string>;
+
+export function StartRecording(arg1:string,arg2:string):Promise<string>;
+
+export function StopAllAudioFiles():Promise<void>;
+
 export function StopAudioFile(arg1:string):Promise<void>;
+
+export function StopClipRecording():Promise<void>;
+
+export function StopRecording():Promise<void>;
+
+export function ToggleEffect(arg1:string):Promise<void>;
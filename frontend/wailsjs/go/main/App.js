@@ -6,18 +6,142 @@ export function AddAudioFile(arg1) {
   return window['go']['main']['App']['AddAudioFile'](arg1);
 }
 
+export function BeginHotkeyCapture() {
+  return window['go']['main']['App']['BeginHotkeyCapture']();
+}
+
+export function BeginMIDILearn(arg1, arg2) {
+  return window['go']['main']['App']['BeginMIDILearn'](arg1, arg2);
+}
+
+export function DeleteEffectProfile(arg1) {
+  return window['go']['main']['App']['DeleteEffectProfile'](arg1);
+}
+
+export function EndHotkeyCapture() {
+  return window['go']['main']['App']['EndHotkeyCapture']();
+}
+
+export function EndMIDILearn() {
+  return window['go']['main']['App']['EndMIDILearn']();
+}
+
+export function GetActiveAudioBackend() {
+  return window['go']['main']['App']['GetActiveAudioBackend']();
+}
+
+export function GetAudioBackend() {
+  return window['go']['main']['App']['GetAudioBackend']();
+}
+
+export function GetAudioFileState(arg1) {
+  return window['go']['main']['App']['GetAudioFileState'](arg1);
+}
+
 export function GetCaptureDeviceID() {
   return window['go']['main']['App']['GetCaptureDeviceID']();
 }
 
+export function GetDuckingSettings() {
+  return window['go']['main']['App']['GetDuckingSettings']();
+}
+
+export function GetEffectProfile() {
+  return window['go']['main']['App']['GetEffectProfile']();
+}
+
+export function GetEffectSettings() {
+  return window['go']['main']['App']['GetEffectSettings']();
+}
+
+export function GetLoopbackDevices() {
+  return window['go']['main']['App']['GetLoopbackDevices']();
+}
+
+export function GetLoopbackSettings() {
+  return window['go']['main']['App']['GetLoopbackSettings']();
+}
+
+export function GetLoopbackStats() {
+  return window['go']['main']['App']['GetLoopbackStats']();
+}
+
+export function GetMIDIFeedbackProfile() {
+  return window['go']['main']['App']['GetMIDIFeedbackProfile']();
+}
+
+export function GetMIDIInputDeviceID() {
+  return window['go']['main']['App']['GetMIDIInputDeviceID']();
+}
+
+export function GetMicGain() {
+  return window['go']['main']['App']['GetMicGain']();
+}
+
+export function GetMonitorDeviceID() {
+  return window['go']['main']['App']['GetMonitorDeviceID']();
+}
+
+export function GetMonitorSettings() {
+  return window['go']['main']['App']['GetMonitorSettings']();
+}
+
+export function GetNoiseSuppressionSettings() {
+  return window['go']['main']['App']['GetNoiseSuppressionSettings']();
+}
+
+export function GetOSCSettings() {
+  return window['go']['main']['App']['GetOSCSettings']();
+}
+
+export function GetOutputBusDeviceID(arg1) {
+  return window['go']['main']['App']['GetOutputBusDeviceID'](arg1);
+}
+
 export function GetPlaybackDeviceID() {
   return window['go']['main']['App']['GetPlaybackDeviceID']();
 }
 
+export function GetRecordingState() {
+  return window['go']['main']['App']['GetRecordingState']();
+}
+
+export function GetRemoteControlSettings() {
+  return window['go']['main']['App']['GetRemoteControlSettings']();
+}
+
+export function GetReplayBufferSettings() {
+  return window['go']['main']['App']['GetReplayBufferSettings']();
+}
+
+export function GetVirtualMicSettings() {
+  return window['go']['main']['App']['GetVirtualMicSettings']();
+}
+
+export function GetVolume() {
+  return window['go']['main']['App']['GetVolume']();
+}
+
+export function LearnNoiseFloor() {
+  return window['go']['main']['App']['LearnNoiseFloor']();
+}
+
+export function ListAudioBackends() {
+  return window['go']['main']['App']['ListAudioBackends']();
+}
+
+export function ListAudioFileBuses() {
+  return window['go']['main']['App']['ListAudioFileBuses']();
+}
+
 export function ListAudioFileKeybindings() {
   return window['go']['main']['App']['ListAudioFileKeybindings']();
 }
 
+export function ListAudioFileLoops() {
+  return window['go']['main']['App']['ListAudioFileLoops']();
+}
+
 export function ListAudioFiles() {
   return window['go']['main']['App']['ListAudioFiles']();
 }
@@ -26,12 +150,40 @@ export function ListCaptureDevices() {
   return window['go']['main']['App']['ListCaptureDevices']();
 }
 
+export function ListClips() {
+  return window['go']['main']['App']['ListClips']();
+}
+
+export function ListEffectKeybindings() {
+  return window['go']['main']['App']['ListEffectKeybindings']();
+}
+
+export function ListEffectProfiles() {
+  return window['go']['main']['App']['ListEffectProfiles']();
+}
+
+export function ListMIDIBindings() {
+  return window['go']['main']['App']['ListMIDIBindings']();
+}
+
+export function ListMIDIDevices() {
+  return window['go']['main']['App']['ListMIDIDevices']();
+}
+
+export function ListMIDIFeedbackProfiles() {
+  return window['go']['main']['App']['ListMIDIFeedbackProfiles']();
+}
+
+export function ListOutputBuses() {
+  return window['go']['main']['App']['ListOutputBuses']();
+}
+
 export function ListPlaybackDevices() {
   return window['go']['main']['App']['ListPlaybackDevices']();
 }
 
-export function LoopbackAudio(arg1) {
-  return window['go']['main']['App']['LoopbackAudio'](arg1);
+export function MeasureLoopbackLatency() {
+  return window['go']['main']['App']['MeasureLoopbackLatency']();
 }
 
 export function OpenMultipleFilesDialog(arg1) {
@@ -42,6 +194,10 @@ export function PlayAudioFile(arg1) {
   return window['go']['main']['App']['PlayAudioFile'](arg1);
 }
 
+export function RecordClip() {
+  return window['go']['main']['App']['RecordClip']();
+}
+
 export function RemoveAudioFile(arg1) {
   return window['go']['main']['App']['RemoveAudioFile'](arg1);
 }
@@ -50,18 +206,142 @@ export function RemoveAudioFileKeybinding(arg1) {
   return window['go']['main']['App']['RemoveAudioFileKeybinding'](arg1);
 }
 
+export function RemoveMIDIBinding(arg1) {
+  return window['go']['main']['App']['RemoveMIDIBinding'](arg1);
+}
+
+export function RemoveOutputBus(arg1) {
+  return window['go']['main']['App']['RemoveOutputBus'](arg1);
+}
+
+export function SaveReplay() {
+  return window['go']['main']['App']['SaveReplay']();
+}
+
+export function SetAudioBackend(arg1) {
+  return window['go']['main']['App']['SetAudioBackend'](arg1);
+}
+
+export function SetAudioFileBuses(arg1, arg2) {
+  return window['go']['main']['App']['SetAudioFileBuses'](arg1, arg2);
+}
+
 export function SetAudioFileKeybinding(arg1, arg2) {
   return window['go']['main']['App']['SetAudioFileKeybinding'](arg1, arg2);
 }
 
+export function SetAudioFileLoop(arg1, arg2) {
+  return window['go']['main']['App']['SetAudioFileLoop'](arg1, arg2);
+}
+
 export function SetCaptureDeviceID(arg1) {
   return window['go']['main']['App']['SetCaptureDeviceID'](arg1);
 }
 
+export function SetDuckingSettings(arg1) {
+  return window['go']['main']['App']['SetDuckingSettings'](arg1);
+}
+
+export function SetEffectKeybinding(arg1, arg2) {
+  return window['go']['main']['App']['SetEffectKeybinding'](arg1, arg2);
+}
+
+export function SetEffectProfile(arg1) {
+  return window['go']['main']['App']['SetEffectProfile'](arg1);
+}
+
+export function SetEffectSettings(arg1) {
+  return window['go']['main']['App']['SetEffectSettings'](arg1);
+}
+
+export function SetLoopbackSettings(arg1) {
+  return window['go']['main']['App']['SetLoopbackSettings'](arg1);
+}
+
+export function SetMIDIBinding(arg1) {
+  return window['go']['main']['App']['SetMIDIBinding'](arg1);
+}
+
+export function SetMIDIFeedbackProfile(arg1) {
+  return window['go']['main']['App']['SetMIDIFeedbackProfile'](arg1);
+}
+
+export function SetMIDIInputDeviceID(arg1) {
+  return window['go']['main']['App']['SetMIDIInputDeviceID'](arg1);
+}
+
+export function SetMicGain(arg1) {
+  return window['go']['main']['App']['SetMicGain'](arg1);
+}
+
+export function SetMonitorDeviceID(arg1) {
+  return window['go']['main']['App']['SetMonitorDeviceID'](arg1);
+}
+
+export function SetMonitorSettings(arg1) {
+  return window['go']['main']['App']['SetMonitorSettings'](arg1);
+}
+
+export function SetNoiseSuppressionSettings(arg1) {
+  return window['go']['main']['App']['SetNoiseSuppressionSettings'](arg1);
+}
+
+export function SetOSCSettings(arg1) {
+  return window['go']['main']['App']['SetOSCSettings'](arg1);
+}
+
+export function SetOutputBus(arg1) {
+  return window['go']['main']['App']['SetOutputBus'](arg1);
+}
+
+export function SetOutputBusDeviceID(arg1, arg2) {
+  return window['go']['main']['App']['SetOutputBusDeviceID'](arg1, arg2);
+}
+
 export function SetPlaybackDeviceID(arg1) {
   return window['go']['main']['App']['SetPlaybackDeviceID'](arg1);
 }
 
+export function SetRemoteControlSettings(arg1) {
+  return window['go']['main']['App']['SetRemoteControlSettings'](arg1);
+}
+
+export function SetReplayBufferSettings(arg1) {
+  return window['go']['main']['App']['SetReplayBufferSettings'](arg1);
+}
+
+export function SetVirtualMicSettings(arg1) {
+  return window['go']['main']['App']['SetVirtualMicSettings'](arg1);
+}
+
+export function SetVolume(arg1) {
+  return window['go']['main']['App']['SetVolume'](arg1);
+}
+
+export function StartBusRecording(arg1, arg2, arg3) {
+  return window['go']['main']['App']['StartBusRecording'](arg1, arg2, arg3);
+}
+
+export function StartRecording(arg1, arg2) {
+  return window['go']['main']['App']['StartRecording'](arg1, arg2);
+}
+
+export function StopAllAudioFiles() {
+  return window['go']['main']['App']['StopAllAudioFiles']();
+}
+
 export function StopAudioFile(arg1) {
   return window['go']['main']['App']['StopAudioFile'](arg1);
 }
+
+export function StopClipRecording() {
+  return window['go']['main']['App']['StopClipRecording']();
+}
+
+export function StopRecording() {
+  return window['go']['main']['App']['StopRecording']();
+}
+
+export function ToggleEffect(arg1) {
+  return window['go']['main']['App']['ToggleEffect'](arg1);
+}
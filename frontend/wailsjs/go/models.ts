@@ -1,5 +1,357 @@
 export namespace main {
 	
+	export class BusDevice {
+	    bus: string;
+	    deviceId: string;
+	    fallback: boolean;
+	
+	    static createFrom(source: any = {}) {
+	        return new BusDevice(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.bus = source["bus"];
+	        this.deviceId = source["deviceId"];
+	        this.fallback = source["fallback"];
+	    }
+	}
+	export class Clip {
+	    id: number;
+	    audioFile: string;
+	    state: string;
+	    keybinding: string;
+	    loop: boolean;
+	
+	    static createFrom(source: any = {}) {
+	        return new Clip(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.id = source["id"];
+	        this.audioFile = source["audioFile"];
+	        this.state = source["state"];
+	        this.keybinding = source["keybinding"];
+	        this.loop = source["loop"];
+	    }
+	}
+	export class CompressorSettings {
+	    enabled: boolean;
+	    thresholdDb: number;
+	    ratio: number;
+	    attackMilliseconds: number;
+	    releaseMilliseconds: number;
+	    makeupGainDb: number;
+	
+	    static createFrom(source: any = {}) {
+	        return new CompressorSettings(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.enabled = source["enabled"];
+	        this.thresholdDb = source["thresholdDb"];
+	        this.ratio = source["ratio"];
+	        this.attackMilliseconds = source["attackMilliseconds"];
+	        this.releaseMilliseconds = source["releaseMilliseconds"];
+	        this.makeupGainDb = source["makeupGainDb"];
+	    }
+	}
+	export class DelaySettings {
+	    enabled: boolean;
+	    delayMilliseconds: number;
+	    feedback: number;
+	    mix: number;
+	
+	    static createFrom(source: any = {}) {
+	        return new DelaySettings(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.enabled = source["enabled"];
+	        this.delayMilliseconds = source["delayMilliseconds"];
+	        this.feedback = source["feedback"];
+	        this.mix = source["mix"];
+	    }
+	}
+	export class DeviceFormat {
+	    format: string;
+	    channels: number;
+	    sampleRate: number;
+	
+	    static createFrom(source: any = {}) {
+	        return new DeviceFormat(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.format = source["format"];
+	        this.channels = source["channels"];
+	        this.sampleRate = source["sampleRate"];
+	    }
+	}
+	export class DuckingRule {
+	    enabled: boolean;
+	    thresholdDb: number;
+	    depthDb: number;
+	    attackMilliseconds: number;
+	    releaseMilliseconds: number;
+	
+	    static createFrom(source: any = {}) {
+	        return new DuckingRule(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.enabled = source["enabled"];
+	        this.thresholdDb = source["thresholdDb"];
+	        this.depthDb = source["depthDb"];
+	        this.attackMilliseconds = source["attackMilliseconds"];
+	        this.releaseMilliseconds = source["releaseMilliseconds"];
+	    }
+	}
+	export class DuckingSettings {
+	    mic: DuckingRule;
+	    clips: DuckingRule;
+	
+	    static createFrom(source: any = {}) {
+	        return new DuckingSettings(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.mic = this.convertValues(source["mic"], DuckingRule);
+	        this.clips = this.convertValues(source["clips"], DuckingRule);
+	    }
+	
+		convertValues(a: any, classs: any, asMap: boolean = false): any {
+		    if (!a) {
+		        return a;
+		    }
+		    if (a.slice && a.map) {
+		        return (a as any[]).map(elem => this.convertValues(elem, classs));
+		    } else if ("object" === typeof a) {
+		        if (asMap) {
+		            for (const key of Object.keys(a)) {
+		                a[key] = new classs(a[key]);
+		            }
+		            return a;
+		        }
+		        return new classs(a);
+		    }
+		    return a;
+		}
+	}
+	export class LimiterSettings {
+	    enabled: boolean;
+	    ceilingDb: number;
+	    releaseMilliseconds: number;
+	
+	    static createFrom(source: any = {}) {
+	        return new LimiterSettings(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.enabled = source["enabled"];
+	        this.ceilingDb = source["ceilingDb"];
+	        this.releaseMilliseconds = source["releaseMilliseconds"];
+	    }
+	}
+	export class GainSettings {
+	    enabled: boolean;
+	    gainDb: number;
+	
+	    static createFrom(source: any = {}) {
+	        return new GainSettings(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.enabled = source["enabled"];
+	        this.gainDb = source["gainDb"];
+	    }
+	}
+	export class ReverbSettings {
+	    enabled: boolean;
+	    roomSize: number;
+	    damping: number;
+	    mix: number;
+	
+	    static createFrom(source: any = {}) {
+	        return new ReverbSettings(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.enabled = source["enabled"];
+	        this.roomSize = source["roomSize"];
+	        this.damping = source["damping"];
+	        this.mix = source["mix"];
+	    }
+	}
+	export class RobotSettings {
+	    enabled: boolean;
+	    frequency: number;
+	    mix: number;
+	
+	    static createFrom(source: any = {}) {
+	        return new RobotSettings(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.enabled = source["enabled"];
+	        this.frequency = source["frequency"];
+	        this.mix = source["mix"];
+	    }
+	}
+	export class PitchShiftSettings {
+	    enabled: boolean;
+	    semitones: number;
+	
+	    static createFrom(source: any = {}) {
+	        return new PitchShiftSettings(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.enabled = source["enabled"];
+	        this.semitones = source["semitones"];
+	    }
+	}
+	export class EqualizerBand {
+	    frequency: number;
+	    gainDb: number;
+	    q: number;
+	
+	    static createFrom(source: any = {}) {
+	        return new EqualizerBand(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.frequency = source["frequency"];
+	        this.gainDb = source["gainDb"];
+	        this.q = source["q"];
+	    }
+	}
+	export class EqualizerSettings {
+	    enabled: boolean;
+	    bands: EqualizerBand[];
+	
+	    static createFrom(source: any = {}) {
+	        return new EqualizerSettings(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.enabled = source["enabled"];
+	        this.bands = this.convertValues(source["bands"], EqualizerBand);
+	    }
+	
+		convertValues(a: any, classs: any, asMap: boolean = false): any {
+		    if (!a) {
+		        return a;
+		    }
+		    if (a.slice && a.map) {
+		        return (a as any[]).map(elem => this.convertValues(elem, classs));
+		    } else if ("object" === typeof a) {
+		        if (asMap) {
+		            for (const key of Object.keys(a)) {
+		                a[key] = new classs(a[key]);
+		            }
+		            return a;
+		        }
+		        return new classs(a);
+		    }
+		    return a;
+		}
+	}
+	export class HighPassSettings {
+	    enabled: boolean;
+	    frequency: number;
+	
+	    static createFrom(source: any = {}) {
+	        return new HighPassSettings(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.enabled = source["enabled"];
+	        this.frequency = source["frequency"];
+	    }
+	}
+	export class NoiseGateSettings {
+	    enabled: boolean;
+	    thresholdDb: number;
+	    attackMilliseconds: number;
+	    releaseMilliseconds: number;
+	
+	    static createFrom(source: any = {}) {
+	        return new NoiseGateSettings(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.enabled = source["enabled"];
+	        this.thresholdDb = source["thresholdDb"];
+	        this.attackMilliseconds = source["attackMilliseconds"];
+	        this.releaseMilliseconds = source["releaseMilliseconds"];
+	    }
+	}
+	export class EffectSettings {
+	    noiseGate: NoiseGateSettings;
+	    highPass: HighPassSettings;
+	    equalizer: EqualizerSettings;
+	    compressor: CompressorSettings;
+	    pitchShift: PitchShiftSettings;
+	    robot: RobotSettings;
+	    delay: DelaySettings;
+	    reverb: ReverbSettings;
+	    gain: GainSettings;
+	    limiter: LimiterSettings;
+	
+	    static createFrom(source: any = {}) {
+	        return new EffectSettings(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.noiseGate = this.convertValues(source["noiseGate"], NoiseGateSettings);
+	        this.highPass = this.convertValues(source["highPass"], HighPassSettings);
+	        this.equalizer = this.convertValues(source["equalizer"], EqualizerSettings);
+	        this.compressor = this.convertValues(source["compressor"], CompressorSettings);
+	        this.pitchShift = this.convertValues(source["pitchShift"], PitchShiftSettings);
+	        this.robot = this.convertValues(source["robot"], RobotSettings);
+	        this.delay = this.convertValues(source["delay"], DelaySettings);
+	        this.reverb = this.convertValues(source["reverb"], ReverbSettings);
+	        this.gain = this.convertValues(source["gain"], GainSettings);
+	        this.limiter = this.convertValues(source["limiter"], LimiterSettings);
+	    }
+	
+		convertValues(a: any, classs: any, asMap: boolean = false): any {
+		    if (!a) {
+		        return a;
+		    }
+		    if (a.slice && a.map) {
+		        return (a as any[]).map(elem => this.convertValues(elem, classs));
+		    } else if ("object" === typeof a) {
+		        if (asMap) {
+		            for (const key of Object.keys(a)) {
+		                a[key] = new classs(a[key]);
+		            }
+		            return a;
+		        }
+		        return new classs(a);
+		    }
+		    return a;
+		}
+	}
+	
+	
 	export class FileFilter {
 	    displayName: string;
 	    pattern: string;
@@ -14,11 +366,167 @@ export namespace main {
 	        this.pattern = source["pattern"];
 	    }
 	}
+	
+	
+	
+	export class LoopbackDevices {
+	    captureDeviceId: string;
+	    playbackDeviceId: string;
+	    captureFallback: boolean;
+	    playbackFallback: boolean;
+	    buses: BusDevice[];
+	
+	    static createFrom(source: any = {}) {
+	        return new LoopbackDevices(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.captureDeviceId = source["captureDeviceId"];
+	        this.playbackDeviceId = source["playbackDeviceId"];
+	        this.captureFallback = source["captureFallback"];
+	        this.playbackFallback = source["playbackFallback"];
+	        this.buses = this.convertValues(source["buses"], BusDevice);
+	    }
+	
+		convertValues(a: any, classs: any, asMap: boolean = false): any {
+		    if (!a) {
+		        return a;
+		    }
+		    if (a.slice && a.map) {
+		        return (a as any[]).map(elem => this.convertValues(elem, classs));
+		    } else if ("object" === typeof a) {
+		        if (asMap) {
+		            for (const key of Object.keys(a)) {
+		                a[key] = new classs(a[key]);
+		            }
+		            return a;
+		        }
+		        return new classs(a);
+		    }
+		    return a;
+		}
+	}
+	export class LoopbackSettings {
+	    sampleRate: number;
+	    channels: number;
+	    periodSizeInMilliseconds: number;
+	    periods: number;
+	    lowLatency: boolean;
+	    targetFillMilliseconds: number;
+	
+	    static createFrom(source: any = {}) {
+	        return new LoopbackSettings(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.sampleRate = source["sampleRate"];
+	        this.channels = source["channels"];
+	        this.periodSizeInMilliseconds = source["periodSizeInMilliseconds"];
+	        this.periods = source["periods"];
+	        this.lowLatency = source["lowLatency"];
+	        this.targetFillMilliseconds = source["targetFillMilliseconds"];
+	    }
+	}
+	export class LoopbackStats {
+	    captureMilliseconds: number;
+	    queueMilliseconds: number;
+	    playbackMilliseconds: number;
+	    estimatedLatencyMilliseconds: number;
+	    roundTripMilliseconds: number;
+	    measuredLatencyMilliseconds: number;
+	    underruns: number;
+	    overruns: number;
+	    droppedFrames: number;
+	    driftPpm: number;
+	
+	    static createFrom(source: any = {}) {
+	        return new LoopbackStats(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.captureMilliseconds = source["captureMilliseconds"];
+	        this.queueMilliseconds = source["queueMilliseconds"];
+	        this.playbackMilliseconds = source["playbackMilliseconds"];
+	        this.estimatedLatencyMilliseconds = source["estimatedLatencyMilliseconds"];
+	        this.roundTripMilliseconds = source["roundTripMilliseconds"];
+	        this.measuredLatencyMilliseconds = source["measuredLatencyMilliseconds"];
+	        this.underruns = source["underruns"];
+	        this.overruns = source["overruns"];
+	        this.droppedFrames = source["droppedFrames"];
+	        this.driftPpm = source["driftPpm"];
+	    }
+	}
+	export class MIDIBinding {
+	    type: string;
+	    channel: number;
+	    number: number;
+	    action: string;
+	    arg: string;
+	
+	    static createFrom(source: any = {}) {
+	        return new MIDIBinding(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.type = source["type"];
+	        this.channel = source["channel"];
+	        this.number = source["number"];
+	        this.action = source["action"];
+	        this.arg = source["arg"];
+	    }
+	}
+	export class MIDIDeviceInfo {
+	    deviceId: string;
+	    label: string;
+	
+	    static createFrom(source: any = {}) {
+	        return new MIDIDeviceInfo(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.deviceId = source["deviceId"];
+	        this.label = source["label"];
+	    }
+	}
+	export class MIDIFeedbackProfile {
+	    name: string;
+	    channel: number;
+	    idle: number;
+	    playing: number;
+	    looping: number;
+	    missing: number;
+	
+	    static createFrom(source: any = {}) {
+	        return new MIDIFeedbackProfile(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.name = source["name"];
+	        this.channel = source["channel"];
+	        this.idle = source["idle"];
+	        this.playing = source["playing"];
+	        this.looping = source["looping"];
+	        this.missing = source["missing"];
+	    }
+	}
 	export class MediaDeviceInfo {
 	    deviceId: string;
 	    groupId: string;
 	    kind: string;
 	    label: string;
+	    backend: string;
+	    isDefault: boolean;
+	    formats: DeviceFormat[];
+	    minChannels: number;
+	    maxChannels: number;
+	    minSampleRate: number;
+	    maxSampleRate: number;
 	
 	    static createFrom(source: any = {}) {
 	        return new MediaDeviceInfo(source);
@@ -30,6 +538,80 @@ export namespace main {
 	        this.groupId = source["groupId"];
 	        this.kind = source["kind"];
 	        this.label = source["label"];
+	        this.backend = source["backend"];
+	        this.isDefault = source["isDefault"];
+	        this.formats = this.convertValues(source["formats"], DeviceFormat);
+	        this.minChannels = source["minChannels"];
+	        this.maxChannels = source["maxChannels"];
+	        this.minSampleRate = source["minSampleRate"];
+	        this.maxSampleRate = source["maxSampleRate"];
+	    }
+	
+		convertValues(a: any, classs: any, asMap: boolean = false): any {
+		    if (!a) {
+		        return a;
+		    }
+		    if (a.slice && a.map) {
+		        return (a as any[]).map(elem => this.convertValues(elem, classs));
+		    } else if ("object" === typeof a) {
+		        if (asMap) {
+		            for (const key of Object.keys(a)) {
+		                a[key] = new classs(a[key]);
+		            }
+		            return a;
+		        }
+		        return new classs(a);
+		    }
+		    return a;
+		}
+	}
+	export class MonitorSettings {
+	    enabled: boolean;
+	    volume: number;
+	    includeMic: boolean;
+	
+	    static createFrom(source: any = {}) {
+	        return new MonitorSettings(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.enabled = source["enabled"];
+	        this.volume = source["volume"];
+	        this.includeMic = source["includeMic"];
+	    }
+	}
+	
+	export class NoiseSuppressionSettings {
+	    enabled: boolean;
+	    reductionDb: number;
+	    noiseFloor: number[];
+	    noiseFloorSampleRate: number;
+	
+	    static createFrom(source: any = {}) {
+	        return new NoiseSuppressionSettings(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.enabled = source["enabled"];
+	        this.reductionDb = source["reductionDb"];
+	        this.noiseFloor = source["noiseFloor"];
+	        this.noiseFloorSampleRate = source["noiseFloorSampleRate"];
+	    }
+	}
+	export class OSCSettings {
+	    enabled: boolean;
+	    address: string;
+	
+	    static createFrom(source: any = {}) {
+	        return new OSCSettings(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.enabled = source["enabled"];
+	        this.address = source["address"];
 	    }
 	}
 	export class OpenDialogOptions {
@@ -76,6 +658,97 @@ export namespace main {
 		    return a;
 		}
 	}
+	export class OutputBus {
+	    name: string;
+	    volume: number;
+	    includeMic: boolean;
+	
+	    static createFrom(source: any = {}) {
+	        return new OutputBus(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.name = source["name"];
+	        this.volume = source["volume"];
+	        this.includeMic = source["includeMic"];
+	    }
+	}
+	
+	export class RecordingState {
+	    recording: boolean;
+	    path: string;
+	    format: string;
+	    bus: string;
+	    seconds: number;
+	    bytes: number;
+	    freeBytes: number;
+	    error: string;
+	
+	    static createFrom(source: any = {}) {
+	        return new RecordingState(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.recording = source["recording"];
+	        this.path = source["path"];
+	        this.format = source["format"];
+	        this.bus = source["bus"];
+	        this.seconds = source["seconds"];
+	        this.bytes = source["bytes"];
+	        this.freeBytes = source["freeBytes"];
+	        this.error = source["error"];
+	    }
+	}
+	export class RemoteControlSettings {
+	    enabled: boolean;
+	    address: string;
+	    token: string;
+	
+	    static createFrom(source: any = {}) {
+	        return new RemoteControlSettings(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.enabled = source["enabled"];
+	        this.address = source["address"];
+	        this.token = source["token"];
+	    }
+	}
+	export class ReplayBufferSettings {
+	    enabled: boolean;
+	    seconds: number;
+	    source: string;
+	    keybinding: string;
+	
+	    static createFrom(source: any = {}) {
+	        return new ReplayBufferSettings(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.enabled = source["enabled"];
+	        this.seconds = source["seconds"];
+	        this.source = source["source"];
+	        this.keybinding = source["keybinding"];
+	    }
+	}
+	
+	
+	export class VirtualMicSettings {
+	    enabled: boolean;
+	
+	    static createFrom(source: any = {}) {
+	        return new VirtualMicSettings(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.enabled = source["enabled"];
+	    }
+	}
 
 }
 
@@ -0,0 +1,260 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	goruntime "runtime"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	hook "github.com/robotn/gohook"
+)
+
+// hotkeyModifiers lists modifier keys in the order they appear in a canonical binding
+var hotkeyModifiers = []string{"ctrl", "rctrl", "shift", "rshift", "alt", "ralt", "cmd", "rcmd"}
+
+// hotkeyExtraKeycodes adds keys that gohook reports but does not name
+var hotkeyExtraKeycodes = map[string]uint16{
+	"rctrl": 3613,
+}
+
+// hotkeyKeycodes maps binding key names to gohook keycodes
+var hotkeyKeycodes = map[string]uint16{}
+
+// hotkeyNames maps gohook keycodes to their canonical binding key name
+var hotkeyNames = map[uint16]string{}
+
+func init() {
+	for name, keycode := range hook.Keycode {
+		hotkeyKeycodes[name] = keycode
+	}
+	for name, keycode := range hotkeyExtraKeycodes {
+		hotkeyKeycodes[name] = keycode
+	}
+
+	names := make([]string, 0, len(hotkeyKeycodes))
+	for name := range hotkeyKeycodes {
+		// Shifted symbols and long aliases share a keycode with a canonical name
+		if _, ok := hook.Special[name]; ok || name == "control" || name == "command" {
+			continue
+		}
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		if _, ok := hotkeyNames[hotkeyKeycodes[name]]; !ok {
+			hotkeyNames[hotkeyKeycodes[name]] = name
+		}
+	}
+}
+
+// hotkeysAvailable reports whether global hotkeys can be hooked, which on
+// Linux needs an X display
+func hotkeysAvailable() bool {
+	return goruntime.GOOS != "linux" || os.Getenv("DISPLAY") != ""
+}
+
+// ParseHotkey parses a binding such as "ctrl + shift + a" into gohook keycodes
+func ParseHotkey(binding string) ([]uint16, error) {
+	var keycodes []uint16
+	for _, name := range strings.Split(strings.ToLower(binding), " + ") {
+		name = strings.TrimSpace(name)
+		if name == "" {
+			continue
+		}
+
+		if keycode, ok := hotkeyKeycodes[name]; ok {
+			keycodes = append(keycodes, keycode)
+			continue
+		}
+
+		if code, ok := strings.CutPrefix(name, "code"); ok {
+			keycode, err := strconv.ParseUint(code, 10, 16)
+			if err == nil {
+				keycodes = append(keycodes, uint16(keycode))
+				continue
+			}
+		}
+
+		return nil, fmt.Errorf("unknown key %q in hotkey %q", name, binding)
+	}
+
+	if len(keycodes) == 0 {
+		return nil, fmt.Errorf("empty hotkey %q", binding)
+	}
+
+	return keycodes, nil
+}
+
+// FormatHotkey formats gohook keycodes as a canonical binding, modifiers first
+func FormatHotkey(keycodes []uint16) string {
+	var modifiers, keys []string
+	for _, keycode := range keycodes {
+		name, ok := hotkeyNames[keycode]
+		if !ok {
+			name = fmt.Sprintf("code%d", keycode)
+		}
+
+		if isHotkeyModifier(name) {
+			modifiers = append(modifiers, name)
+		} else {
+			keys = append(keys, name)
+		}
+	}
+
+	sort.Slice(modifiers, func(i, j int) bool {
+		return hotkeyModifierIndex(modifiers[i]) < hotkeyModifierIndex(modifiers[j])
+	})
+
+	return strings.Join(append(modifiers, keys...), " + ")
+}
+
+func isHotkeyModifier(name string) bool {
+	return hotkeyModifierIndex(name) >= 0
+}
+
+func hotkeyModifierIndex(name string) int {
+	for i, modifier := range hotkeyModifiers {
+		if modifier == name {
+			return i
+		}
+	}
+	return -1
+}
+
+type hotkeyBinding struct {
+	keycodes []uint16
+	callback func()
+}
+
+// HotkeyDispatcher fires callbacks for key chords read from the gohook event
+// stream, and records new chords while a capture is in progress
+type HotkeyDispatcher struct {
+	mu        sync.Mutex
+	bindings  []hotkeyBinding
+	pressed   map[uint16]bool
+	capturing bool
+	chord     []uint16
+	onCapture func(string)
+}
+
+// NewHotkeyDispatcher creates a new HotkeyDispatcher
+func NewHotkeyDispatcher() *HotkeyDispatcher {
+	return &HotkeyDispatcher{
+		pressed: make(map[uint16]bool),
+	}
+}
+
+// SetBindings replaces all registered bindings, skipping ones that fail to parse
+func (d *HotkeyDispatcher) SetBindings(bindings map[string]func()) error {
+	var parsed []hotkeyBinding
+	var errs []string
+	for binding, callback := range bindings {
+		keycodes, err := ParseHotkey(binding)
+		if err != nil {
+			errs = append(errs, err.Error())
+			continue
+		}
+
+		parsed = append(parsed, hotkeyBinding{
+			keycodes: keycodes,
+			callback: callback,
+		})
+	}
+
+	d.mu.Lock()
+	d.bindings = parsed
+	d.mu.Unlock()
+
+	if len(errs) > 0 {
+		return fmt.Errorf("%s", strings.Join(errs, "; "))
+	}
+
+	return nil
+}
+
+// BeginCapture starts recording chords, calling onCapture with the canonical
+// binding whenever the chord being held changes. Bindings do not fire while
+// capturing.
+func (d *HotkeyDispatcher) BeginCapture(onCapture func(string)) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	d.capturing = true
+	d.chord = nil
+	d.onCapture = onCapture
+}
+
+// EndCapture stops recording chords
+func (d *HotkeyDispatcher) EndCapture() {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	d.capturing = false
+	d.chord = nil
+	d.onCapture = nil
+}
+
+// Run dispatches events until the channel is closed
+func (d *HotkeyDispatcher) Run(events <-chan hook.Event) {
+	for e := range events {
+		d.handle(e)
+	}
+}
+
+func (d *HotkeyDispatcher) handle(e hook.Event) {
+	var callbacks []func()
+
+	d.mu.Lock()
+	switch e.Kind {
+	case hook.KeyDown, hook.KeyHold:
+		// Typed events carry no keycode, and held keys repeat their press
+		if e.Keycode == 0 || d.pressed[e.Keycode] {
+			break
+		}
+
+		if d.capturing {
+			// A new chord starts once every key of the previous one is released
+			if len(d.pressed) == 0 {
+				d.chord = nil
+			}
+			d.pressed[e.Keycode] = true
+			d.chord = append(d.chord, e.Keycode)
+
+			onCapture, binding := d.onCapture, FormatHotkey(d.chord)
+			callbacks = append(callbacks, func() { onCapture(binding) })
+			break
+		}
+
+		d.pressed[e.Keycode] = true
+		for _, binding := range d.bindings {
+			if d.completes(binding.keycodes, e.Keycode) {
+				callbacks = append(callbacks, binding.callback)
+			}
+		}
+	case hook.KeyUp:
+		delete(d.pressed, e.Keycode)
+	}
+	d.mu.Unlock()
+
+	for _, callback := range callbacks {
+		callback()
+	}
+}
+
+// completes reports whether pressing keycode completed the given chord
+func (d *HotkeyDispatcher) completes(keycodes []uint16, keycode uint16) bool {
+	found := false
+	for _, k := range keycodes {
+		if !d.pressed[k] {
+			return false
+		}
+		if k == keycode {
+			found = true
+		}
+	}
+	return found
+}
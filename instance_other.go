@@ -0,0 +1,9 @@
+//go:build !unix
+
+package main
+
+// lockInstance does nothing where file locks are unavailable; listenControl
+// still refuses to replace a socket that accepts connections
+func lockInstance(path string) error {
+	return nil
+}
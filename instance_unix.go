@@ -0,0 +1,29 @@
+//go:build unix
+
+package main
+
+import (
+	"os"
+	"syscall"
+)
+
+// instanceLock stays open for the life of the process so that the lock is
+// held until it exits
+var instanceLock *os.File
+
+// lockInstance takes an exclusive lock on path, failing if another process holds it
+func lockInstance(path string) error {
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_RDWR, 0600)
+	if err != nil {
+		return err
+	}
+
+	if err := syscall.Flock(int(file.Fd()), syscall.LOCK_EX|syscall.LOCK_NB); err != nil {
+		file.Close()
+		return errAlreadyRunning
+	}
+
+	instanceLock = file
+
+	return nil
+}
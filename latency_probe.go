@@ -0,0 +1,150 @@
+package main
+
+import (
+	"errors"
+	"math"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// latencyProbeTimeout is how long MeasureLoopbackLatency waits to hear its
+// click
+const latencyProbeTimeout = 2 * time.Second
+
+// The click is a short burst of a tone, which survives speakers and mics that
+// would smear a single sample
+const (
+	latencyProbeClickDuration = 10 * time.Millisecond
+	latencyProbeFrequency     = 1000
+	latencyProbeAmplitude     = 0.5
+)
+
+// latencyProbeMinThreshold is the lowest level the click is heard at, and the
+// threshold is raised above whatever the mic picks up before the click
+const latencyProbeMinThreshold = 0.01
+
+// LatencyProbe measures the round trip from the playback device back to the
+// capture device by playing a click and timing when it is heard. Play is
+// called from the playback callback with the final output, and Capture from
+// the capture callback, each with the time of the callback.
+type LatencyProbe struct {
+	sampleRate uint32
+	channels   int
+	click      []float32
+	armed      atomic.Bool
+	played     int
+	playedAt   atomic.Int64
+	noisePeak  float64
+	threshold  float64
+	roundTrip  chan time.Duration
+	heardOnce  sync.Once
+}
+
+// NewLatencyProbe creates a new LatencyProbe for the loopback format
+func NewLatencyProbe(sampleRate uint32, channels int) *LatencyProbe {
+	click := make([]float32, int(latencyProbeClickDuration.Seconds()*float64(sampleRate)))
+	for i := range click {
+		click[i] = float32(latencyProbeAmplitude * math.Sin(2*math.Pi*latencyProbeFrequency*float64(i)/float64(sampleRate)))
+	}
+
+	return &LatencyProbe{
+		sampleRate: sampleRate,
+		channels:   channels,
+		click:      click,
+		roundTrip:  make(chan time.Duration, 1),
+	}
+}
+
+// Arm has the click played from the next playback callback on
+func (p *LatencyProbe) Arm() {
+	p.armed.Store(true)
+}
+
+// Play mixes the click into the output once armed
+func (p *LatencyProbe) Play(samples []float32, now time.Time) {
+	if !p.armed.Load() || p.played == len(p.click) {
+		return
+	}
+	if p.played == 0 {
+		p.playedAt.Store(now.UnixNano())
+	}
+
+	for i := 0; i+p.channels <= len(samples) && p.played < len(p.click); i += p.channels {
+		for ch := 0; ch < p.channels; ch++ {
+			samples[i+ch] += p.click[p.played]
+		}
+		p.played++
+	}
+}
+
+// Capture listens for the click, timing it from the first frame loud enough.
+// Until the click is played, it learns how loud the mic is without it.
+func (p *LatencyProbe) Capture(samples []float32, now time.Time) {
+	playedAt := p.playedAt.Load()
+	if playedAt == 0 {
+		p.noisePeak = max(p.noisePeak, framePeak(samples))
+		return
+	}
+	if p.threshold == 0 {
+		p.threshold = max(latencyProbeMinThreshold, 4*p.noisePeak)
+	}
+
+	frames := len(samples) / p.channels
+	for i, s := range samples {
+		if math.Abs(float64(s)) < p.threshold {
+			continue
+		}
+
+		// The callback comes once its last frame is captured
+		capturedAt := now.Add(-time.Duration(frames-1-i/p.channels) * time.Second / time.Duration(p.sampleRate))
+		p.heardOnce.Do(func() {
+			p.roundTrip <- capturedAt.Sub(time.Unix(0, playedAt))
+		})
+		return
+	}
+}
+
+// RoundTrip is sent the time from the click being played to it being heard
+func (p *LatencyProbe) RoundTrip() <-chan time.Duration {
+	return p.roundTrip
+}
+
+// MeasureLoopbackLatency measures the latency of the loopback by playing a
+// click on its playback device and timing how long the capture device takes
+// to hear it, which takes the output looped back to the input, with a cable or
+// by holding the headphones to the mic. The click goes out on the virtual mic
+// too. The loopback latency reported in its stats is this round trip through
+// both devices along with the audio queued between them.
+func (a *App) MeasureLoopbackLatency() error {
+	a.loopbackMu.Lock()
+	running := a.loopbackDone != nil
+	a.loopbackMu.Unlock()
+
+	if !running {
+		return errors.New("the loopback is not running")
+	}
+
+	loopbackSettings, err := a.GetLoopbackSettings()
+	if err != nil {
+		return err
+	}
+
+	probe := NewLatencyProbe(loopbackSettings.SampleRate, int(loopbackSettings.Channels))
+	if !a.latencyProbe.CompareAndSwap(nil, probe) {
+		return errors.New("already measuring the latency")
+	}
+	defer a.latencyProbe.CompareAndSwap(probe, nil)
+
+	// The mic is listened to for a while before the click
+	time.Sleep(100 * time.Millisecond)
+	probe.Arm()
+
+	select {
+	case roundTrip := <-probe.RoundTrip():
+		a.roundTripMilliseconds.Store(math.Float64bits(math.Round(float64(roundTrip.Microseconds())/100) / 10))
+		return nil
+	case <-time.After(latencyProbeTimeout):
+		return errors.New("the click was not heard, loop the output back to the input")
+	}
+}
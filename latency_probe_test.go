@@ -0,0 +1,73 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+func TestLatencyProbe(t *testing.T) {
+	const blockFrames = 480
+
+	tests := []struct {
+		name      string
+		channels  int
+		noise     float32
+		delay     time.Duration
+		offset    int
+		level     float32
+		wantHeard bool
+	}{
+		{"mono", 1, 0.001, 30 * time.Millisecond, 100, 0.5, true},
+		{"stereo", 2, 0.001, 50 * time.Millisecond, 0, 0.1, true},
+		{"under the noise", 1, 0.05, 30 * time.Millisecond, 100, 0.1, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			probe := NewLatencyProbe(testSampleRate, tt.channels)
+			start := time.Unix(1000, 0)
+
+			noise := make([]float32, blockFrames*tt.channels)
+			for i := range noise {
+				noise[i] = tt.noise * float32(1-2*(i%2))
+			}
+			probe.Capture(noise, start.Add(-10*time.Millisecond))
+
+			// Nothing is played until armed
+			output := make([]float32, blockFrames*tt.channels)
+			probe.Play(output, start)
+			if framePeak(output) != 0 {
+				t.Fatal("played before being armed")
+			}
+			probe.Arm()
+			probe.Play(output, start)
+			if peak := framePeak(output); peak < latencyProbeAmplitude*0.9 {
+				t.Fatalf("played the click at %.2f", peak)
+			}
+
+			// The click comes back quieter, offset frames into a block
+			// captured delay after it was played
+			captured := append([]float32(nil), noise...)
+			for i := tt.offset * tt.channels; i < len(captured); i++ {
+				captured[i] += output[i-tt.offset*tt.channels] * tt.level / latencyProbeAmplitude
+			}
+			capturedAt := start.Add(tt.delay + time.Duration(blockFrames-1-tt.offset)*time.Second/testSampleRate)
+			probe.Capture(captured, capturedAt)
+
+			select {
+			case roundTrip := <-probe.RoundTrip():
+				if !tt.wantHeard {
+					t.Fatalf("heard the click after %v under the noise", roundTrip)
+				}
+				// The tone crosses the threshold within its first cycle
+				if roundTrip < tt.delay || roundTrip > tt.delay+time.Millisecond {
+					t.Errorf("got a round trip of %v, want %v", roundTrip, tt.delay)
+				}
+			default:
+				if tt.wantHeard {
+					t.Error("did not hear the click")
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,432 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"math"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/gen2brain/malgo"
+)
+
+// LoopbackSettings configures the loopback stream. A period size or period
+// count of 0 leaves the choice to the backend. The target fill is how much
+// audio is kept queued between the capture and playback devices to absorb
+// jitter and the drift between their clocks.
+type LoopbackSettings struct {
+	SampleRate               uint32 `json:"sampleRate"`
+	Channels                 uint32 `json:"channels"`
+	PeriodSizeInMilliseconds uint32 `json:"periodSizeInMilliseconds"`
+	Periods                  uint32 `json:"periods"`
+	LowLatency               bool   `json:"lowLatency"`
+	TargetFillMilliseconds   uint32 `json:"targetFillMilliseconds"`
+}
+
+// defaultLoopbackSettings matches the stream format of earlier versions
+var defaultLoopbackSettings = LoopbackSettings{
+	SampleRate: 44100,
+	Channels:   1,
+}
+
+// Low latency preset, used for the period size, count and target fill when
+// they are not set
+const (
+	lowLatencyPeriodSizeInMilliseconds = 5
+	lowLatencyPeriods                  = 2
+	lowLatencyTargetFillMilliseconds   = 10
+)
+
+// defaultTargetFillMilliseconds is the target fill when none is set
+const defaultTargetFillMilliseconds = 20
+
+// defaultPeriods is the number of periods miniaudio uses when none is set
+const defaultPeriods = 3
+
+// Bounds of the buffering settings, beyond which devices either refuse to
+// open or add more latency than a loopback is any use with
+const (
+	maxPeriodSizeInMilliseconds = 100
+	minPeriods                  = 2
+	maxPeriods                  = 16
+	maxTargetFillMilliseconds   = 1000
+)
+
+// LoopbackStats reports the latency of the loopback along with glitch
+// counters since it started. The estimated latency adds up the callback sizes,
+// the queued audio and the number of playback periods. The measured latency
+// adds the queued audio to the round trip MeasureLoopbackLatency last timed
+// through the devices, and is 0 until then. DriftPPM is how much faster the
+// capture clock runs than the playback clock, in parts per million, as
+// measured by the resampler compensating for it.
+type LoopbackStats struct {
+	CaptureMilliseconds          float64 `json:"captureMilliseconds"`
+	QueueMilliseconds            float64 `json:"queueMilliseconds"`
+	PlaybackMilliseconds         float64 `json:"playbackMilliseconds"`
+	EstimatedLatencyMilliseconds float64 `json:"estimatedLatencyMilliseconds"`
+	RoundTripMilliseconds        float64 `json:"roundTripMilliseconds"`
+	MeasuredLatencyMilliseconds  float64 `json:"measuredLatencyMilliseconds"`
+	Underruns                    uint64  `json:"underruns"`
+	Overruns                     uint64  `json:"overruns"`
+	DroppedFrames                uint64  `json:"droppedFrames"`
+	DriftPPM                     float64 `json:"driftPpm"`
+}
+
+// GetLoopbackSettings gets the loopback stream settings
+func (a *App) GetLoopbackSettings() (LoopbackSettings, error) {
+	serializedLoopbackSettings, _ := a.fs.GetItem("loopbackSettings")
+	if serializedLoopbackSettings == "" {
+		return defaultLoopbackSettings, nil
+	}
+
+	var loopbackSettings LoopbackSettings
+	if err := json.Unmarshal([]byte(serializedLoopbackSettings), &loopbackSettings); err != nil {
+		return LoopbackSettings{}, err
+	}
+
+	return loopbackSettings, nil
+}
+
+// SetLoopbackSettings sets the loopback stream settings and restarts the loopback
+func (a *App) SetLoopbackSettings(loopbackSettings LoopbackSettings) error {
+	if loopbackSettings.SampleRate < 8000 || loopbackSettings.SampleRate > 384000 {
+		return fmt.Errorf("unsupported sample rate %d", loopbackSettings.SampleRate)
+	}
+
+	if loopbackSettings.Channels != 1 && loopbackSettings.Channels != 2 {
+		return fmt.Errorf("unsupported channel count %d", loopbackSettings.Channels)
+	}
+
+	if loopbackSettings.PeriodSizeInMilliseconds > maxPeriodSizeInMilliseconds {
+		return fmt.Errorf("the period size is at most %d ms", maxPeriodSizeInMilliseconds)
+	}
+
+	if loopbackSettings.Periods != 0 && (loopbackSettings.Periods < minPeriods || loopbackSettings.Periods > maxPeriods) {
+		return fmt.Errorf("the period count is between %d and %d", minPeriods, maxPeriods)
+	}
+
+	if loopbackSettings.TargetFillMilliseconds > maxTargetFillMilliseconds {
+		return fmt.Errorf("the target fill is at most %d ms", maxTargetFillMilliseconds)
+	}
+
+	serializedLoopbackSettings, err := json.Marshal(loopbackSettings)
+	if err != nil {
+		return err
+	}
+
+	if err := a.fs.SetItem("loopbackSettings", string(serializedLoopbackSettings)); err != nil {
+		return err
+	}
+
+	a.loopbackMu.Lock()
+	// Restart on the same devices, or leave it to the device monitor if stopped
+	if a.loopbackDone != nil {
+		a.startLoopbackAudio(a.loopbackDevices)
+	}
+	a.loopbackMu.Unlock()
+
+	// The replay buffer only takes in audio at the format it was started at
+	return a.restartReplayBuffer()
+}
+
+// GetLoopbackStats gets the estimated latency and glitch counters of the running loopback
+func (a *App) GetLoopbackStats() (LoopbackStats, error) {
+	if loopbackStats := a.loopbackStats.Load(); loopbackStats != nil {
+		return *loopbackStats, nil
+	}
+
+	return LoopbackStats{}, nil
+}
+
+// applyLoopbackSettings sets the stream format and buffering of a device config
+func applyLoopbackSettings(deviceConfig *malgo.DeviceConfig, loopbackSettings LoopbackSettings) {
+	deviceConfig.SampleRate = loopbackSettings.SampleRate
+	deviceConfig.PeriodSizeInMilliseconds = loopbackSettings.PeriodSizeInMilliseconds
+	deviceConfig.Periods = loopbackSettings.Periods
+
+	if loopbackSettings.LowLatency {
+		if deviceConfig.PeriodSizeInMilliseconds == 0 {
+			deviceConfig.PeriodSizeInMilliseconds = lowLatencyPeriodSizeInMilliseconds
+		}
+		if deviceConfig.Periods == 0 {
+			deviceConfig.Periods = lowLatencyPeriods
+		}
+	}
+}
+
+// loopbackAudio loops back audio from the capture device to the playback
+// device, and to the devices of the output buses, until ctx is done or any
+// of them stops. An empty ID selects the default device.
+func (a *App) loopbackAudio(ctx context.Context, loopbackDevices LoopbackDevices) error {
+	loopbackSettings, err := a.GetLoopbackSettings()
+	if err != nil {
+		return err
+	}
+
+	targetFillMilliseconds := loopbackSettings.TargetFillMilliseconds
+	if targetFillMilliseconds == 0 {
+		targetFillMilliseconds = defaultTargetFillMilliseconds
+		if loopbackSettings.LowLatency {
+			targetFillMilliseconds = lowLatencyTargetFillMilliseconds
+		}
+	}
+
+	channels := int(loopbackSettings.Channels)
+	targetFill := int(loopbackSettings.SampleRate*targetFillMilliseconds/1000) * channels
+
+	// The round trip measured through other devices or settings no longer holds
+	a.roundTripMilliseconds.Store(0)
+
+	// Hold up to a second, and always a few times the target fill
+	queue := NewRingBuffer(max(int(loopbackSettings.SampleRate)*channels, 8*targetFill))
+
+	stopped := make(chan struct{})
+	var stopOnce sync.Once
+	onStop := func() {
+		stopOnce.Do(func() {
+			close(stopped)
+		})
+	}
+
+	// Callback sizes are measured to report the latency
+	var captureFrames, playbackFrames atomic.Uint32
+	var droppedFrames atomic.Uint64
+	var driftRatio atomic.Uint64
+	driftRatio.Store(math.Float64bits(1))
+
+	captureDeviceConfig := malgo.DefaultDeviceConfig(malgo.Capture)
+	captureDeviceConfig.Alsa.NoMMap = 1
+	captureDeviceConfig.Capture.Channels = loopbackSettings.Channels
+	captureDeviceConfig.Capture.Format = malgo.FormatF32
+	applyLoopbackSettings(&captureDeviceConfig, loopbackSettings)
+
+	if loopbackDevices.CaptureDeviceID != "" {
+		deviceID, err := ParseHexStringToDeviceID(loopbackDevices.CaptureDeviceID)
+		if err != nil {
+			return err
+		}
+		captureDeviceConfig.Capture.DeviceID = deviceID.Pointer()
+	}
+
+	captureDeviceCallbacks := malgo.DeviceCallbacks{
+		Data: func(_, pInputSamples []byte, frameCount uint32) {
+			captureFrames.Store(frameCount)
+
+			if probe := a.latencyProbe.Load(); probe != nil {
+				probe.Capture(float32Samples(pInputSamples), time.Now())
+			}
+
+			// malgo reuses the buffer, so the samples are copied into the queue
+			queue.Write(float32Samples(pInputSamples))
+		},
+		Stop: onStop,
+	}
+
+	captureDevice, err := a.engine.OpenDevice(captureDeviceConfig, captureDeviceCallbacks)
+	if err != nil {
+		return err
+	}
+	defer captureDevice.Close()
+
+	playbackDeviceConfig := malgo.DefaultDeviceConfig(malgo.Playback)
+	playbackDeviceConfig.Alsa.NoMMap = 1
+	playbackDeviceConfig.Playback.Channels = loopbackSettings.Channels
+	playbackDeviceConfig.Playback.Format = malgo.FormatF32
+	applyLoopbackSettings(&playbackDeviceConfig, loopbackSettings)
+
+	if loopbackDevices.PlaybackDeviceID != "" {
+		deviceID, err := ParseHexStringToDeviceID(loopbackDevices.PlaybackDeviceID)
+		if err != nil {
+			return err
+		}
+		playbackDeviceConfig.Playback.DeviceID = deviceID.Pointer()
+	}
+
+	a.applyNoiseSuppressionSettings(loopbackDevices.captureDevice)
+
+	// The effect chain is built here and by the effect settings setters, and
+	// the playback callback only picks up the latest one
+	effects := NewLiveEffectChain(loopbackSettings.SampleRate, channels)
+	a.effectChain.Store(effects)
+	defer a.effectChain.CompareAndSwap(effects, nil)
+	effects.Update(&a.effectSettings)
+
+	// The resampler and noise suppressor are only touched by the playback
+	// callback
+	resampler := NewDriftResampler(channels, targetFill)
+	var noiseSuppressionSettings *NoiseSuppressionSettings
+	var noiseSuppressor Processor
+	mixer := a.mixer.Attach(loopbackSettings.SampleRate, channels)
+	defer mixer.Detach()
+
+	for _, busDevice := range loopbackDevices.Buses {
+		device, err := a.openBusDevice(busDevice, mixer, loopbackSettings, targetFill, onStop)
+		if err != nil {
+			return err
+		}
+		defer device.Close()
+
+		if err := device.Start(); err != nil {
+			return err
+		}
+		defer func() {
+			if err := device.Stop(); err != nil {
+				log.Println(err)
+			}
+		}()
+	}
+
+	playbackDeviceCallbacks := malgo.DeviceCallbacks{
+		Data: func(pOutputSample, _ []byte, frameCount uint32) {
+			playbackFrames.Store(frameCount)
+
+			// The resampler keeps the queue at its target fill however far
+			// apart the device clocks drift
+			resampler.Read(queue, float32Samples(pOutputSample))
+			driftRatio.Store(math.Float64bits(resampler.Ratio()))
+
+			// A stalled playback device can still leave far too much queued,
+			// which is trimmed back to the target fill rather than played late
+			if fill := queue.Len(); fill > 4*targetFill {
+				excess := fill - targetFill
+				excess -= excess % channels
+				droppedFrames.Add(uint64(queue.Discard(excess) / channels))
+			}
+
+			scaleSamples(pOutputSample, malgo.FormatF32, math.Float64frombits(a.micGain.Load()))
+
+			if profiler := a.noiseProfiler.Load(); profiler != nil {
+				profiler.Write(float32Samples(pOutputSample))
+			}
+
+			if settings := a.noiseSuppressionSettings.Load(); settings != noiseSuppressionSettings {
+				noiseSuppressionSettings = settings
+				noiseSuppressor = newNoiseSuppressor(settings, loopbackSettings.SampleRate, channels)
+			}
+			if noiseSuppressor != nil {
+				noiseSuppressor.Process(float32Samples(pOutputSample))
+			}
+
+			effects.Process(float32Samples(pOutputSample))
+
+			mixer.Mix(float32Samples(pOutputSample), float32(math.Float64frombits(a.volume.Load())))
+
+			if probe := a.latencyProbe.Load(); probe != nil {
+				probe.Play(float32Samples(pOutputSample), time.Now())
+			}
+		},
+		Stop: onStop,
+	}
+
+	playbackDevice, err := a.engine.OpenDevice(playbackDeviceConfig, playbackDeviceCallbacks)
+	if err != nil {
+		return err
+	}
+	defer playbackDevice.Close()
+
+	if err := captureDevice.Start(); err != nil {
+		return err
+	}
+
+	if err := playbackDevice.Start(); err != nil {
+		return err
+	}
+
+	periods := playbackDeviceConfig.Periods
+	if periods == 0 {
+		periods = defaultPeriods
+	}
+
+	ticker := time.NewTicker(time.Second)
+	defer ticker.Stop()
+
+loop:
+	for {
+		select {
+		case <-ctx.Done():
+			break loop
+		case <-stopped:
+			err = errDeviceStopped
+			break loop
+		case <-ticker.C:
+			sampleRate := playbackDevice.SampleRate()
+			a.updateLoopbackStats(LoopbackStats{
+				CaptureMilliseconds:   framesToMilliseconds(captureFrames.Load(), sampleRate),
+				QueueMilliseconds:     framesToMilliseconds(uint32(queue.Len()/channels), sampleRate),
+				PlaybackMilliseconds:  framesToMilliseconds(playbackFrames.Load()*periods, sampleRate),
+				Underruns:             queue.Underruns(),
+				Overruns:              queue.Overruns(),
+				DroppedFrames:         droppedFrames.Load(),
+				RoundTripMilliseconds: math.Float64frombits(a.roundTripMilliseconds.Load()),
+				DriftPPM:              math.Round((math.Float64frombits(driftRatio.Load())-1)*1e7) / 10,
+			})
+		}
+	}
+
+	if err := captureDevice.Stop(); err != nil {
+		return err
+	}
+
+	if err := playbackDevice.Stop(); err != nil {
+		return err
+	}
+
+	return err
+}
+
+// openBusDevice opens the playback device of an output bus, which plays what
+// the mixer queues for the bus. The device runs on a clock of its own, so it
+// gets a queue and resampler just like the capture device.
+func (a *App) openBusDevice(busDevice BusDevice, mixer *MixerOutput, loopbackSettings LoopbackSettings, targetFill int, onStop func()) (*EngineDevice, error) {
+	channels := int(loopbackSettings.Channels)
+	queue := NewRingBuffer(max(int(loopbackSettings.SampleRate)*channels, 8*targetFill))
+	resampler := NewDriftResampler(channels, targetFill)
+	mixer.AddBus(busDevice.Bus, queue)
+
+	deviceConfig := malgo.DefaultDeviceConfig(malgo.Playback)
+	deviceConfig.Alsa.NoMMap = 1
+	deviceConfig.Playback.Channels = loopbackSettings.Channels
+	deviceConfig.Playback.Format = malgo.FormatF32
+	applyLoopbackSettings(&deviceConfig, loopbackSettings)
+
+	if busDevice.DeviceID != "" {
+		deviceID, err := ParseHexStringToDeviceID(busDevice.DeviceID)
+		if err != nil {
+			return nil, err
+		}
+		deviceConfig.Playback.DeviceID = deviceID.Pointer()
+	}
+
+	deviceCallbacks := malgo.DeviceCallbacks{
+		Data: func(pOutputSample, _ []byte, _ uint32) {
+			resampler.Read(queue, float32Samples(pOutputSample))
+		},
+		Stop: onStop,
+	}
+
+	return a.engine.OpenDevice(deviceConfig, deviceCallbacks)
+}
+
+func framesToMilliseconds(frames uint32, sampleRate uint32) float64 {
+	if sampleRate == 0 {
+		return 0
+	}
+	return math.Round(float64(frames)*10000/float64(sampleRate)) / 10
+}
+
+// updateLoopbackStats stores the loopback stats, emitting them when they change
+func (a *App) updateLoopbackStats(loopbackStats LoopbackStats) {
+	loopbackStats.EstimatedLatencyMilliseconds = loopbackStats.CaptureMilliseconds + loopbackStats.QueueMilliseconds + loopbackStats.PlaybackMilliseconds
+	if loopbackStats.RoundTripMilliseconds > 0 {
+		loopbackStats.MeasuredLatencyMilliseconds = loopbackStats.RoundTripMilliseconds + loopbackStats.QueueMilliseconds
+	}
+
+	if previous := a.loopbackStats.Swap(&loopbackStats); previous != nil && *previous == loopbackStats {
+		return
+	}
+
+	a.emit("loopbackStats", loopbackStats)
+}
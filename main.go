@@ -2,7 +2,10 @@ package main
 
 import (
 	"embed"
+	"errors"
+	"fmt"
 	"log"
+	"os"
 
 	"github.com/wailsapp/wails/v2"
 	"github.com/wailsapp/wails/v2/pkg/logger"
@@ -18,11 +21,37 @@ var assets embed.FS
 var icon []byte
 
 func main() {
+	// Run subcommands such as daemon or play without a window
+	if handled, err := runCLI(os.Args[1:]); handled {
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+		return
+	}
+
+	// Hand the launch over to the instance that is already running
+	control, err := listenControl()
+	if errors.Is(err, errAlreadyRunning) {
+		requests := append(launchRequests(os.Args[1:]), ControlRequest{Command: "show"})
+		for _, request := range requests {
+			if err := sendControlRequest(request, nil); err != nil {
+				fmt.Fprintln(os.Stderr, err)
+				os.Exit(1)
+			}
+		}
+		return
+	} else if err != nil {
+		log.Println(err)
+	}
+
 	// Create an instance of the app structure
 	app := NewApp()
+	app.control = control
+	app.launchRequests = launchRequests(os.Args[1:])
 
 	// Create application with options
-	err := wails.Run(&options.App{
+	err = wails.Run(&options.App{
 		Title:             "various-yam",
 		Width:             1024,
 		Height:            768,
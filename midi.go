@@ -0,0 +1,421 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"io"
+	"log"
+	"sync"
+)
+
+// MIDI channel voice message types
+const (
+	MIDINoteOff       = 0x80
+	MIDINoteOn        = 0x90
+	MIDIControlChange = 0xB0
+)
+
+// MIDI binding types
+const (
+	MIDIBindingNote = "note"
+	MIDIBindingCC   = "cc"
+)
+
+// MIDIMessage is a decoded MIDI channel voice message
+type MIDIMessage struct {
+	Type    byte
+	Channel byte
+	Data1   byte
+	Data2   byte
+}
+
+// MIDIDeviceInfo describes a MIDI device
+type MIDIDeviceInfo struct {
+	DeviceID string `json:"deviceId"`
+	Label    string `json:"label"`
+}
+
+// MIDIBinding binds a note or control change to an action
+type MIDIBinding struct {
+	Type    string `json:"type"`
+	Channel int    `json:"channel"`
+	Number  int    `json:"number"`
+	Action  string `json:"action"`
+	Arg     string `json:"arg"`
+}
+
+// matches reports whether the binding is triggered by the same note or controller as other
+func (b MIDIBinding) matches(other MIDIBinding) bool {
+	return b.Type == other.Type && b.Channel == other.Channel && b.Number == other.Number
+}
+
+// midiParser decodes a raw MIDI byte stream into channel voice messages,
+// following running status and skipping system messages
+type midiParser struct {
+	status byte
+	data   []byte
+}
+
+// Feed feeds one byte to the parser, returning a message once one is complete
+func (p *midiParser) Feed(b byte) (MIDIMessage, bool) {
+	switch {
+	case b >= 0xF8:
+		// Real-time messages may appear anywhere and do not affect running status
+		return MIDIMessage{}, false
+	case b >= 0xF0:
+		// System common and exclusive messages cancel running status
+		p.status = 0
+		p.data = p.data[:0]
+		return MIDIMessage{}, false
+	case b >= 0x80:
+		p.status = b
+		p.data = p.data[:0]
+		return MIDIMessage{}, false
+	case p.status == 0:
+		return MIDIMessage{}, false
+	}
+
+	p.data = append(p.data, b)
+	if len(p.data) < midiDataLength(p.status) {
+		return MIDIMessage{}, false
+	}
+
+	msg := MIDIMessage{
+		Type:    p.status & 0xF0,
+		Channel: p.status & 0x0F,
+		Data1:   p.data[0],
+	}
+	if len(p.data) > 1 {
+		msg.Data2 = p.data[1]
+	}
+	p.data = p.data[:0]
+
+	return msg, true
+}
+
+func midiDataLength(status byte) int {
+	switch status & 0xF0 {
+	case 0xC0, 0xD0:
+		return 1
+	default:
+		return 2
+	}
+}
+
+// MIDIDispatcher runs actions for MIDI messages matching its bindings, and
+// turns the next message into a binding while MIDI learn is active
+type MIDIDispatcher struct {
+	mu          sync.Mutex
+	bindings    []MIDIBinding
+	run         func(action string, arg string, value float64) error
+	learning    bool
+	learnAction string
+	learnArg    string
+	onLearn     func(MIDIBinding)
+}
+
+// NewMIDIDispatcher creates a new MIDIDispatcher that runs actions with run
+func NewMIDIDispatcher(run func(action string, arg string, value float64) error) *MIDIDispatcher {
+	return &MIDIDispatcher{
+		run: run,
+	}
+}
+
+// SetBindings replaces all bindings
+func (d *MIDIDispatcher) SetBindings(bindings []MIDIBinding) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	d.bindings = bindings
+}
+
+// BeginLearn binds the next note-on or control change to the given action,
+// calling onLearn with the new binding. Bindings do not fire while learning.
+func (d *MIDIDispatcher) BeginLearn(action string, arg string, onLearn func(MIDIBinding)) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	d.learning = true
+	d.learnAction = action
+	d.learnArg = arg
+	d.onLearn = onLearn
+}
+
+// EndLearn stops MIDI learn
+func (d *MIDIDispatcher) EndLearn() {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	d.learning = false
+	d.onLearn = nil
+}
+
+// Listen parses raw MIDI bytes from r and dispatches them until r is
+// exhausted or ctx is done. Closing r is left to the caller.
+func (d *MIDIDispatcher) Listen(ctx context.Context, r io.Reader) error {
+	reader := bufio.NewReader(r)
+
+	var parser midiParser
+	for {
+		b, err := reader.ReadByte()
+		if ctx.Err() != nil {
+			return nil
+		}
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+
+		if msg, ok := parser.Feed(b); ok {
+			d.handle(msg)
+		}
+	}
+}
+
+func (d *MIDIDispatcher) handle(msg MIDIMessage) {
+	var trigger MIDIBinding
+	var value float64
+
+	switch msg.Type {
+	case MIDINoteOn:
+		// Note-on with zero velocity is a note-off
+		if msg.Data2 == 0 {
+			return
+		}
+		trigger.Type = MIDIBindingNote
+	case MIDIControlChange:
+		trigger.Type = MIDIBindingCC
+	default:
+		return
+	}
+	trigger.Channel = int(msg.Channel)
+	trigger.Number = int(msg.Data1)
+	value = float64(msg.Data2) / 127
+
+	d.mu.Lock()
+	if d.learning {
+		trigger.Action = d.learnAction
+		trigger.Arg = d.learnArg
+		onLearn := d.onLearn
+
+		d.learning = false
+		d.onLearn = nil
+		d.mu.Unlock()
+
+		onLearn(trigger)
+		return
+	}
+
+	var matched []MIDIBinding
+	for _, binding := range d.bindings {
+		if binding.matches(trigger) {
+			matched = append(matched, binding)
+		}
+	}
+	d.mu.Unlock()
+
+	for _, binding := range matched {
+		if err := d.run(binding.Action, binding.Arg, value); err != nil {
+			log.Println(err)
+		}
+	}
+}
+
+// ListMIDIDevices lists all available MIDI devices
+func (a *App) ListMIDIDevices() ([]MIDIDeviceInfo, error) {
+	return listMIDIDevices()
+}
+
+// GetMIDIInputDeviceID gets the MIDI input device by ID
+func (a *App) GetMIDIInputDeviceID() (string, error) {
+	serializedMIDIInputDeviceID, _ := a.fs.GetItem("midiInputDeviceID")
+	if serializedMIDIInputDeviceID == "" {
+		return "", nil
+	}
+
+	var midiInputDeviceID string
+	if err := json.Unmarshal([]byte(serializedMIDIInputDeviceID), &midiInputDeviceID); err != nil {
+		return "", err
+	}
+
+	return midiInputDeviceID, nil
+}
+
+// SetMIDIInputDeviceID sets the MIDI input device by ID
+func (a *App) SetMIDIInputDeviceID(midiInputDeviceID string) error {
+	serializedMIDIInputDeviceID, err := json.Marshal(midiInputDeviceID)
+	if err != nil {
+		return err
+	}
+
+	if err := a.fs.SetItem("midiInputDeviceID", string(serializedMIDIInputDeviceID)); err != nil {
+		return err
+	}
+
+	a.restartMIDIInput()
+
+	return nil
+}
+
+// ListMIDIBindings lists all MIDI bindings
+func (a *App) ListMIDIBindings() ([]MIDIBinding, error) {
+	serializedMIDIBindings, _ := a.fs.GetItem("midiBindings")
+	if serializedMIDIBindings == "" {
+		return []MIDIBinding{}, nil
+	}
+
+	var midiBindings []MIDIBinding
+	if err := json.Unmarshal([]byte(serializedMIDIBindings), &midiBindings); err != nil {
+		return nil, err
+	}
+
+	return midiBindings, nil
+}
+
+// SetMIDIBinding sets a MIDI binding, replacing any binding for the same note or controller
+func (a *App) SetMIDIBinding(midiBinding MIDIBinding) error {
+	if err := a.updateMIDIBindings(func(midiBindings []MIDIBinding) []MIDIBinding {
+		midiBindings = removeMIDIBinding(midiBindings, midiBinding)
+		return append(midiBindings, midiBinding)
+	}); err != nil {
+		return err
+	}
+
+	return a.registerMIDIBindings()
+}
+
+// RemoveMIDIBinding removes the MIDI binding for the same note or controller
+func (a *App) RemoveMIDIBinding(midiBinding MIDIBinding) error {
+	if err := a.updateMIDIBindings(func(midiBindings []MIDIBinding) []MIDIBinding {
+		return removeMIDIBinding(midiBindings, midiBinding)
+	}); err != nil {
+		return err
+	}
+
+	return a.registerMIDIBindings()
+}
+
+// BeginMIDILearn binds the next note or controller received to an action,
+// emitting the new binding through the "midiLearn" event
+func (a *App) BeginMIDILearn(action string, arg string) error {
+	a.midi.BeginLearn(action, arg, func(midiBinding MIDIBinding) {
+		if err := a.SetMIDIBinding(midiBinding); err != nil {
+			log.Println(err)
+			return
+		}
+
+		a.emit("midiLearn", midiBinding)
+	})
+
+	return nil
+}
+
+// EndMIDILearn stops MIDI learn
+func (a *App) EndMIDILearn() error {
+	a.midi.EndLearn()
+
+	return nil
+}
+
+func (a *App) updateMIDIBindings(callback func([]MIDIBinding) []MIDIBinding) error {
+	return a.fs.UpdateItem("midiBindings", func(value string) (string, error) {
+		var midiBindings []MIDIBinding
+		if value != "" {
+			if err := json.Unmarshal([]byte(value), &midiBindings); err != nil {
+				return "", err
+			}
+		}
+
+		serializedMIDIBindings, err := json.Marshal(callback(midiBindings))
+		if err != nil {
+			return "", err
+		}
+
+		return string(serializedMIDIBindings), nil
+	})
+}
+
+func removeMIDIBinding(midiBindings []MIDIBinding, midiBinding MIDIBinding) []MIDIBinding {
+	var remaining []MIDIBinding
+	for _, b := range midiBindings {
+		if !b.matches(midiBinding) {
+			remaining = append(remaining, b)
+		}
+	}
+	return remaining
+}
+
+func (a *App) registerMIDIBindings() error {
+	midiBindings, err := a.ListMIDIBindings()
+	if err != nil {
+		return err
+	}
+
+	a.midi.SetBindings(midiBindings)
+	a.midiFeedback.SetBindings(midiBindings)
+	a.refreshMIDIFeedback()
+
+	return nil
+}
+
+// restartMIDIInput reopens the selected MIDI input device
+func (a *App) restartMIDIInput() {
+	a.midiInputMu.Lock()
+	defer a.midiInputMu.Unlock()
+
+	a.closeMIDIInput()
+
+	ctx, cancel := context.WithCancel(a.ctx)
+	a.cancelMIDIInput = cancel
+
+	go func() {
+		if err := a.listenMIDIInput(ctx); err != nil {
+			log.Println(err)
+		}
+	}()
+}
+
+// stopMIDIInput closes the MIDI input device
+func (a *App) stopMIDIInput() {
+	a.midiInputMu.Lock()
+	defer a.midiInputMu.Unlock()
+
+	a.closeMIDIInput()
+}
+
+// closeMIDIInput closes the MIDI input device, if any. The MIDI input lock
+// must be held.
+func (a *App) closeMIDIInput() {
+	if a.cancelMIDIInput == nil {
+		return
+	}
+
+	a.cancelMIDIInput()
+	a.cancelMIDIInput = nil
+}
+
+func (a *App) listenMIDIInput(ctx context.Context) error {
+	midiInputDeviceID, err := a.GetMIDIInputDeviceID()
+	if err != nil || midiInputDeviceID == "" {
+		return err
+	}
+
+	device, err := openMIDIDevice(midiInputDeviceID)
+	if err != nil {
+		return err
+	}
+
+	a.midiFeedback.SetOutput(device)
+	a.refreshMIDIFeedback()
+
+	go func() {
+		<-ctx.Done()
+		a.midiFeedback.RemoveOutput(device)
+		device.Close()
+	}()
+
+	return a.midi.Listen(ctx, device)
+}
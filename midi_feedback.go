@@ -0,0 +1,187 @@
+package main
+
+import (
+	"encoding/json"
+	"io"
+	"sync"
+)
+
+// MIDIFeedbackProfile maps audio file states to the note-on velocities a
+// controller uses to light its pads. A profile without a name disables feedback.
+type MIDIFeedbackProfile struct {
+	Name    string `json:"name"`
+	Channel int    `json:"channel"`
+	Idle    int    `json:"idle"`
+	Playing int    `json:"playing"`
+	Looping int    `json:"looping"`
+	Missing int    `json:"missing"`
+}
+
+// midiFeedbackProfiles are the built-in controller profiles. A channel of -1
+// answers on the channel each pad was bound on.
+var midiFeedbackProfiles = []MIDIFeedbackProfile{
+	{Name: "Generic", Channel: -1, Idle: 0, Playing: 127, Looping: 127, Missing: 0},
+	// Launchpad Mini and S encode red and green brightness in the velocity
+	{Name: "Launchpad Mini / S", Channel: -1, Idle: 29, Playing: 60, Looping: 63, Missing: 15},
+	// Launchpad Mk2, X and Mini Mk3 pick colors from a 128-entry palette
+	{Name: "Launchpad Mk2 / X / Mini Mk3", Channel: -1, Idle: 1, Playing: 21, Looping: 45, Missing: 5},
+}
+
+// velocity returns the velocity for an audio file state
+func (p MIDIFeedbackProfile) velocity(state string) byte {
+	var velocity int
+	switch state {
+	case AudioFileStatePlaying:
+		velocity = p.Playing
+	case AudioFileStateLooping:
+		velocity = p.Looping
+	case AudioFileStateMissing:
+		velocity = p.Missing
+	default:
+		velocity = p.Idle
+	}
+
+	return byte(velocity & 0x7F)
+}
+
+// MIDIFeedback lights the pads bound to audio files according to their state
+type MIDIFeedback struct {
+	mu       sync.Mutex
+	output   io.Writer
+	profile  MIDIFeedbackProfile
+	bindings []MIDIBinding
+}
+
+// NewMIDIFeedback creates a new MIDIFeedback
+func NewMIDIFeedback() *MIDIFeedback {
+	return &MIDIFeedback{}
+}
+
+// SetOutput sets where MIDI messages are written, or disables output when nil
+func (f *MIDIFeedback) SetOutput(output io.Writer) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.output = output
+}
+
+// RemoveOutput disables output if it is still written to output
+func (f *MIDIFeedback) RemoveOutput(output io.Writer) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if f.output == output {
+		f.output = nil
+	}
+}
+
+// SetProfile sets the controller profile
+func (f *MIDIFeedback) SetProfile(profile MIDIFeedbackProfile) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.profile = profile
+}
+
+// SetBindings sets the bindings whose pads are lit
+func (f *MIDIFeedback) SetBindings(bindings []MIDIBinding) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.bindings = bindings
+}
+
+// AudioFiles lists the audio files that have a pad bound to them
+func (f *MIDIFeedback) AudioFiles() []string {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	var audioFiles []string
+	for _, binding := range f.bindings {
+		if binding.Type == MIDIBindingNote && binding.Action == ActionPlayAudioFile {
+			audioFiles = append(audioFiles, binding.Arg)
+		}
+	}
+
+	return audioFiles
+}
+
+// Update lights the pads bound to an audio file for its new state. Output is
+// disabled after the first failed write.
+func (f *MIDIFeedback) Update(audioFile string, state string) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if f.output == nil || f.profile.Name == "" {
+		return nil
+	}
+
+	var msg []byte
+	for _, binding := range f.bindings {
+		if binding.Type != MIDIBindingNote || binding.Action != ActionPlayAudioFile || binding.Arg != audioFile {
+			continue
+		}
+
+		channel := binding.Channel
+		if f.profile.Channel >= 0 {
+			channel = f.profile.Channel
+		}
+
+		msg = append(msg, MIDINoteOn|byte(channel&0x0F), byte(binding.Number&0x7F), f.profile.velocity(state))
+	}
+
+	if len(msg) == 0 {
+		return nil
+	}
+
+	if _, err := f.output.Write(msg); err != nil {
+		f.output = nil
+		return err
+	}
+
+	return nil
+}
+
+// ListMIDIFeedbackProfiles lists the built-in controller profiles
+func (a *App) ListMIDIFeedbackProfiles() ([]MIDIFeedbackProfile, error) {
+	return midiFeedbackProfiles, nil
+}
+
+// GetMIDIFeedbackProfile gets the controller profile used to light pads
+func (a *App) GetMIDIFeedbackProfile() (MIDIFeedbackProfile, error) {
+	serializedMIDIFeedbackProfile, _ := a.fs.GetItem("midiFeedbackProfile")
+	if serializedMIDIFeedbackProfile == "" {
+		return MIDIFeedbackProfile{}, nil
+	}
+
+	var midiFeedbackProfile MIDIFeedbackProfile
+	if err := json.Unmarshal([]byte(serializedMIDIFeedbackProfile), &midiFeedbackProfile); err != nil {
+		return MIDIFeedbackProfile{}, err
+	}
+
+	return midiFeedbackProfile, nil
+}
+
+// SetMIDIFeedbackProfile sets the controller profile used to light pads
+func (a *App) SetMIDIFeedbackProfile(midiFeedbackProfile MIDIFeedbackProfile) error {
+	serializedMIDIFeedbackProfile, err := json.Marshal(midiFeedbackProfile)
+	if err != nil {
+		return err
+	}
+
+	if err := a.fs.SetItem("midiFeedbackProfile", string(serializedMIDIFeedbackProfile)); err != nil {
+		return err
+	}
+
+	a.midiFeedback.SetProfile(midiFeedbackProfile)
+	a.refreshMIDIFeedback()
+
+	return nil
+}
+
+// refreshMIDIFeedback relights every bound pad
+func (a *App) refreshMIDIFeedback() {
+	for _, audioFile := range a.midiFeedback.AudioFiles() {
+		a.voices.Notify(audioFile)
+	}
+}
@@ -0,0 +1,71 @@
+//go:build linux
+
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// listMIDIDevices lists the ALSA raw MIDI devices, including virtual ports
+// created by snd-virmidi. Ports that only exist on the ALSA sequencer, such as
+// those of software like VMPK, are not listed, as the sequencer is not
+// supported. They can be connected to a snd-virmidi port with aconnect.
+func listMIDIDevices() ([]MIDIDeviceInfo, error) {
+	paths, err := filepath.Glob("/dev/snd/midiC*D*")
+	if err != nil {
+		return nil, err
+	}
+	sort.Strings(paths)
+
+	var devices []MIDIDeviceInfo
+	for _, path := range paths {
+		devices = append(devices, MIDIDeviceInfo{
+			DeviceID: path,
+			Label:    midiDeviceLabel(path),
+		})
+	}
+
+	return devices, nil
+}
+
+// midiDeviceLabel reads the device name ALSA reports for a raw MIDI device
+func midiDeviceLabel(path string) string {
+	var card, device int
+	if _, err := fmt.Sscanf(filepath.Base(path), "midiC%dD%d", &card, &device); err != nil {
+		return path
+	}
+
+	file, err := os.Open(fmt.Sprintf("/proc/asound/card%d/midi%d", card, device))
+	if err != nil {
+		return path
+	}
+	defer file.Close()
+
+	name, err := bufio.NewReader(file).ReadString('\n')
+	if err != nil && name == "" {
+		return path
+	}
+
+	return strings.TrimSpace(name)
+}
+
+// openMIDIDevice opens a raw MIDI device for reading, and for writing when
+// the device has an output port
+func openMIDIDevice(deviceID string) (io.ReadWriteCloser, error) {
+	if !strings.HasPrefix(deviceID, "/dev/snd/midi") {
+		return nil, fmt.Errorf("not a raw MIDI device: %s", deviceID)
+	}
+
+	file, err := os.OpenFile(deviceID, os.O_RDWR, 0)
+	if err != nil {
+		return os.Open(deviceID)
+	}
+
+	return file, nil
+}
@@ -0,0 +1,18 @@
+//go:build !linux
+
+package main
+
+import (
+	"errors"
+	"io"
+)
+
+// listMIDIDevices lists no devices, as raw MIDI devices are only supported on Linux
+func listMIDIDevices() ([]MIDIDeviceInfo, error) {
+	return []MIDIDeviceInfo{}, nil
+}
+
+// openMIDIDevice fails, as raw MIDI devices are only supported on Linux
+func openMIDIDevice(deviceID string) (io.ReadWriteCloser, error) {
+	return nil, errors.New("MIDI devices are only supported on Linux")
+}
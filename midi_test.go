@@ -0,0 +1,209 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"reflect"
+	"sync"
+	"testing"
+)
+
+func TestMIDIParser(t *testing.T) {
+	tests := []struct {
+		name   string
+		stream []byte
+		want   []MIDIMessage
+	}{
+		{
+			name:   "note on",
+			stream: []byte{0x90, 36, 127},
+			want:   []MIDIMessage{{Type: MIDINoteOn, Channel: 0, Data1: 36, Data2: 127}},
+		},
+		{
+			name:   "running status",
+			stream: []byte{0x91, 36, 127, 38, 64, 40, 0},
+			want: []MIDIMessage{
+				{Type: MIDINoteOn, Channel: 1, Data1: 36, Data2: 127},
+				{Type: MIDINoteOn, Channel: 1, Data1: 38, Data2: 64},
+				{Type: MIDINoteOn, Channel: 1, Data1: 40, Data2: 0},
+			},
+		},
+		{
+			name:   "realtime bytes inside a message",
+			stream: []byte{0xB2, 0xF8, 7, 0xFA, 64, 0xFE, 7, 0xF8, 65},
+			want: []MIDIMessage{
+				{Type: MIDIControlChange, Channel: 2, Data1: 7, Data2: 64},
+				{Type: MIDIControlChange, Channel: 2, Data1: 7, Data2: 65},
+			},
+		},
+		{
+			name:   "system exclusive cancels running status",
+			stream: []byte{0x90, 36, 127, 0xF0, 1, 2, 0xF7, 36, 0, 0x80, 36, 0},
+			want: []MIDIMessage{
+				{Type: MIDINoteOn, Channel: 0, Data1: 36, Data2: 127},
+				{Type: MIDINoteOff, Channel: 0, Data1: 36, Data2: 0},
+			},
+		},
+		{
+			name:   "program change has one data byte",
+			stream: []byte{0xC3, 5, 6},
+			want: []MIDIMessage{
+				{Type: 0xC0, Channel: 3, Data1: 5},
+				{Type: 0xC0, Channel: 3, Data1: 6},
+			},
+		},
+		{
+			name:   "data without a status",
+			stream: []byte{36, 127},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var parser midiParser
+			var got []MIDIMessage
+			for _, b := range tt.stream {
+				if msg, ok := parser.Feed(b); ok {
+					got = append(got, msg)
+				}
+			}
+
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("got %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+type midiRun struct {
+	action string
+	arg    string
+	value  float64
+}
+
+func TestMIDIDispatcher(t *testing.T) {
+	bindings := []MIDIBinding{
+		{Type: MIDIBindingNote, Channel: 0, Number: 36, Action: "play", Arg: "a.wav"},
+		{Type: MIDIBindingCC, Channel: 1, Number: 7, Action: "setVolume"},
+	}
+
+	tests := []struct {
+		name   string
+		stream []byte
+		want   []midiRun
+	}{
+		{
+			name:   "note on runs the bound action",
+			stream: []byte{0x90, 36, 127},
+			want:   []midiRun{{"play", "a.wav", 1}},
+		},
+		{
+			name:   "note on with zero velocity is a note off",
+			stream: []byte{0x90, 36, 0},
+		},
+		{
+			name:   "note off",
+			stream: []byte{0x80, 36, 64},
+		},
+		{
+			name:   "control change passes its value",
+			stream: []byte{0xB1, 7, 0},
+			want:   []midiRun{{"setVolume", "", 0}},
+		},
+		{
+			name:   "running status with realtime bytes",
+			stream: []byte{0x90, 36, 127, 0xF8, 36, 0, 0xF8, 36, 127},
+			want:   []midiRun{{"play", "a.wav", 1}, {"play", "a.wav", 1}},
+		},
+		{
+			name:   "unbound channel",
+			stream: []byte{0x92, 36, 127},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got []midiRun
+			d := NewMIDIDispatcher(func(action string, arg string, value float64) error {
+				got = append(got, midiRun{action, arg, value})
+				return nil
+			})
+			d.SetBindings(bindings)
+
+			if err := d.Listen(context.Background(), bytes.NewReader(tt.stream)); err != nil {
+				t.Fatal(err)
+			}
+
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("got %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestMIDIDispatcherLearn(t *testing.T) {
+	var runs []midiRun
+	d := NewMIDIDispatcher(func(action string, arg string, value float64) error {
+		runs = append(runs, midiRun{action, arg, value})
+		return nil
+	})
+	d.SetBindings([]MIDIBinding{{Type: MIDIBindingNote, Channel: 0, Number: 36, Action: "play", Arg: "a.wav"}})
+
+	var learned []MIDIBinding
+	d.BeginLearn("play", "b.wav", func(midiBinding MIDIBinding) {
+		learned = append(learned, midiBinding)
+	})
+
+	// The note-off is ignored, the bound note is learnt instead of running
+	// and the controller after it runs nothing
+	stream := []byte{0x80, 40, 0, 0x90, 36, 100, 0xB4, 20, 127}
+	if err := d.Listen(context.Background(), bytes.NewReader(stream)); err != nil {
+		t.Fatal(err)
+	}
+
+	want := []MIDIBinding{{Type: MIDIBindingNote, Channel: 0, Number: 36, Action: "play", Arg: "b.wav"}}
+	if !reflect.DeepEqual(learned, want) {
+		t.Errorf("learned %v, want %v", learned, want)
+	}
+	if len(runs) != 0 {
+		t.Errorf("ran %v while learning", runs)
+	}
+
+	// Learning ends after one message
+	if err := d.Listen(context.Background(), bytes.NewReader([]byte{0x90, 36, 127})); err != nil {
+		t.Fatal(err)
+	}
+	if len(learned) != 1 || len(runs) != 1 {
+		t.Errorf("learned %v and ran %v after learning", learned, runs)
+	}
+
+	d.BeginLearn("stopAll", "", func(midiBinding MIDIBinding) {
+		learned = append(learned, midiBinding)
+	})
+	d.EndLearn()
+	if err := d.Listen(context.Background(), bytes.NewReader([]byte{0xB0, 1, 1})); err != nil {
+		t.Fatal(err)
+	}
+	if len(learned) != 1 {
+		t.Errorf("learned %v after ending learn", learned)
+	}
+}
+
+func TestRestartMIDIInputConcurrently(t *testing.T) {
+	a := newTestApp(t)
+	a.ctx = context.Background()
+	defer a.stopMIDIInput()
+
+	// The race detector catches the input being swapped without the lock
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if err := a.SetMIDIInputDeviceID(""); err != nil {
+				t.Error(err)
+			}
+		}()
+	}
+	wg.Wait()
+}
@@ -0,0 +1,501 @@
+package main
+
+import (
+	"io"
+	"log"
+	"slices"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/gen2brain/malgo"
+)
+
+// mixerClipBlockFrames is how many frames a clip decodes at a time
+const mixerClipBlockFrames = 1024
+
+// Clips decode ahead of playback on their own goroutine, keeping up to
+// mixerClipQueueFrames queued and checking for room every
+// mixerClipDecodeInterval, so that playback never waits on the disk
+const (
+	mixerClipQueueFrames    = 16 * mixerClipBlockFrames
+	mixerClipDecodeInterval = 10 * time.Millisecond
+)
+
+// MixerClip plays an audio stream through the mixer. It decodes the stream
+// into a queue on its own goroutine, and converts the queued audio to the
+// sample rate and channels it is read at as it goes. Only one output may read
+// it at a time.
+type MixerClip struct {
+	audioFile      string
+	stream         *audioStream
+	loop           bool
+	buses          []string
+	streamRate     uint32
+	streamChannels int
+	queue          *RingBuffer
+	eof            atomic.Bool
+	stop           chan struct{}
+	decoderDone    chan struct{}
+	resampler      *SincResampler
+	sampleRate     uint32
+	channels       int
+	input          []float32
+	ended          bool
+	done           chan struct{}
+}
+
+// NewMixerClip creates a new MixerClip reading stream, reopening the audio
+// file each time it ends when looping. It plays on the named buses, or on
+// every bus if there are none.
+func NewMixerClip(audioFile string, stream *audioStream, loop bool, buses []string) *MixerClip {
+	c := &MixerClip{
+		audioFile:      audioFile,
+		stream:         stream,
+		loop:           loop,
+		buses:          buses,
+		streamRate:     stream.sampleRate,
+		streamChannels: int(stream.channels),
+		queue:          NewRingBuffer(mixerClipQueueFrames * int(stream.channels)),
+		stop:           make(chan struct{}),
+		decoderDone:    make(chan struct{}),
+		done:           make(chan struct{}),
+	}
+
+	go c.decode()
+
+	return c
+}
+
+// Routed reports whether the clip plays on the named bus
+func (c *MixerClip) Routed(bus string) bool {
+	return len(c.buses) == 0 || slices.Contains(c.buses, bus)
+}
+
+// Done is closed once the clip has played to the end
+func (c *MixerClip) Done() <-chan struct{} {
+	return c.done
+}
+
+// Close stops decoding and closes the clip's stream. Reading the clip
+// afterwards only plays what was decoded already.
+func (c *MixerClip) Close() error {
+	close(c.stop)
+	<-c.decoderDone
+
+	return c.stream.Close()
+}
+
+// decode keeps the queue topped up with decoded samples until the stream ends
+// or the clip is closed
+func (c *MixerClip) decode() {
+	defer close(c.decoderDone)
+	defer c.eof.Store(true)
+
+	sampleSize := malgo.SampleSizeInBytes(c.stream.format)
+	frameSize := sampleSize * c.streamChannels
+	raw := make([]byte, mixerClipBlockFrames*frameSize)
+	samples := make([]float32, mixerClipBlockFrames*c.streamChannels)
+
+	ticker := time.NewTicker(mixerClipDecodeInterval)
+	defer ticker.Stop()
+
+	for {
+		for c.queue.Cap()-c.queue.Len() >= len(samples) {
+			n := c.readBlock(raw, frameSize)
+			if n == 0 {
+				return
+			}
+
+			decodeSamples(samples[:n/sampleSize], raw[:n], c.stream.format)
+			c.queue.Write(samples[:n/sampleSize])
+		}
+
+		select {
+		case <-c.stop:
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// readBlock reads the next block of whole frames of the stream into raw,
+// starting over from the top of the audio file when looping. It returns the
+// number of bytes read, which is 0 at the end.
+func (c *MixerClip) readBlock(raw []byte, frameSize int) int {
+	n, err := io.ReadFull(c.stream, raw)
+	for n < frameSize && err != nil && c.loop {
+		next, openErr := openAudioStream(c.audioFile)
+		if openErr != nil {
+			log.Println(openErr)
+			break
+		}
+		c.stream.Close()
+		c.stream = next
+
+		n, err = io.ReadFull(c.stream, raw)
+		if n == 0 {
+			break
+		}
+	}
+
+	return n - n%frameSize
+}
+
+// Read fills out with the next frames of the clip at the given format, and
+// with silence once it has ended. It only takes what was decoded already, and
+// plays silence in its place if decoding falls behind.
+func (c *MixerClip) Read(out []float32, sampleRate uint32, channels int) {
+	if c.resampler == nil || c.sampleRate != sampleRate || c.channels != channels {
+		c.resampler = NewSincResampler(c.streamRate, sampleRate, channels)
+		c.sampleRate, c.channels = sampleRate, channels
+	}
+
+	if c.ended {
+		clear(out)
+		return
+	}
+
+	if c.resampler.Read(out, c.fill) {
+		return
+	}
+
+	c.ended = true
+	close(c.done)
+}
+
+// fill takes decoded frames from the queue into input at the channels it is
+// read at, mixing the stream's channels down to mono or spreading mono out as
+// needed. It reports false once the stream has ended and the queue is empty.
+func (c *MixerClip) fill(input []float32) (int, bool) {
+	streamChannels := c.streamChannels
+
+	// The end has to be seen before the last samples are taken, or samples
+	// queued in between would be lost
+	eof := c.eof.Load()
+
+	frames := min(len(input)/c.channels, c.queue.Len()/streamChannels)
+	if cap(c.input) < frames*streamChannels {
+		c.input = make([]float32, frames*streamChannels)
+	}
+	decoded := c.input[:frames*streamChannels]
+	c.queue.Read(decoded)
+
+	for i := 0; i < frames; i++ {
+		frame := input[i*c.channels : (i+1)*c.channels]
+		samples := decoded[i*streamChannels : (i+1)*streamChannels]
+
+		switch {
+		case c.channels == streamChannels:
+			copy(frame, samples)
+		case c.channels == 1:
+			var sum float32
+			for _, s := range samples {
+				sum += s
+			}
+			frame[0] = sum / float32(streamChannels)
+		default:
+			for ch := range frame {
+				frame[ch] = samples[min(ch, streamChannels-1)]
+			}
+		}
+	}
+
+	return frames, !eof || c.queue.Len() > 0
+}
+
+// Mixer combines the mic with the clips being played and ducks one under the
+// other. The loopback renders it through a MixerOutput while it runs, on the
+// main bus along with any other output buses. The output reads the clips and
+// taps without locking, as the lists are replaced rather than changed.
+type Mixer struct {
+	mu       sync.Mutex
+	clips    atomic.Pointer[[]*MixerClip]
+	attached bool
+	detached chan struct{}
+	taps     atomic.Pointer[[]*MixerTap]
+	ducking  atomic.Pointer[DuckingSettings]
+	buses    atomic.Pointer[[]OutputBus]
+}
+
+// micTap names the mic, after its effects and before any ducking, for taps
+const micTap = "mic"
+
+// MixerTap receives a copy of what the mixer renders on a bus, or of the mic,
+// for as long as the loopback plays it. Outputs rendering another format than
+// the tap's skip it.
+type MixerTap struct {
+	bus        string
+	sampleRate uint32
+	channels   int
+	queue      *RingBuffer
+}
+
+// NewMixerTap creates a new MixerTap of the named bus, or of the mic, at the
+// given format, queueing up to capacity samples for the reader
+func NewMixerTap(bus string, sampleRate uint32, channels int, capacity int) *MixerTap {
+	return &MixerTap{
+		bus:        bus,
+		sampleRate: sampleRate,
+		channels:   channels,
+		queue:      NewRingBuffer(capacity),
+	}
+}
+
+// Read dequeues up to len(samples) of the tapped samples, returning how many
+// there were. Only one reader may call it.
+func (t *MixerTap) Read(samples []float32) int {
+	n := min(len(samples), t.queue.Len())
+	return t.queue.Read(samples[:n])
+}
+
+// Len returns the number of tapped samples waiting to be read
+func (t *MixerTap) Len() int {
+	return t.queue.Len()
+}
+
+// NewMixer creates a new Mixer without clips
+func NewMixer() *Mixer {
+	m := &Mixer{}
+	m.clips.Store(&[]*MixerClip{})
+	m.taps.Store(&[]*MixerTap{})
+	return m
+}
+
+// Add starts mixing a clip into the output, returning a channel that is
+// closed when the output detaches, or false if there is no output to mix it
+// into
+func (m *Mixer) Add(clip *MixerClip) (<-chan struct{}, bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if !m.attached {
+		return nil, false
+	}
+	clips := append(slices.Clone(*m.clips.Load()), clip)
+	m.clips.Store(&clips)
+
+	return m.detached, true
+}
+
+// Remove stops mixing a clip. A callback that started before it returns may
+// still read the clip, but the output leaves it alone after that.
+func (m *Mixer) Remove(clip *MixerClip) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	clips := slices.DeleteFunc(slices.Clone(*m.clips.Load()), func(c *MixerClip) bool {
+		return c == clip
+	})
+	m.clips.Store(&clips)
+}
+
+// AddTap starts copying a bus into a tap
+func (m *Mixer) AddTap(tap *MixerTap) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	taps := append(slices.Clone(*m.taps.Load()), tap)
+	m.taps.Store(&taps)
+}
+
+// RemoveTap stops copying into a tap. A callback that started before it
+// returns may still write to the tap, but the output leaves it alone after
+// that.
+func (m *Mixer) RemoveTap(tap *MixerTap) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	taps := slices.DeleteFunc(slices.Clone(*m.taps.Load()), func(t *MixerTap) bool {
+		return t == tap
+	})
+	m.taps.Store(&taps)
+}
+
+// SetDucking sets the ducking rules, which the output picks up on its next
+// callback
+func (m *Mixer) SetDucking(settings DuckingSettings) {
+	m.ducking.Store(&settings)
+}
+
+// SetBuses sets the volume and mic of the output buses, which the output picks
+// up on its next callback
+func (m *Mixer) SetBuses(buses []OutputBus) {
+	m.buses.Store(&buses)
+}
+
+// Attach creates the output rendering the mixer at the given format
+func (m *Mixer) Attach(sampleRate uint32, channels int) *MixerOutput {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.attached = true
+	m.detached = make(chan struct{})
+
+	return &MixerOutput{
+		mixer:      m,
+		sampleRate: sampleRate,
+		channels:   channels,
+	}
+}
+
+// mixerBus is an output bus rendered into a queue for its playback device
+type mixerBus struct {
+	name       string
+	queue      *RingBuffer
+	clips      []float32
+	clipDucker *Ducker
+	settings   OutputBus
+	found      bool
+}
+
+// MixerOutput renders the mixer for a single playback device, the main bus,
+// and for the devices of the other buses through their queues. Only the main
+// bus device's callback may call Mix.
+type MixerOutput struct {
+	mixer       *Mixer
+	sampleRate  uint32
+	channels    int
+	clip        []float32
+	clips       []float32
+	mic         []float32
+	buses       []*mixerBus
+	ducking     *DuckingSettings
+	busSettings *[]OutputBus
+	micDucker   *Ducker
+	clipDucker  *Ducker
+}
+
+// AddBus queues the clips routed to the named bus, with the mic if the bus
+// includes it, for another playback device to play. It must be called before
+// the first call to Mix.
+func (o *MixerOutput) AddBus(name string, queue *RingBuffer) {
+	o.buses = append(o.buses, &mixerBus{name: name, queue: queue})
+}
+
+// Mix mixes the clips routed to the main bus, turned up or down by clipGain,
+// into the mic samples, ducking either one under the other. Clips are ducked
+// under the mic on every bus, but the mic is only ducked under the clips on
+// the main bus, and goes out ducked that way on the buses that include it.
+func (o *MixerOutput) Mix(samples []float32, clipGain float32) {
+	if cap(o.clips) < len(samples) {
+		o.clip = make([]float32, len(samples))
+		o.clips = make([]float32, len(samples))
+		o.mic = make([]float32, len(samples))
+		for _, bus := range o.buses {
+			bus.clips = make([]float32, len(samples))
+		}
+	}
+	clip, clips, mic := o.clip[:len(samples)], o.clips[:len(samples)], o.mic[:len(samples)]
+
+	clear(clips)
+	for _, bus := range o.buses {
+		bus.clips = bus.clips[:len(samples)]
+		clear(bus.clips)
+	}
+
+	for _, c := range *o.mixer.clips.Load() {
+		c.Read(clip, o.sampleRate, o.channels)
+		if c.Routed(mainBus) {
+			for i, s := range clip {
+				clips[i] += s
+			}
+		}
+		for _, bus := range o.buses {
+			if c.Routed(bus.name) {
+				for i, s := range clip {
+					bus.clips[i] += s
+				}
+			}
+		}
+	}
+
+	// Duckers are rebuilt, losing their state, when the rules change
+	if settings := o.mixer.ducking.Load(); settings != o.ducking {
+		o.ducking = settings
+		o.micDucker, o.clipDucker = nil, nil
+		for _, bus := range o.buses {
+			bus.clipDucker = nil
+		}
+		if settings != nil && settings.Mic.Enabled {
+			o.micDucker = newDucker(settings.Mic, o.sampleRate, o.channels)
+		}
+		if settings != nil && settings.Clips.Enabled {
+			o.clipDucker = newDucker(settings.Clips, o.sampleRate, o.channels)
+			for _, bus := range o.buses {
+				bus.clipDucker = newDucker(settings.Clips, o.sampleRate, o.channels)
+			}
+		}
+	}
+
+	if busSettings := o.mixer.buses.Load(); busSettings != o.busSettings {
+		o.busSettings = busSettings
+		for _, bus := range o.buses {
+			bus.found = false
+			if busSettings == nil {
+				continue
+			}
+			for _, settings := range *busSettings {
+				if settings.Name == bus.name {
+					bus.settings, bus.found = settings, true
+				}
+			}
+		}
+	}
+
+	// Each side is keyed by the other one before either is ducked
+	copy(mic, samples)
+	o.tap(micTap, mic)
+	if o.micDucker != nil {
+		o.micDucker.Process(samples, clips)
+	}
+	if o.clipDucker != nil {
+		o.clipDucker.Process(clips, mic)
+	}
+
+	// A bus removed since the loopback started plays nothing until it restarts
+	for _, bus := range o.buses {
+		if !bus.found {
+			continue
+		}
+
+		if bus.clipDucker != nil {
+			bus.clipDucker.Process(bus.clips, mic)
+		}
+
+		volume := float32(bus.settings.Volume)
+		for i, s := range bus.clips {
+			if bus.settings.IncludeMic {
+				s += samples[i]
+			}
+			bus.clips[i] = s * volume
+		}
+		bus.queue.Write(bus.clips)
+		o.tap(bus.name, bus.clips)
+	}
+
+	for i, s := range clips {
+		samples[i] += s * clipGain
+	}
+	o.tap(mainBus, samples)
+}
+
+// tap copies the samples rendered on the named bus into its taps
+func (o *MixerOutput) tap(bus string, samples []float32) {
+	for _, tap := range *o.mixer.taps.Load() {
+		if tap.bus == bus && tap.sampleRate == o.sampleRate && tap.channels == o.channels {
+			tap.queue.Write(samples)
+		}
+	}
+}
+
+// Detach stops rendering the mixer, closing the channel Add returned for its
+// clips so that their players end them rather than leave them waiting for an
+// output that is gone
+func (o *MixerOutput) Detach() {
+	o.mixer.mu.Lock()
+	defer o.mixer.mu.Unlock()
+
+	o.mixer.attached = false
+	close(o.mixer.detached)
+}
@@ -0,0 +1,217 @@
+package main
+
+import (
+	"context"
+	"math"
+	"testing"
+	"time"
+)
+
+// readMixerClip reads frames from a clip in callback sized blocks, waiting for
+// its decoder before each one as a real device callback would not
+func readMixerClip(t *testing.T, clip *MixerClip, frames int, sampleRate uint32, channels int) []float32 {
+	t.Helper()
+
+	const blockFrames = 480
+
+	out := make([]float32, frames*channels)
+	for i := 0; i < frames; i += blockFrames {
+		waitFor(t, "the clip to decode", func() bool {
+			return clip.eof.Load() || clip.queue.Len() >= mixerClipBlockFrames*clip.streamChannels
+		})
+		clip.Read(out[i*channels:min(i+blockFrames, frames)*channels], sampleRate, channels)
+	}
+
+	return out
+}
+
+func TestMixerClip(t *testing.T) {
+	const seconds = 0.5
+
+	tests := []struct {
+		name           string
+		fileRate       uint32
+		fileChannels   int
+		loop           bool
+		sampleRate     uint32
+		channels       int
+		wantDoneFrames int
+	}{
+		{"same format", 48000, 2, false, 48000, 2, 24000},
+		{"upsampling", 44100, 2, false, 48000, 2, 24000},
+		{"downsampling", 48000, 1, false, 44100, 1, 22050},
+		{"mono to stereo", 44100, 1, false, 48000, 2, 24000},
+		{"stereo to mono", 48000, 2, false, 48000, 1, 24000},
+		{"looping", 44100, 2, true, 48000, 2, 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			filePath := writeTestWAV(t, tt.fileRate, tt.fileChannels, seconds)
+			stream, err := openAudioStream(filePath)
+			if err != nil {
+				t.Fatal(err)
+			}
+			clip := NewMixerClip(filePath, stream, tt.loop, nil)
+			defer clip.Close()
+
+			// Read on for three times the length of the file
+			frames := int(3 * seconds * float64(tt.sampleRate))
+			out := readMixerClip(t, clip, frames, tt.sampleRate, tt.channels)
+
+			playing := out[tt.channels*1000 : tt.channels*int(0.9*seconds*float64(tt.sampleRate))]
+			if p := framePeak(playing); math.Abs(p-0.5) > 0.01 {
+				t.Errorf("peak %v while playing, want 0.5", p)
+			}
+			for i := 0; i < len(playing); i += tt.channels {
+				for c := 1; c < tt.channels; c++ {
+					if playing[i+c] != playing[i] {
+						t.Fatalf("channel %d is %v at frame %d, want %v", c, playing[i+c], i/tt.channels, playing[i])
+					}
+				}
+			}
+
+			select {
+			case <-clip.Done():
+				if tt.loop {
+					t.Fatal("a looping clip ended")
+				}
+			default:
+				if !tt.loop {
+					t.Fatal("the clip did not end")
+				}
+			}
+
+			ended := out[tt.channels*(tt.wantDoneFrames+64):]
+			if tt.loop {
+				if p := framePeak(ended[len(ended)-tt.channels*1000:]); math.Abs(p-0.5) > 0.01 {
+					t.Errorf("peak %v after looping, want 0.5", p)
+				}
+			} else if p := framePeak(ended); p != 0 {
+				t.Errorf("peak %v after the end, want silence", p)
+			}
+		})
+	}
+}
+
+// TestMixerMix checks that mixing never waits on the mixer's lock, which is
+// held while clips come and go
+func TestMixerMix(t *testing.T) {
+	filePath := writeTestWAV(t, 44100, 2, 1)
+	stream, err := openAudioStream(filePath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	clip := NewMixerClip(filePath, stream, false, nil)
+	defer clip.Close()
+
+	mixer := NewMixer()
+	output := mixer.Attach(48000, 2)
+	if _, ok := mixer.Add(clip); !ok {
+		t.Fatal("the clip was not added")
+	}
+	waitFor(t, "the clip to decode", func() bool {
+		return clip.queue.Len() > 0
+	})
+
+	mixer.mu.Lock()
+	defer mixer.mu.Unlock()
+
+	mixed := make(chan []float32)
+	go func() {
+		samples := make([]float32, 2*480)
+		for i := 0; i < 10; i++ {
+			output.Mix(samples, 1)
+		}
+		mixed <- samples
+	}()
+
+	select {
+	case samples := <-mixed:
+		if framePeak(samples) == 0 {
+			t.Error("the clip was not mixed")
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("mixing waited on the mixer lock")
+	}
+}
+
+func TestMixerTap(t *testing.T) {
+	tests := []struct {
+		name       string
+		bus        string
+		sampleRate uint32
+		channels   int
+		want       float32
+	}{
+		{"main bus", mainBus, 48000, 1, 0.25},
+		{"mic", micTap, 48000, 1, 0.25},
+		{"other bus", "phones", 48000, 1, 0.125},
+		{"missing bus", "stream", 48000, 1, 0},
+		{"other sample rate", mainBus, 44100, 1, 0},
+		{"other channels", mainBus, 48000, 2, 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mixer := NewMixer()
+			mixer.SetBuses([]OutputBus{{Name: "phones", Volume: 0.5, IncludeMic: true}})
+			output := mixer.Attach(48000, 1)
+			output.AddBus("phones", NewRingBuffer(48000))
+
+			tap := NewMixerTap(tt.bus, tt.sampleRate, tt.channels, 48000)
+			mixer.AddTap(tap)
+
+			mic := make([]float32, 480)
+			for i := range mic {
+				mic[i] = 0.25
+			}
+			output.Mix(mic, 1)
+
+			tapped := make([]float32, 1000)
+			n := tap.Read(tapped)
+			if tt.want == 0 {
+				if n != 0 {
+					t.Errorf("tapped %d samples, want none", n)
+				}
+				return
+			}
+			if n != len(mic) || tapped[0] != tt.want || tapped[n-1] != tt.want {
+				t.Errorf("tapped %d samples at %v, want %d at %v", n, tapped[0], len(mic), tt.want)
+			}
+
+			// A removed tap is left alone
+			mixer.RemoveTap(tap)
+			output.Mix(mic, 1)
+			if tap.Len() != 0 {
+				t.Errorf("a removed tap got %d samples", tap.Len())
+			}
+		})
+	}
+}
+
+func TestMixerDetachEndsClips(t *testing.T) {
+	a := newTestApp(t)
+	a.ctx = context.Background()
+	filePath := writeTestWAV(t, 48000, 2, 5)
+
+	output := a.mixer.Attach(48000, 2)
+	if err := a.PlayAudioFile(filePath); err != nil {
+		t.Fatal(err)
+	}
+	if state := a.voices.State(filePath); state != AudioFileStatePlaying {
+		t.Fatalf("clip is %s, want %s", state, AudioFileStatePlaying)
+	}
+	waitFor(t, "the clip to be mixed", func() bool {
+		return len(*a.mixer.clips.Load()) == 1
+	})
+
+	// The clip ends with the loopback rather than waiting for it to return
+	output.Detach()
+	waitFor(t, "the clip to end", func() bool {
+		return a.voices.State(filePath) == AudioFileStateIdle
+	})
+	if clips := *a.mixer.clips.Load(); len(clips) != 0 {
+		t.Errorf("mixer still has %d clips", len(clips))
+	}
+}
@@ -0,0 +1,68 @@
+package main
+
+import (
+	"encoding/json"
+	"math"
+)
+
+// MonitorSettings configures the monitor output, a second playback device
+// such as headphones to hear the clips on while the main playback device goes
+// to a virtual cable. The mic is left out unless IncludeMic is set. The mixer
+// renders it as the monitor bus.
+type MonitorSettings struct {
+	Enabled    bool    `json:"enabled"`
+	Volume     float64 `json:"volume"`
+	IncludeMic bool    `json:"includeMic"`
+}
+
+// defaultMonitorSettings has the monitor disabled, at full volume once enabled
+var defaultMonitorSettings = MonitorSettings{
+	Volume: 1,
+}
+
+// GetMonitorSettings gets the monitor output settings
+func (a *App) GetMonitorSettings() (MonitorSettings, error) {
+	serializedMonitorSettings, _ := a.fs.GetItem("monitorSettings")
+	if serializedMonitorSettings == "" {
+		return defaultMonitorSettings, nil
+	}
+
+	var monitorSettings MonitorSettings
+	if err := json.Unmarshal([]byte(serializedMonitorSettings), &monitorSettings); err != nil {
+		return MonitorSettings{}, err
+	}
+
+	return monitorSettings, nil
+}
+
+// SetMonitorSettings sets the monitor output settings. The volume and mic
+// apply right away, while enabling or disabling the monitor restarts the
+// loopback.
+func (a *App) SetMonitorSettings(monitorSettings MonitorSettings) error {
+	monitorSettings.Volume = math.Max(0, math.Min(1, monitorSettings.Volume))
+
+	serializedMonitorSettings, err := json.Marshal(monitorSettings)
+	if err != nil {
+		return err
+	}
+
+	if err := a.fs.SetItem("monitorSettings", string(serializedMonitorSettings)); err != nil {
+		return err
+	}
+
+	if err := a.applyOutputBuses(); err != nil {
+		return err
+	}
+
+	return a.restartLoopbackAudio()
+}
+
+// GetMonitorDeviceID gets the current ID of the selected monitor device
+func (a *App) GetMonitorDeviceID() (string, error) {
+	return a.getDeviceID("monitorDevice", "monitorDeviceID", "audiooutput")
+}
+
+// SetMonitorDeviceID selects the monitor device by ID, remembering it by name too
+func (a *App) SetMonitorDeviceID(monitorDeviceID string) error {
+	return a.setDeviceID("monitorDevice", "monitorDeviceID", "audiooutput", monitorDeviceID)
+}
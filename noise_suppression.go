@@ -0,0 +1,478 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"math"
+	"math/cmplx"
+	"slices"
+	"sync"
+	"time"
+)
+
+// Noise suppression frame size, analyzed at half overlap
+const noiseSuppressionFrameSize = 512
+
+// noiseFloorLearnDuration is how long LearnNoiseFloor listens to the room
+const noiseFloorLearnDuration = 2 * time.Second
+
+// Without a learned noise floor, the estimate of every bin follows the
+// quietest frames and creeps back up by this factor per frame. It adapts to
+// louder noise within seconds, yet slowly enough not to eat a held note.
+const noiseFloorRise = 1.0005
+
+// Frames partly peaking below noiseSilenceThresholdDB, such as the digital
+// silence the loopback starts on, leave the estimate of the noise floor alone.
+// It could never creep back up from nothing.
+const noiseSilenceThresholdDB = -90
+
+// The smoothed level of noise dips below its average, so its minimum is
+// scaled up by this factor to estimate the noise floor
+const noiseMinimumBias = 1.5
+
+// Spectral subtraction removes more than the noise floor, which keeps the
+// noise that remains from sounding like scattered tones
+const noiseOverSubtraction = 3
+
+// NoiseSuppressionSettings configures the noise suppression of a capture
+// device. The noise floor is the average magnitude spectrum of the room noise
+// at NoiseFloorSampleRate, set by LearnNoiseFloor.
+type NoiseSuppressionSettings struct {
+	Enabled              bool      `json:"enabled"`
+	ReductionDB          float64   `json:"reductionDb"`
+	NoiseFloor           []float64 `json:"noiseFloor"`
+	NoiseFloorSampleRate uint32    `json:"noiseFloorSampleRate"`
+}
+
+// defaultNoiseSuppressionSettings has noise suppression disabled
+var defaultNoiseSuppressionSettings = NoiseSuppressionSettings{
+	ReductionDB: 20,
+}
+
+// sqrtHannWindow returns the square root of a periodic Hann window, which
+// applied before and after the transform adds back up to 1 at half overlap
+func sqrtHannWindow(size int) []float64 {
+	window := make([]float64, size)
+	for k := range window {
+		window[k] = math.Sqrt(0.5 - 0.5*math.Cos(2*math.Pi*float64(k)/float64(size)))
+	}
+	return window
+}
+
+type noiseSuppressionChannel struct {
+	input    []float64
+	output   []float64
+	accum    []float64
+	level    []float64
+	smoothed []float64
+	noise    []float64
+	tracking bool
+	rover    int
+}
+
+// NoiseSuppressor removes steady background noise such as fans and hum by
+// spectral subtraction, delaying the signal by one frame
+type NoiseSuppressor struct {
+	window     []float64
+	bins       []complex128
+	magnitudes []float64
+	sorted     []float64
+	floor      float64
+	learned    bool
+	channels   []noiseSuppressionChannel
+}
+
+// NewNoiseSuppressor creates a new NoiseSuppressor turning noise down by up to
+// reductionDB. A nil noise floor is estimated as it goes.
+func NewNoiseSuppressor(channels int, reductionDB float64, noiseFloor []float64) *NoiseSuppressor {
+	size := noiseSuppressionFrameSize
+	n := &NoiseSuppressor{
+		window:     sqrtHannWindow(size),
+		bins:       make([]complex128, size),
+		magnitudes: make([]float64, size/2+1),
+		sorted:     make([]float64, size/2+1),
+		floor:      dbToLinear(-math.Abs(reductionDB)),
+		learned:    len(noiseFloor) == size/2+1,
+		channels:   make([]noiseSuppressionChannel, channels),
+	}
+
+	for c := range n.channels {
+		channel := noiseSuppressionChannel{
+			input:    make([]float64, size),
+			output:   make([]float64, size),
+			accum:    make([]float64, size),
+			level:    make([]float64, size/2+1),
+			smoothed: make([]float64, size/2+1),
+			noise:    make([]float64, size/2+1),
+			rover:    size / 2,
+		}
+
+		if n.learned {
+			copy(channel.noise, noiseFloor)
+		}
+
+		n.channels[c] = channel
+	}
+
+	return n
+}
+
+func (n *NoiseSuppressor) Process(samples []float32) {
+	hop := len(n.window) / 2
+	for i, s := range samples {
+		channel := &n.channels[i%len(n.channels)]
+
+		channel.input[channel.rover] = float64(s)
+		samples[i] = float32(channel.output[channel.rover-hop])
+		channel.rover++
+
+		if channel.rover == len(n.window) {
+			channel.rover = hop
+			n.suppressFrame(channel)
+		}
+	}
+}
+
+// suppressFrame turns down every bin of a full input frame by how much of it
+// the noise floor explains
+func (n *NoiseSuppressor) suppressFrame(channel *noiseSuppressionChannel) {
+	size := len(n.window)
+	half := size / 2
+
+	for k := range n.bins {
+		n.bins[k] = complex(channel.input[k]*n.window[k], 0)
+	}
+	fft(n.bins, false)
+
+	for k := range n.magnitudes {
+		n.magnitudes[k] = cmplx.Abs(n.bins[k])
+	}
+	if !n.learned && !silentFrame(channel.input) {
+		n.trackNoise(channel)
+	}
+
+	for k, magnitude := range n.magnitudes {
+		noise := channel.noise[k]
+		if !n.learned {
+			noise *= noiseMinimumBias
+		}
+
+		// Smoothing the magnitude keeps the noise that remains from
+		// fluttering between frames
+		channel.smoothed[k] += (magnitude - channel.smoothed[k]) * 0.5
+
+		gain := 1.0
+		if channel.smoothed[k] > 0 {
+			ratio := noise / channel.smoothed[k]
+			gain = math.Sqrt(max(1-noiseOverSubtraction*ratio*ratio, 0))
+		}
+		n.bins[k] *= complex(max(gain, n.floor), 0)
+	}
+	for k := half + 1; k < size; k++ {
+		n.bins[k] = cmplx.Conj(n.bins[size-k])
+	}
+	fft(n.bins, true)
+
+	for k := range n.window {
+		channel.accum[k] += real(n.bins[k]) * n.window[k] / float64(size)
+	}
+
+	copy(channel.output, channel.accum[:half])
+	copy(channel.accum, channel.accum[half:])
+	clear(channel.accum[half:])
+	copy(channel.input, channel.input[half:])
+}
+
+// trackNoise estimates the noise floor of every bin as the minimum of its
+// smoothed level. The estimate starts out at the median level of the first
+// frame, as noise spreads across the spectrum while a note held from the
+// start stands out of it.
+func (n *NoiseSuppressor) trackNoise(channel *noiseSuppressionChannel) {
+	if !channel.tracking {
+		copy(n.sorted, n.magnitudes)
+		slices.Sort(n.sorted)
+		median := n.sorted[len(n.sorted)/2]
+
+		copy(channel.level, n.magnitudes)
+		for k, level := range channel.level {
+			channel.noise[k] = min(level, median)
+		}
+		channel.tracking = true
+		return
+	}
+
+	// The level is smoothed before tracking its minimum, which would
+	// otherwise follow the dips of the noise rather than its average
+	for k, magnitude := range n.magnitudes {
+		channel.level[k] += (magnitude - channel.level[k]) * 0.1
+		channel.noise[k] = min(channel.level[k], channel.noise[k]*noiseFloorRise)
+	}
+}
+
+// silentFrame reports whether either half of a frame peaks below the silence
+// threshold
+func silentFrame(frame []float64) bool {
+	threshold := dbToLinear(noiseSilenceThresholdDB)
+	half := len(frame) / 2
+	for _, hop := range [][]float64{frame[:half], frame[half:]} {
+		if !slices.ContainsFunc(hop, func(s float64) bool { return math.Abs(s) >= threshold }) {
+			return true
+		}
+	}
+	return false
+}
+
+// NoiseProfiler averages the magnitude spectrum of the signal over a while to
+// learn the noise floor of a room. It is fed from an audio callback and read
+// once Done is closed.
+type NoiseProfiler struct {
+	channels int
+	window   []float64
+	bins     []complex128
+	frame    []float64
+	filled   int
+	frames   int
+	sum      []float64
+	count    int
+	done     chan struct{}
+	doneOnce sync.Once
+}
+
+// NewNoiseProfiler creates a new NoiseProfiler listening for duration
+func NewNoiseProfiler(sampleRate uint32, channels int, duration time.Duration) *NoiseProfiler {
+	size := noiseSuppressionFrameSize
+	return &NoiseProfiler{
+		channels: channels,
+		window:   sqrtHannWindow(size),
+		bins:     make([]complex128, size),
+		frame:    make([]float64, size),
+		frames:   max(1, int(duration.Seconds()*float64(sampleRate))/size),
+		sum:      make([]float64, size/2+1),
+		done:     make(chan struct{}),
+	}
+}
+
+// Write analyzes interleaved samples, mixing the channels together
+func (p *NoiseProfiler) Write(samples []float32) {
+	for i := 0; i+p.channels <= len(samples) && p.count < p.frames; i += p.channels {
+		mixed := 0.0
+		for _, s := range samples[i : i+p.channels] {
+			mixed += float64(s)
+		}
+		p.frame[p.filled] = mixed / float64(p.channels)
+		p.filled++
+
+		if p.filled < len(p.frame) {
+			continue
+		}
+		p.filled = 0
+
+		for k := range p.bins {
+			p.bins[k] = complex(p.frame[k]*p.window[k], 0)
+		}
+		fft(p.bins, false)
+
+		for k := range p.sum {
+			p.sum[k] += cmplx.Abs(p.bins[k])
+		}
+		p.count++
+	}
+
+	if p.count == p.frames {
+		p.doneOnce.Do(func() {
+			close(p.done)
+		})
+	}
+}
+
+// Done is closed once the profiler has heard enough
+func (p *NoiseProfiler) Done() <-chan struct{} {
+	return p.done
+}
+
+// NoiseFloor returns the average magnitude spectrum. It may only be called
+// once Done is closed.
+func (p *NoiseProfiler) NoiseFloor() []float64 {
+	noiseFloor := make([]float64, len(p.sum))
+	for k, sum := range p.sum {
+		noiseFloor[k] = sum / float64(p.count)
+	}
+	return noiseFloor
+}
+
+// noiseSuppressionKey keys the noise suppression settings of a capture device
+// by its backend and name, which unlike its ID stay the same across reboots
+// and replugs. Devices with the same name share their settings, and the
+// default device has an empty key.
+func noiseSuppressionKey(captureDevice DeviceIdentity) string {
+	if captureDevice.DeviceID == "" {
+		return ""
+	}
+
+	return captureDevice.Backend + "/" + captureDevice.Name
+}
+
+// listNoiseSuppressionSettings reads the noise suppression settings of every
+// capture device, keyed by noiseSuppressionKey
+func (a *App) listNoiseSuppressionSettings() (map[string]NoiseSuppressionSettings, error) {
+	serializedNoiseSuppressionSettings, _ := a.fs.GetItem("noiseSuppressionSettings")
+	if serializedNoiseSuppressionSettings == "" {
+		return map[string]NoiseSuppressionSettings{}, nil
+	}
+
+	var noiseSuppressionSettings map[string]NoiseSuppressionSettings
+	if err := json.Unmarshal([]byte(serializedNoiseSuppressionSettings), &noiseSuppressionSettings); err != nil {
+		return nil, err
+	}
+
+	return noiseSuppressionSettings, nil
+}
+
+// getNoiseSuppressionSettings gets the noise suppression settings of a capture device
+func (a *App) getNoiseSuppressionSettings(captureDevice DeviceIdentity) (NoiseSuppressionSettings, error) {
+	noiseSuppressionSettings, err := a.listNoiseSuppressionSettings()
+	if err != nil {
+		return NoiseSuppressionSettings{}, err
+	}
+
+	settings, ok := noiseSuppressionSettings[noiseSuppressionKey(captureDevice)]
+	if !ok {
+		return defaultNoiseSuppressionSettings, nil
+	}
+
+	return settings, nil
+}
+
+// setNoiseSuppressionSettings sets the noise suppression settings of a capture device
+func (a *App) setNoiseSuppressionSettings(captureDevice DeviceIdentity, settings NoiseSuppressionSettings) error {
+	return a.fs.UpdateItem("noiseSuppressionSettings", func(value string) (string, error) {
+		noiseSuppressionSettings := make(map[string]NoiseSuppressionSettings)
+		if value != "" {
+			if err := json.Unmarshal([]byte(value), &noiseSuppressionSettings); err != nil {
+				return "", err
+			}
+		}
+
+		noiseSuppressionSettings[noiseSuppressionKey(captureDevice)] = settings
+
+		serializedNoiseSuppressionSettings, err := json.Marshal(noiseSuppressionSettings)
+		if err != nil {
+			return "", err
+		}
+
+		return string(serializedNoiseSuppressionSettings), nil
+	})
+}
+
+// GetNoiseSuppressionSettings gets the noise suppression settings of the
+// capture device the loopback is using
+func (a *App) GetNoiseSuppressionSettings() (NoiseSuppressionSettings, error) {
+	loopbackDevices, err := a.GetLoopbackDevices()
+	if err != nil {
+		return NoiseSuppressionSettings{}, err
+	}
+
+	return a.getNoiseSuppressionSettings(loopbackDevices.captureDevice)
+}
+
+// SetNoiseSuppressionSettings sets the noise suppression settings of the
+// capture device the loopback is using. The noise floor is kept, as only
+// LearnNoiseFloor sets it.
+func (a *App) SetNoiseSuppressionSettings(settings NoiseSuppressionSettings) error {
+	if settings.ReductionDB < 0 || settings.ReductionDB > 60 {
+		return fmt.Errorf("unsupported noise reduction of %g dB", settings.ReductionDB)
+	}
+
+	loopbackDevices, err := a.GetLoopbackDevices()
+	if err != nil {
+		return err
+	}
+
+	stored, err := a.getNoiseSuppressionSettings(loopbackDevices.captureDevice)
+	if err != nil {
+		return err
+	}
+	settings.NoiseFloor = stored.NoiseFloor
+	settings.NoiseFloorSampleRate = stored.NoiseFloorSampleRate
+
+	if err := a.setNoiseSuppressionSettings(loopbackDevices.captureDevice, settings); err != nil {
+		return err
+	}
+
+	a.noiseSuppressionSettings.Store(&settings)
+
+	return nil
+}
+
+// LearnNoiseFloor listens to the capture device the loopback is using while
+// nobody speaks, and saves what it hears as the device's noise floor
+func (a *App) LearnNoiseFloor() error {
+	a.loopbackMu.Lock()
+	running := a.loopbackDone != nil
+	captureDevice := a.loopbackDevices.captureDevice
+	a.loopbackMu.Unlock()
+
+	if !running {
+		return errors.New("the loopback is not running")
+	}
+
+	loopbackSettings, err := a.GetLoopbackSettings()
+	if err != nil {
+		return err
+	}
+
+	profiler := NewNoiseProfiler(loopbackSettings.SampleRate, int(loopbackSettings.Channels), noiseFloorLearnDuration)
+	a.noiseProfiler.Store(profiler)
+	defer a.noiseProfiler.CompareAndSwap(profiler, nil)
+
+	select {
+	case <-profiler.Done():
+	case <-time.After(2 * noiseFloorLearnDuration):
+		return errors.New("timed out learning the noise floor")
+	}
+
+	settings, err := a.getNoiseSuppressionSettings(captureDevice)
+	if err != nil {
+		return err
+	}
+	settings.NoiseFloor = profiler.NoiseFloor()
+	settings.NoiseFloorSampleRate = loopbackSettings.SampleRate
+
+	if err := a.setNoiseSuppressionSettings(captureDevice, settings); err != nil {
+		return err
+	}
+
+	a.noiseSuppressionSettings.Store(&settings)
+	a.emit("noiseSuppressionSettings", settings)
+
+	return nil
+}
+
+// applyNoiseSuppressionSettings hands the noise suppression settings of a
+// capture device to the loopback, which rebuilds its suppressor when they change
+func (a *App) applyNoiseSuppressionSettings(captureDevice DeviceIdentity) {
+	settings, err := a.getNoiseSuppressionSettings(captureDevice)
+	if err != nil {
+		log.Println(err)
+		settings = defaultNoiseSuppressionSettings
+	}
+
+	a.noiseSuppressionSettings.Store(&settings)
+}
+
+// newNoiseSuppressor creates the noise suppressor for the loopback, or nil if
+// disabled. A noise floor learned at another sample rate does not apply.
+func newNoiseSuppressor(settings *NoiseSuppressionSettings, sampleRate uint32, channels int) Processor {
+	if settings == nil || !settings.Enabled {
+		return nil
+	}
+
+	noiseFloor := settings.NoiseFloor
+	if settings.NoiseFloorSampleRate != sampleRate {
+		noiseFloor = nil
+	}
+
+	return NewNoiseSuppressor(channels, settings.ReductionDB, noiseFloor)
+}
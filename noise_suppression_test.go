@@ -0,0 +1,193 @@
+package main
+
+import (
+	"math"
+	"math/rand"
+	"testing"
+	"time"
+)
+
+// whiteNoise returns seconds of mono white noise at the RMS level
+func whiteNoise(seconds float64, rms float64) []float32 {
+	random := rand.New(rand.NewSource(1))
+	samples := make([]float32, int(seconds*testSampleRate))
+	for i := range samples {
+		samples[i] = float32(random.NormFloat64() * rms)
+	}
+	return samples
+}
+
+// lastSecondRMS returns the RMS level of the last second of mono samples
+func lastSecondRMS(samples []float32) float64 {
+	var sum float64
+	for _, s := range samples[len(samples)-testSampleRate:] {
+		sum += float64(s) * float64(s)
+	}
+	return math.Sqrt(sum / testSampleRate)
+}
+
+func TestNoiseSuppressor(t *testing.T) {
+	tone := func(seconds float64) []float32 {
+		var samples []float32
+		for i := 0; i < int(seconds); i++ {
+			samples = append(samples, sine(1000, 0.3)...)
+		}
+		return samples
+	}
+	learn := func(noise []float32) []float64 {
+		profiler := NewNoiseProfiler(testSampleRate, 1, noiseFloorLearnDuration)
+		profiler.Write(noise)
+		return profiler.NoiseFloor()
+	}
+
+	tests := []struct {
+		name       string
+		input      []float32
+		noiseFloor []float64
+		minRMS     float64
+		maxRMS     float64
+	}{
+		{
+			name:   "noise",
+			input:  whiteNoise(5, 0.01),
+			maxRMS: 0.003,
+		},
+		{
+			name:   "noise after silence",
+			input:  append(make([]float32, testSampleRate/20), whiteNoise(5, 0.01)...),
+			maxRMS: 0.003,
+		},
+		{
+			name:   "tone held from the start",
+			input:  tone(5),
+			minRMS: 0.3 / math.Sqrt2 * 0.9,
+			maxRMS: 0.3 / math.Sqrt2 * 1.1,
+		},
+		{
+			name:       "noise under a learned floor",
+			input:      whiteNoise(5, 0.01),
+			noiseFloor: learn(whiteNoise(2, 0.01)),
+			maxRMS:     0.003,
+		},
+		{
+			name:       "tone over a learned floor",
+			input:      tone(5),
+			noiseFloor: learn(whiteNoise(2, 0.01)),
+			minRMS:     0.3 / math.Sqrt2 * 0.9,
+			maxRMS:     0.3 / math.Sqrt2 * 1.1,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			samples := append([]float32(nil), tt.input...)
+			NewNoiseSuppressor(1, 30, tt.noiseFloor).Process(samples)
+
+			if rms := lastSecondRMS(samples); rms < tt.minRMS || rms > tt.maxRMS {
+				t.Errorf("RMS %.5f in the last second, want %.5f to %.5f", rms, tt.minRMS, tt.maxRMS)
+			}
+		})
+	}
+}
+
+func TestNoiseSuppressorChannels(t *testing.T) {
+	// Noise on the left and a tone on the right are tracked apart
+	noise, tone := whiteNoise(5, 0.01), sine(1000, 0.3)
+	samples := make([]float32, 2*len(noise))
+	for i := range noise {
+		samples[2*i], samples[2*i+1] = noise[i], tone[i%len(tone)]
+	}
+
+	NewNoiseSuppressor(2, 30, nil).Process(samples)
+
+	left, right := make([]float32, len(noise)), make([]float32, len(noise))
+	for i := range noise {
+		left[i], right[i] = samples[2*i], samples[2*i+1]
+	}
+	if rms := lastSecondRMS(left); rms > 0.003 {
+		t.Errorf("noise RMS %.5f in the last second, want at most 0.003", rms)
+	}
+	if rms := lastSecondRMS(right); math.Abs(rms-0.3/math.Sqrt2) > 0.03 {
+		t.Errorf("tone RMS %.5f in the last second, want %.5f", rms, 0.3/math.Sqrt2)
+	}
+}
+
+func TestNoiseProfiler(t *testing.T) {
+	const duration = time.Second
+
+	// A tone in the middle of bin 32, mixed from two channels, one of which
+	// is silent
+	frequency := 32 * float64(testSampleRate) / noiseSuppressionFrameSize
+	tone := sine(frequency, 0.5)
+	samples := make([]float32, 2*len(tone))
+	for i, s := range tone {
+		samples[2*i] = s
+	}
+
+	profiler := NewNoiseProfiler(testSampleRate, 2, duration)
+	profiler.Write(samples[:len(samples)/2])
+	select {
+	case <-profiler.Done():
+		t.Fatal("done after half the duration")
+	default:
+	}
+	profiler.Write(samples[len(samples)/2:])
+	select {
+	case <-profiler.Done():
+	default:
+		t.Fatal("not done after the duration")
+	}
+
+	noiseFloor := profiler.NoiseFloor()
+	if len(noiseFloor) != noiseSuppressionFrameSize/2+1 {
+		t.Fatalf("got %d bins, want %d", len(noiseFloor), noiseSuppressionFrameSize/2+1)
+	}
+
+	// Half of the tone's amplitude is left after mixing, spread by the window
+	// over the bins next to it
+	peak := 0
+	for k, magnitude := range noiseFloor {
+		if magnitude > noiseFloor[peak] {
+			peak = k
+		}
+	}
+	if peak != 32 {
+		t.Errorf("loudest bin %d, want 32", peak)
+	}
+	var windowSum float64
+	for _, w := range sqrtHannWindow(noiseSuppressionFrameSize) {
+		windowSum += w
+	}
+	if want := 0.25 * windowSum / 2; math.Abs(noiseFloor[peak]-want) > want*0.01 {
+		t.Errorf("loudest bin %.2f, want %.2f", noiseFloor[peak], want)
+	}
+	if far := noiseFloor[96]; far > noiseFloor[peak]*1e-2 {
+		t.Errorf("bin 96 at %.4f, want nothing", far)
+	}
+}
+
+func TestNoiseSuppressionSettingsFollowDevice(t *testing.T) {
+	a := newTestApp(t)
+
+	mic := DeviceIdentity{Backend: "alsa", DeviceID: "6877303a31", Name: "USB Microphone"}
+	settings := NoiseSuppressionSettings{Enabled: true, ReductionDB: 30, NoiseFloor: []float64{0.1, 0.2}, NoiseFloorSampleRate: 48000}
+	if err := a.setNoiseSuppressionSettings(mic, settings); err != nil {
+		t.Fatal(err)
+	}
+
+	// The ID changed after a reboot, but the name did not
+	replugged := mic
+	replugged.DeviceID = "6877303a32"
+	got, err := a.getNoiseSuppressionSettings(replugged)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !got.Enabled || got.ReductionDB != 30 || len(got.NoiseFloor) != 2 {
+		t.Errorf("got %+v for the replugged device, want %+v", got, settings)
+	}
+
+	other := DeviceIdentity{Backend: "alsa", DeviceID: mic.DeviceID, Name: "Webcam"}
+	if got, err := a.getNoiseSuppressionSettings(other); err != nil || got.Enabled {
+		t.Errorf("got %+v, %v for another device, want the defaults", got, err)
+	}
+}
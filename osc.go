@@ -0,0 +1,473 @@
+package main
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"math"
+	"net"
+	"strings"
+	"sync"
+)
+
+// OSCSettings configures the OSC server
+type OSCSettings struct {
+	Enabled bool   `json:"enabled"`
+	Address string `json:"address"`
+}
+
+// defaultOSCAddress only accepts messages from this machine
+const defaultOSCAddress = "127.0.0.1:9000"
+
+// maxOSCSubscribers limits how many clients receive state broadcasts
+const maxOSCSubscribers = 32
+
+// OSCMessage is an OSC message with int32, float32, string or bool arguments
+type OSCMessage struct {
+	Address string
+	Args    []any
+}
+
+// Float returns the first numeric argument, or fallback when there is none
+func (m OSCMessage) Float(fallback float64) float64 {
+	for _, arg := range m.Args {
+		switch v := arg.(type) {
+		case float32:
+			return float64(v)
+		case int32:
+			return float64(v)
+		case bool:
+			if v {
+				return 1
+			}
+			return 0
+		}
+	}
+	return fallback
+}
+
+// MarshalBinary encodes the message as an OSC packet
+func (m OSCMessage) MarshalBinary() ([]byte, error) {
+	var buf bytes.Buffer
+	writeOSCString(&buf, m.Address)
+
+	typeTags := ","
+	var args bytes.Buffer
+	for _, arg := range m.Args {
+		switch v := arg.(type) {
+		case int32:
+			typeTags += "i"
+			binary.Write(&args, binary.BigEndian, v)
+		case int:
+			typeTags += "i"
+			binary.Write(&args, binary.BigEndian, int32(v))
+		case float32:
+			typeTags += "f"
+			binary.Write(&args, binary.BigEndian, math.Float32bits(v))
+		case float64:
+			typeTags += "f"
+			binary.Write(&args, binary.BigEndian, math.Float32bits(float32(v)))
+		case string:
+			typeTags += "s"
+			writeOSCString(&args, v)
+		case bool:
+			if v {
+				typeTags += "T"
+			} else {
+				typeTags += "F"
+			}
+		default:
+			return nil, fmt.Errorf("unsupported OSC argument type %T", arg)
+		}
+	}
+
+	writeOSCString(&buf, typeTags)
+	buf.Write(args.Bytes())
+
+	return buf.Bytes(), nil
+}
+
+func writeOSCString(buf *bytes.Buffer, s string) {
+	buf.WriteString(s)
+	// Strings are null terminated and padded to a multiple of four bytes
+	buf.Write(make([]byte, 4-len(s)%4))
+}
+
+// parseOSCPacket decodes an OSC packet, flattening bundles into their messages
+func parseOSCPacket(packet []byte) ([]OSCMessage, error) {
+	if bytes.HasPrefix(packet, []byte("#bundle\x00")) {
+		if len(packet) < 16 {
+			return nil, errors.New("truncated OSC bundle")
+		}
+
+		// Skip the bundle header and time tag; messages run immediately
+		packet = packet[16:]
+
+		var messages []OSCMessage
+		for len(packet) >= 4 {
+			size := int(binary.BigEndian.Uint32(packet))
+			packet = packet[4:]
+			if size > len(packet) || size%4 != 0 {
+				return nil, errors.New("invalid OSC bundle element size")
+			}
+
+			elementMessages, err := parseOSCPacket(packet[:size])
+			if err != nil {
+				return nil, err
+			}
+			messages = append(messages, elementMessages...)
+
+			packet = packet[size:]
+		}
+
+		return messages, nil
+	}
+
+	address, packet, err := readOSCString(packet)
+	if err != nil {
+		return nil, err
+	}
+	if !strings.HasPrefix(address, "/") {
+		return nil, fmt.Errorf("invalid OSC address %q", address)
+	}
+
+	msg := OSCMessage{Address: address}
+	if len(packet) == 0 {
+		return []OSCMessage{msg}, nil
+	}
+
+	typeTags, packet, err := readOSCString(packet)
+	if err != nil {
+		return nil, err
+	}
+	if !strings.HasPrefix(typeTags, ",") {
+		return nil, fmt.Errorf("invalid OSC type tags %q", typeTags)
+	}
+
+	for _, tag := range typeTags[1:] {
+		switch tag {
+		case 'i', 'f':
+			if len(packet) < 4 {
+				return nil, errors.New("truncated OSC argument")
+			}
+			v := binary.BigEndian.Uint32(packet)
+			packet = packet[4:]
+
+			if tag == 'i' {
+				msg.Args = append(msg.Args, int32(v))
+			} else {
+				msg.Args = append(msg.Args, math.Float32frombits(v))
+			}
+		case 's':
+			var s string
+			s, packet, err = readOSCString(packet)
+			if err != nil {
+				return nil, err
+			}
+			msg.Args = append(msg.Args, s)
+		case 'T':
+			msg.Args = append(msg.Args, true)
+		case 'F':
+			msg.Args = append(msg.Args, false)
+		default:
+			return nil, fmt.Errorf("unsupported OSC type tag %q", tag)
+		}
+	}
+
+	return []OSCMessage{msg}, nil
+}
+
+func readOSCString(packet []byte) (string, []byte, error) {
+	end := bytes.IndexByte(packet, 0)
+	if end < 0 {
+		return "", nil, errors.New("unterminated OSC string")
+	}
+
+	size := (end/4 + 1) * 4
+	if size > len(packet) {
+		return "", nil, errors.New("truncated OSC string")
+	}
+
+	return string(packet[:end]), packet[size:], nil
+}
+
+// OSCServer receives OSC messages over UDP and broadcasts messages to the
+// clients that sent /yam/subscribe
+type OSCServer struct {
+	conn        net.PacketConn
+	handle      func(OSCMessage) []OSCMessage
+	mu          sync.Mutex
+	subscribers map[string]net.Addr
+}
+
+// NewOSCServer creates a new OSCServer that replies to each message with the
+// messages returned by handle
+func NewOSCServer(conn net.PacketConn, handle func(OSCMessage) []OSCMessage) *OSCServer {
+	return &OSCServer{
+		conn:        conn,
+		handle:      handle,
+		subscribers: make(map[string]net.Addr),
+	}
+}
+
+// Serve handles packets until the connection is closed
+func (s *OSCServer) Serve() error {
+	buf := make([]byte, 65536)
+	for {
+		n, addr, err := s.conn.ReadFrom(buf)
+		if err != nil {
+			if errors.Is(err, net.ErrClosed) {
+				return nil
+			}
+			return err
+		}
+
+		messages, err := parseOSCPacket(buf[:n])
+		if err != nil {
+			log.Println(err)
+			continue
+		}
+
+		for _, msg := range messages {
+			for _, reply := range s.dispatch(msg, addr) {
+				if err := s.send(reply, addr); err != nil {
+					log.Println(err)
+				}
+			}
+		}
+	}
+}
+
+func (s *OSCServer) dispatch(msg OSCMessage, addr net.Addr) []OSCMessage {
+	switch msg.Address {
+	case "/yam/subscribe":
+		s.mu.Lock()
+		defer s.mu.Unlock()
+
+		if len(s.subscribers) >= maxOSCSubscribers {
+			return []OSCMessage{{Address: "/yam/error", Args: []any{"too many subscribers"}}}
+		}
+		s.subscribers[addr.String()] = addr
+
+		return []OSCMessage{{Address: "/yam/subscribed"}}
+	case "/yam/unsubscribe":
+		s.mu.Lock()
+		defer s.mu.Unlock()
+
+		delete(s.subscribers, addr.String())
+
+		return []OSCMessage{{Address: "/yam/unsubscribed"}}
+	}
+
+	return s.handle(msg)
+}
+
+// Broadcast sends a message to every subscribed client
+func (s *OSCServer) Broadcast(msg OSCMessage) {
+	s.mu.Lock()
+	subscribers := make([]net.Addr, 0, len(s.subscribers))
+	for _, addr := range s.subscribers {
+		subscribers = append(subscribers, addr)
+	}
+	s.mu.Unlock()
+
+	for _, addr := range subscribers {
+		if err := s.send(msg, addr); err != nil {
+			log.Println(err)
+		}
+	}
+}
+
+func (s *OSCServer) send(msg OSCMessage, addr net.Addr) error {
+	packet, err := msg.MarshalBinary()
+	if err != nil {
+		return err
+	}
+
+	_, err = s.conn.WriteTo(packet, addr)
+	return err
+}
+
+// Close stops the server
+func (s *OSCServer) Close() error {
+	return s.conn.Close()
+}
+
+// GetOSCSettings gets the OSC server settings
+func (a *App) GetOSCSettings() (OSCSettings, error) {
+	serializedOSCSettings, _ := a.fs.GetItem("oscSettings")
+	if serializedOSCSettings == "" {
+		return OSCSettings{Address: defaultOSCAddress}, nil
+	}
+
+	var oscSettings OSCSettings
+	if err := json.Unmarshal([]byte(serializedOSCSettings), &oscSettings); err != nil {
+		return OSCSettings{}, err
+	}
+
+	return oscSettings, nil
+}
+
+// SetOSCSettings sets the OSC server settings and restarts the server
+func (a *App) SetOSCSettings(oscSettings OSCSettings) error {
+	if oscSettings.Address == "" {
+		oscSettings.Address = defaultOSCAddress
+	}
+
+	serializedOSCSettings, err := json.Marshal(oscSettings)
+	if err != nil {
+		return err
+	}
+
+	if err := a.fs.SetItem("oscSettings", string(serializedOSCSettings)); err != nil {
+		return err
+	}
+
+	return a.restartOSC()
+}
+
+// restartOSC stops the OSC server and starts it again if enabled
+func (a *App) restartOSC() error {
+	a.oscMu.Lock()
+	defer a.oscMu.Unlock()
+
+	a.closeOSC()
+
+	oscSettings, err := a.GetOSCSettings()
+	if err != nil {
+		return err
+	}
+
+	if !oscSettings.Enabled {
+		return nil
+	}
+
+	conn, err := net.ListenPacket("udp", oscSettings.Address)
+	if err != nil {
+		return err
+	}
+
+	osc := NewOSCServer(conn, a.handleOSCMessage)
+	a.osc.Store(osc)
+
+	go func() {
+		if err := osc.Serve(); err != nil {
+			log.Println(err)
+		}
+	}()
+
+	return nil
+}
+
+// stopOSC stops the OSC server
+func (a *App) stopOSC() {
+	a.oscMu.Lock()
+	defer a.oscMu.Unlock()
+
+	a.closeOSC()
+}
+
+// closeOSC closes the OSC server, if any. The OSC lock must be held.
+func (a *App) closeOSC() {
+	osc := a.osc.Swap(nil)
+	if osc == nil {
+		return
+	}
+
+	if err := osc.Close(); err != nil {
+		log.Println(err)
+	}
+}
+
+// handleOSCMessage runs the action for an OSC address:
+//
+//	/yam/clip/{id}/play    play a clip
+//	/yam/clip/{id}/stop    stop a clip
+//	/yam/stopall           stop all clips
+//	/yam/volume f          set the clip volume
+//	/yam/mic/gain f        set the microphone gain
+//	/yam/clips             reply with /yam/clip/{id} s:audioFile s:state for each clip
+//
+// Buttons that send 0 on release only trigger on press.
+func (a *App) handleOSCMessage(msg OSCMessage) []OSCMessage {
+	parts := strings.Split(strings.TrimPrefix(msg.Address, "/"), "/")
+	value := msg.Float(1)
+
+	var err error
+	switch {
+	case len(parts) == 4 && parts[0] == "yam" && parts[1] == "clip" && (parts[3] == "play" || parts[3] == "stop"):
+		if value == 0 {
+			return nil
+		}
+
+		var audioFile string
+		audioFile, err = a.resolveClip(parts[2])
+		if err != nil {
+			break
+		}
+
+		if parts[3] == "play" {
+			err = a.actions.Run(ActionPlayAudioFile, audioFile, value)
+		} else {
+			err = a.actions.Run(ActionStopAudioFile, audioFile, value)
+		}
+	case msg.Address == "/yam/stopall":
+		if value == 0 {
+			return nil
+		}
+		err = a.actions.Run(ActionStopAll, "", value)
+	case msg.Address == "/yam/volume":
+		err = a.actions.Run(ActionSetVolume, "", value)
+	case msg.Address == "/yam/mic/gain":
+		err = a.actions.Run(ActionSetMicGain, "", value)
+	case msg.Address == "/yam/clips":
+		var clips []Clip
+		clips, err = a.ListClips()
+		if err != nil {
+			break
+		}
+
+		replies := make([]OSCMessage, len(clips))
+		for i, clip := range clips {
+			replies[i] = OSCMessage{
+				Address: fmt.Sprintf("/yam/clip/%d", clip.ID),
+				Args:    []any{clip.AudioFile, clip.State},
+			}
+		}
+		return replies
+	default:
+		err = fmt.Errorf("unknown OSC address %q", msg.Address)
+	}
+
+	if err != nil {
+		return []OSCMessage{{Address: "/yam/error", Args: []any{err.Error()}}}
+	}
+
+	return nil
+}
+
+// broadcastOSCState sends /yam/clip/{id}/state s:state to OSC subscribers
+func (a *App) broadcastOSCState(event AudioFileStateEvent) {
+	osc := a.osc.Load()
+	if osc == nil {
+		return
+	}
+
+	audioFiles, err := a.ListAudioFiles()
+	if err != nil {
+		log.Println(err)
+		return
+	}
+
+	for id, audioFile := range audioFiles {
+		if audioFile == event.AudioFile {
+			osc.Broadcast(OSCMessage{
+				Address: fmt.Sprintf("/yam/clip/%d/state", id),
+				Args:    []any{event.State},
+			})
+		}
+	}
+}
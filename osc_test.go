@@ -0,0 +1,102 @@
+package main
+
+import (
+	"context"
+	"net"
+	"testing"
+	"time"
+)
+
+func TestOSCServer(t *testing.T) {
+	a := newTestApp(t)
+	a.startup(context.Background())
+	defer a.shutdown(context.Background())
+
+	audioFile := writeTestWAV(t, 44100, 2, 2)
+	if err := a.AddAudioFile(audioFile); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := a.SetOSCSettings(OSCSettings{Enabled: true, Address: "127.0.0.1:0"}); err != nil {
+		t.Fatal(err)
+	}
+	server := a.osc.Load().conn.LocalAddr()
+
+	client, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer client.Close()
+
+	send := func(msg OSCMessage) {
+		t.Helper()
+
+		packet, err := msg.MarshalBinary()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := client.WriteTo(packet, server); err != nil {
+			t.Fatal(err)
+		}
+	}
+	receive := func() OSCMessage {
+		t.Helper()
+
+		buf := make([]byte, 1024)
+		if err := client.SetReadDeadline(time.Now().Add(2 * time.Second)); err != nil {
+			t.Fatal(err)
+		}
+		n, _, err := client.ReadFrom(buf)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		messages, err := parseOSCPacket(buf[:n])
+		if err != nil || len(messages) != 1 {
+			t.Fatal(messages, err)
+		}
+		return messages[0]
+	}
+
+	send(OSCMessage{Address: "/yam/subscribe"})
+	if msg := receive(); msg.Address != "/yam/subscribed" {
+		t.Fatalf("got %v, want /yam/subscribed", msg)
+	}
+
+	send(OSCMessage{Address: "/yam/clip/0/play", Args: []any{float32(1)}})
+	if msg := receive(); msg.Address != "/yam/clip/0/state" || len(msg.Args) != 1 || msg.Args[0] != AudioFileStatePlaying {
+		t.Fatalf("got %v, want /yam/clip/0/state playing", msg)
+	}
+	if state := a.voices.State(audioFile); state != AudioFileStatePlaying {
+		t.Errorf("clip is %s, want playing", state)
+	}
+
+	send(OSCMessage{Address: "/yam/stopall", Args: []any{int32(1)}})
+	if msg := receive(); msg.Address != "/yam/clip/0/state" || len(msg.Args) != 1 || msg.Args[0] == AudioFileStatePlaying {
+		t.Fatalf("got %v, want /yam/clip/0/state not playing", msg)
+	}
+
+	send(OSCMessage{Address: "/yam/mic/gain", Args: []any{float32(0.5)}})
+	send(OSCMessage{Address: "/yam/clip/9/play"})
+	if msg := receive(); msg.Address != "/yam/error" {
+		t.Fatalf("got %v, want /yam/error", msg)
+	}
+	if micGain, err := a.GetMicGain(); err != nil || micGain != 0.5 {
+		t.Errorf("mic gain is %v, want 0.5", micGain)
+	}
+
+	// Restarting the server while clips change state must not race
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for i := 0; i < 20; i++ {
+			a.broadcastOSCState(AudioFileStateEvent{AudioFile: audioFile, State: AudioFileStatePlaying})
+		}
+	}()
+	for i := 0; i < 5; i++ {
+		if err := a.restartOSC(); err != nil {
+			t.Fatal(err)
+		}
+	}
+	<-done
+}
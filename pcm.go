@@ -0,0 +1,92 @@
+package main
+
+import (
+	"encoding/binary"
+	"math"
+	"unsafe"
+
+	"github.com/gen2brain/malgo"
+)
+
+// scaleSamples multiplies interleaved samples of the given format by gain in place
+func scaleSamples(samples []byte, format malgo.FormatType, gain float64) {
+	if gain == 1 {
+		return
+	}
+
+	switch format {
+	case malgo.FormatU8:
+		for i, s := range samples {
+			samples[i] = byte(clampSample((float64(s)-128)*gain, -128, 127) + 128)
+		}
+	case malgo.FormatS16:
+		for i := 0; i+2 <= len(samples); i += 2 {
+			s := float64(int16(binary.LittleEndian.Uint16(samples[i:])))
+			binary.LittleEndian.PutUint16(samples[i:], uint16(int16(clampSample(s*gain, math.MinInt16, math.MaxInt16))))
+		}
+	case malgo.FormatS24:
+		for i := 0; i+3 <= len(samples); i += 3 {
+			s := float64(int32(uint32(samples[i])<<8|uint32(samples[i+1])<<16|uint32(samples[i+2])<<24) >> 8)
+			v := int32(clampSample(s*gain, -1<<23, 1<<23-1))
+			samples[i] = byte(v)
+			samples[i+1] = byte(v >> 8)
+			samples[i+2] = byte(v >> 16)
+		}
+	case malgo.FormatS32:
+		for i := 0; i+4 <= len(samples); i += 4 {
+			s := float64(int32(binary.LittleEndian.Uint32(samples[i:])))
+			binary.LittleEndian.PutUint32(samples[i:], uint32(int32(clampSample(s*gain, math.MinInt32, math.MaxInt32))))
+		}
+	case malgo.FormatF32:
+		for i := 0; i+4 <= len(samples); i += 4 {
+			s := float64(math.Float32frombits(binary.LittleEndian.Uint32(samples[i:])))
+			binary.LittleEndian.PutUint32(samples[i:], math.Float32bits(float32(s*gain)))
+		}
+	}
+}
+
+// decodeSamples converts interleaved samples of the given format into out,
+// which must hold one value per sample, scaled to the range -1 to 1
+func decodeSamples(out []float32, samples []byte, format malgo.FormatType) {
+	switch format {
+	case malgo.FormatU8:
+		for i, s := range samples {
+			out[i] = (float32(s) - 128) / 128
+		}
+	case malgo.FormatS16:
+		for i := 0; i+2 <= len(samples); i += 2 {
+			out[i/2] = float32(int16(binary.LittleEndian.Uint16(samples[i:]))) / (1 << 15)
+		}
+	case malgo.FormatS24:
+		for i := 0; i+3 <= len(samples); i += 3 {
+			out[i/3] = float32(int32(uint32(samples[i])<<8|uint32(samples[i+1])<<16|uint32(samples[i+2])<<24)>>8) / (1 << 23)
+		}
+	case malgo.FormatS32:
+		for i := 0; i+4 <= len(samples); i += 4 {
+			out[i/4] = float32(int32(binary.LittleEndian.Uint32(samples[i:]))) / (1 << 31)
+		}
+	case malgo.FormatF32:
+		for i := 0; i+4 <= len(samples); i += 4 {
+			out[i/4] = math.Float32frombits(binary.LittleEndian.Uint32(samples[i:]))
+		}
+	}
+}
+
+// float32Samples views F32 samples in place. Every supported platform is
+// little-endian, like the samples malgo passes.
+func float32Samples(samples []byte) []float32 {
+	if len(samples) < 4 {
+		return nil
+	}
+	return unsafe.Slice((*float32)(unsafe.Pointer(&samples[0])), len(samples)/4)
+}
+
+func clampSample(s, min, max float64) float64 {
+	if s < min {
+		return min
+	}
+	if s > max {
+		return max
+	}
+	return s
+}
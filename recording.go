@@ -0,0 +1,307 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/adrg/xdg"
+)
+
+// minRecordingFreeBytes is how much disk space has to be left to start a
+// recording or to keep one going
+const minRecordingFreeBytes = 256 << 20
+
+// recordingDrainInterval is how often the tapped audio is written to disk
+const recordingDrainInterval = 100 * time.Millisecond
+
+// recordingTapSeconds is how much audio the tap holds while waiting to be
+// written, to ride out a slow disk
+const recordingTapSeconds = 5
+
+var (
+	errRecording        = errors.New("already recording")
+	errRecordingTooLong = errors.New("the recording is too long for its format")
+	errDiskFull         = errors.New("stopped recording, the disk is almost full")
+)
+
+// RecordingState reports the recording in progress, or the last one once it
+// has stopped along with the error that stopped it, if any
+type RecordingState struct {
+	Recording bool    `json:"recording"`
+	Path      string  `json:"path"`
+	Format    string  `json:"format"`
+	Bus       string  `json:"bus"`
+	Seconds   float64 `json:"seconds"`
+	Bytes     int64   `json:"bytes"`
+	FreeBytes uint64  `json:"freeBytes"`
+	Error     string  `json:"error"`
+}
+
+// recording is a recording in progress
+type recording struct {
+	cancel context.CancelFunc
+	done   chan struct{}
+	err    error
+}
+
+// defaultRecordingsPath is where recordings go when no folder is given
+func defaultRecordingsPath() string {
+	return filepath.Join(xdg.DataHome, "various-yam", "recordings")
+}
+
+// clipLibraryPath is where clips made in the app are saved
+func clipLibraryPath() string {
+	return filepath.Join(xdg.DataHome, "various-yam", "clips")
+}
+
+// StartRecording starts recording the final mix, the mic and clips on the
+// main bus, into a new WAV or FLAC file, which can be added back as an audio
+// file, and returns its path. path is the file to record into when it ends in
+// .wav or .flac, which also picks the format if none is given. Otherwise it is
+// the folder to record into, the default one if empty, with the file named
+// after the time. An existing file is never overwritten, a counter is added to
+// the name instead. The recording keeps going across loopback restarts,
+// leaving out the audio while the bus is not playing.
+func (a *App) StartRecording(path string, format string) (string, error) {
+	return a.StartBusRecording(path, format, mainBus)
+}
+
+// StartBusRecording starts recording like StartRecording, but what the mixer
+// renders on a single bus, the main bus if empty
+func (a *App) StartBusRecording(path string, format string, bus string) (string, error) {
+	format = strings.ToLower(format)
+
+	// A path with the extension of a format is the file itself
+	dir, name := path, ""
+	if ext := strings.ToLower(strings.TrimPrefix(filepath.Ext(path), ".")); ext == recordingFormatWAV || ext == recordingFormatFLAC {
+		if format != "" && format != ext {
+			return "", fmt.Errorf("cannot record %s into %s", format, path)
+		}
+		format = ext
+		dir, name = filepath.Dir(path), strings.TrimSuffix(filepath.Base(path), filepath.Ext(path))
+	}
+
+	if format == "" {
+		format = recordingFormatWAV
+	}
+	if format != recordingFormatWAV && format != recordingFormatFLAC {
+		return "", fmt.Errorf("unsupported recording format %q", format)
+	}
+
+	if bus == "" {
+		bus = mainBus
+	}
+	if bus != mainBus && bus != monitorBus {
+		outputBuses, err := a.ListOutputBuses()
+		if err != nil {
+			return "", err
+		}
+		if !slices.ContainsFunc(outputBuses, func(outputBus OutputBus) bool {
+			return outputBus.Name == bus
+		}) {
+			return "", fmt.Errorf("no output bus named %q", bus)
+		}
+	}
+
+	if dir == "" {
+		dir = defaultRecordingsPath()
+	}
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return "", err
+	}
+
+	freeBytes, err := diskFreeBytes(dir)
+	if err != nil {
+		return "", err
+	}
+	if freeBytes < minRecordingFreeBytes {
+		return "", fmt.Errorf("only %d MB left on the disk", freeBytes>>20)
+	}
+
+	loopbackSettings, err := a.GetLoopbackSettings()
+	if err != nil {
+		return "", err
+	}
+
+	a.recordingMu.Lock()
+	defer a.recordingMu.Unlock()
+
+	if a.recording != nil {
+		select {
+		case <-a.recording.done:
+		default:
+			return "", errRecording
+		}
+	}
+
+	if name == "" {
+		name = "recording-" + time.Now().Format("2006-01-02_15-04-05")
+		if bus != mainBus {
+			name += "-" + bus
+		}
+	}
+	file, err := createAudioFile(dir, name, format)
+	if err != nil {
+		return "", err
+	}
+	filePath := file.Name()
+
+	channels := int(loopbackSettings.Channels)
+	encoder, err := newAudioEncoder(file, format, loopbackSettings.SampleRate, channels)
+	if err != nil {
+		file.Close()
+		return "", err
+	}
+
+	tap := NewMixerTap(bus, loopbackSettings.SampleRate, channels, int(loopbackSettings.SampleRate)*channels*recordingTapSeconds)
+	a.mixer.AddTap(tap)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	r := &recording{
+		cancel: cancel,
+		done:   make(chan struct{}),
+	}
+	a.recording = r
+
+	state := RecordingState{
+		Recording: true,
+		Path:      filePath,
+		Format:    format,
+		Bus:       bus,
+		FreeBytes: freeBytes,
+	}
+	a.updateRecordingState(state)
+
+	go func() {
+		r.err = a.record(ctx, file, encoder, tap, state, loopbackSettings.SampleRate)
+		if r.err != nil {
+			log.Println(r.err)
+		}
+		close(r.done)
+	}()
+
+	return filePath, nil
+}
+
+// record writes what the tap receives with the encoder until ctx is done, the
+// disk is almost full or writing fails, and then completes the file
+func (a *App) record(ctx context.Context, file *os.File, encoder AudioEncoder, tap *MixerTap, state RecordingState, sampleRate uint32) error {
+	channels := tap.channels
+	samples := make([]float32, tap.queue.Cap())
+	var frames int64
+
+	drain := func() error {
+		n := tap.Read(samples)
+		n -= n % channels
+		frames += int64(n / channels)
+
+		return encoder.Write(samples[:n])
+	}
+
+	ticker := time.NewTicker(recordingDrainInterval)
+	defer ticker.Stop()
+
+	var err error
+	lastUpdate := time.Now()
+	for err == nil {
+		select {
+		case <-ctx.Done():
+			err = context.Canceled
+			continue
+		case <-ticker.C:
+		}
+
+		if err = drain(); err != nil {
+			break
+		}
+
+		if time.Since(lastUpdate) < time.Second {
+			continue
+		}
+		lastUpdate = time.Now()
+
+		state.Seconds = float64(frames) / float64(sampleRate)
+		if info, statErr := file.Stat(); statErr == nil {
+			state.Bytes = info.Size()
+		}
+		if freeBytes, diskErr := diskFreeBytes(filepath.Dir(state.Path)); diskErr == nil {
+			state.FreeBytes = freeBytes
+			if freeBytes < minRecordingFreeBytes {
+				err = errDiskFull
+			}
+		}
+		a.updateRecordingState(state)
+	}
+
+	a.mixer.RemoveTap(tap)
+	if errors.Is(err, context.Canceled) {
+		err = drain()
+	}
+
+	if closeErr := encoder.Close(); err == nil {
+		err = closeErr
+	}
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+
+	state.Recording = false
+	state.Seconds = float64(frames) / float64(sampleRate)
+	if info, statErr := os.Stat(state.Path); statErr == nil {
+		state.Bytes = info.Size()
+	}
+	if err != nil {
+		state.Error = err.Error()
+	}
+	a.updateRecordingState(state)
+
+	return err
+}
+
+// StopRecording stops the recording in progress, if any, once what was
+// recorded has been written
+func (a *App) StopRecording() error {
+	a.recordingMu.Lock()
+	r := a.recording
+	a.recording = nil
+	a.recordingMu.Unlock()
+
+	if r == nil {
+		return nil
+	}
+
+	select {
+	case <-r.done:
+		// It stopped by itself, and its state says why
+		return nil
+	default:
+	}
+
+	r.cancel()
+	<-r.done
+
+	return r.err
+}
+
+// GetRecordingState gets the state of the recording in progress, or of the
+// last one
+func (a *App) GetRecordingState() (RecordingState, error) {
+	if recordingState := a.recordingState.Load(); recordingState != nil {
+		return *recordingState, nil
+	}
+
+	return RecordingState{}, nil
+}
+
+// updateRecordingState stores the recording state and emits it
+func (a *App) updateRecordingState(recordingState RecordingState) {
+	a.recordingState.Store(&recordingState)
+	a.emit("recording", recordingState)
+}
@@ -0,0 +1,123 @@
+package main
+
+import (
+	"encoding/binary"
+	"errors"
+	"io"
+	"path/filepath"
+	"testing"
+)
+
+func TestStartRecordingErrors(t *testing.T) {
+	a := newTestApp(t)
+	dir := t.TempDir()
+
+	tests := []struct {
+		name   string
+		path   string
+		format string
+		bus    string
+	}{
+		{"unsupported format", dir, "ogg", ""},
+		{"format of another extension", filepath.Join(dir, "take.wav"), "flac", ""},
+		{"missing bus", dir, "wav", "stream"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := a.StartBusRecording(tt.path, tt.format, tt.bus); err == nil {
+				t.Error("started recording")
+			}
+		})
+	}
+
+	if err := a.StopRecording(); err != nil {
+		t.Errorf("got %v stopping without a recording, want nil", err)
+	}
+}
+
+func TestRecording(t *testing.T) {
+	tests := []struct {
+		name     string
+		path     string
+		format   string
+		wantName string
+	}{
+		{"wav into a folder", "", "wav", ""},
+		{"flac into a folder", "", "FLAC", ""},
+		{"wav file", "take.wav", "", "take.wav"},
+		{"flac file", "take.flac", "flac", "take.flac"},
+		{"existing file", "existing.wav", "", "existing-2.wav"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			a := newTestApp(t)
+			dir := t.TempDir()
+
+			path := filepath.Join(dir, tt.path)
+			if tt.wantName == "existing-2.wav" {
+				file, err := createAudioFile(dir, "existing", "wav")
+				if err != nil {
+					t.Fatal(err)
+				}
+				file.Close()
+			}
+
+			// The mixer renders as the loopback would at its default settings
+			output := a.mixer.Attach(defaultLoopbackSettings.SampleRate, int(defaultLoopbackSettings.Channels))
+			defer output.Detach()
+
+			filePath, err := a.StartRecording(path, tt.format)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if tt.wantName != "" && filePath != filepath.Join(dir, tt.wantName) {
+				t.Errorf("recording into %s, want %s", filePath, filepath.Join(dir, tt.wantName))
+			}
+			if _, err := a.StartRecording(dir, "wav"); !errors.Is(err, errRecording) {
+				t.Errorf("got %v starting a second recording, want %v", err, errRecording)
+			}
+
+			const blocks, blockFrames = 10, 441
+			for i := 0; i < blocks; i++ {
+				mic := make([]float32, blockFrames)
+				for j := range mic {
+					mic[j] = 0.25
+				}
+				output.Mix(mic, 1)
+			}
+
+			if err := a.StopRecording(); err != nil {
+				t.Fatal(err)
+			}
+
+			state, err := a.GetRecordingState()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if state.Recording || state.Path != filePath || state.Error != "" || state.Seconds != 0.1 {
+				t.Errorf("got state %+v after stopping", state)
+			}
+
+			stream, err := openAudioStream(filePath)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer stream.Close()
+			pcm, err := io.ReadAll(stream)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if len(pcm) != blocks*blockFrames*2 {
+				t.Fatalf("recorded %d samples, want %d", len(pcm)/2, blocks*blockFrames)
+			}
+			for i := 0; i < len(pcm); i += 2 {
+				if got, want := int16(binary.LittleEndian.Uint16(pcm[i:])), quantizeSample(0.25); got != want {
+					t.Fatalf("sample %d is %d, want %d", i/2, got, want)
+				}
+			}
+		})
+	}
+}
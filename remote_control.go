@@ -0,0 +1,491 @@
+package main
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net"
+	"net/http"
+	"net/url"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"golang.org/x/net/websocket"
+)
+
+// RemoteControlSettings configures the HTTP remote control server
+type RemoteControlSettings struct {
+	Enabled bool   `json:"enabled"`
+	Address string `json:"address"`
+	Token   string `json:"token"`
+}
+
+// defaultRemoteControlAddress only accepts connections from this machine
+const defaultRemoteControlAddress = "127.0.0.1:8765"
+
+// Clip describes an audio file as seen by remote clients. ID is the audio
+// file's position in the list.
+type Clip struct {
+	ID         int    `json:"id"`
+	AudioFile  string `json:"audioFile"`
+	State      string `json:"state"`
+	Keybinding string `json:"keybinding"`
+	Loop       bool   `json:"loop"`
+}
+
+// ListClips lists all audio files along with their state and settings
+func (a *App) ListClips() ([]Clip, error) {
+	audioFiles, err := a.ListAudioFiles()
+	if err != nil {
+		return nil, err
+	}
+
+	audioFileKeybindings, err := a.ListAudioFileKeybindings()
+	if err != nil {
+		return nil, err
+	}
+
+	audioFileLoops, err := a.ListAudioFileLoops()
+	if err != nil {
+		return nil, err
+	}
+
+	clips := make([]Clip, len(audioFiles))
+	for i, audioFile := range audioFiles {
+		clips[i] = Clip{
+			ID:         i,
+			AudioFile:  audioFile,
+			State:      a.voices.State(audioFile),
+			Keybinding: audioFileKeybindings[audioFile],
+			Loop:       audioFileLoops[audioFile],
+		}
+	}
+
+	return clips, nil
+}
+
+// resolveClip returns the audio file for a clip ID, path or file name
+func (a *App) resolveClip(clip string) (string, error) {
+	audioFiles, err := a.ListAudioFiles()
+	if err != nil {
+		return "", err
+	}
+
+	if index, err := strconv.Atoi(clip); err == nil {
+		if index < 0 || index >= len(audioFiles) {
+			return "", fmt.Errorf("no clip with ID %d", index)
+		}
+		return audioFiles[index], nil
+	}
+
+	for _, audioFile := range audioFiles {
+		if audioFile == clip {
+			return audioFile, nil
+		}
+	}
+
+	for _, audioFile := range audioFiles {
+		name := filepath.Base(audioFile)
+		if name == clip || strings.TrimSuffix(name, filepath.Ext(name)) == clip {
+			return audioFile, nil
+		}
+	}
+
+	return "", fmt.Errorf("no clip named %q", clip)
+}
+
+// GetRemoteControlSettings gets the remote control server settings
+func (a *App) GetRemoteControlSettings() (RemoteControlSettings, error) {
+	serializedRemoteControlSettings, _ := a.fs.GetItem("remoteControlSettings")
+	if serializedRemoteControlSettings == "" {
+		return RemoteControlSettings{Address: defaultRemoteControlAddress}, nil
+	}
+
+	var remoteControlSettings RemoteControlSettings
+	if err := json.Unmarshal([]byte(serializedRemoteControlSettings), &remoteControlSettings); err != nil {
+		return RemoteControlSettings{}, err
+	}
+
+	return remoteControlSettings, nil
+}
+
+// SetRemoteControlSettings sets the remote control server settings and restarts
+// the server. A token is generated when enabling the server without one.
+func (a *App) SetRemoteControlSettings(remoteControlSettings RemoteControlSettings) (RemoteControlSettings, error) {
+	if remoteControlSettings.Address == "" {
+		remoteControlSettings.Address = defaultRemoteControlAddress
+	}
+
+	if remoteControlSettings.Enabled && remoteControlSettings.Token == "" {
+		token := make([]byte, 16)
+		if _, err := rand.Read(token); err != nil {
+			return RemoteControlSettings{}, err
+		}
+		remoteControlSettings.Token = hex.EncodeToString(token)
+	}
+
+	serializedRemoteControlSettings, err := json.Marshal(remoteControlSettings)
+	if err != nil {
+		return RemoteControlSettings{}, err
+	}
+
+	if err := a.fs.SetItem("remoteControlSettings", string(serializedRemoteControlSettings)); err != nil {
+		return RemoteControlSettings{}, err
+	}
+
+	return remoteControlSettings, a.restartRemoteControl()
+}
+
+// restartRemoteControl stops the remote control server and starts it again if enabled
+func (a *App) restartRemoteControl() error {
+	a.remoteControlMu.Lock()
+	defer a.remoteControlMu.Unlock()
+
+	a.closeRemoteControl()
+
+	remoteControlSettings, err := a.GetRemoteControlSettings()
+	if err != nil {
+		return err
+	}
+
+	if !remoteControlSettings.Enabled {
+		return nil
+	}
+
+	listener, err := net.Listen("tcp", remoteControlSettings.Address)
+	if err != nil {
+		return err
+	}
+
+	// Requests see the context canceled once the server is closed, which
+	// keeps WebSocket connections accepted meanwhile from streaming
+	ctx, cancel := context.WithCancel(a.ctx)
+	server := &http.Server{
+		Handler:           a.remoteControlHandler(remoteControlSettings.Token, remoteControlSettings.Address),
+		ReadHeaderTimeout: 10 * time.Second,
+		BaseContext: func(net.Listener) context.Context {
+			return ctx
+		},
+	}
+	a.remoteControl = server
+	a.cancelRemoteControl = cancel
+
+	go func() {
+		if err := server.Serve(listener); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			log.Println(err)
+		}
+	}()
+
+	return nil
+}
+
+// stopRemoteControl stops the remote control server, closing all connections
+// including WebSocket ones
+func (a *App) stopRemoteControl() {
+	a.remoteControlMu.Lock()
+	defer a.remoteControlMu.Unlock()
+
+	a.closeRemoteControl()
+}
+
+// closeRemoteControl closes the remote control server, if any, along with
+// the WebSocket connections it hijacked, which closing the server leaves
+// open. The remote control lock must be held.
+func (a *App) closeRemoteControl() {
+	if a.remoteControl == nil {
+		return
+	}
+
+	a.cancelRemoteControl()
+	if err := a.remoteControl.Close(); err != nil {
+		log.Println(err)
+	}
+	a.remoteControl = nil
+	a.cancelRemoteControl = nil
+
+	for ws := range a.remoteControlConns {
+		if err := ws.Close(); err != nil {
+			log.Println(err)
+		}
+	}
+	clear(a.remoteControlConns)
+}
+
+// trackRemoteControlConn keeps a WebSocket connection to close along with the
+// server, and reports false if the server is already closed
+func (a *App) trackRemoteControlConn(ws *websocket.Conn) bool {
+	a.remoteControlMu.Lock()
+	defer a.remoteControlMu.Unlock()
+
+	if ws.Request().Context().Err() != nil {
+		return false
+	}
+
+	if a.remoteControlConns == nil {
+		a.remoteControlConns = make(map[*websocket.Conn]struct{})
+	}
+	a.remoteControlConns[ws] = struct{}{}
+
+	return true
+}
+
+// untrackRemoteControlConn forgets a closed WebSocket connection
+func (a *App) untrackRemoteControlConn(ws *websocket.Conn) {
+	a.remoteControlMu.Lock()
+	defer a.remoteControlMu.Unlock()
+
+	delete(a.remoteControlConns, ws)
+}
+
+// remoteControlHandler serves the REST API under /api and streams audio file
+// state events over a WebSocket at /api/events. Browsers may only connect from
+// pages on this machine or on the host the server listens on at address.
+func (a *App) remoteControlHandler(token string, address string) http.Handler {
+	mux := http.NewServeMux()
+
+	mux.HandleFunc("/api/clips", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			writeRemoteControlError(w, http.StatusMethodNotAllowed, errors.New("method not allowed"))
+			return
+		}
+
+		clips, err := a.ListClips()
+		writeRemoteControlResponse(w, clips, err)
+	})
+
+	mux.HandleFunc("/api/clips/", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			writeRemoteControlError(w, http.StatusMethodNotAllowed, errors.New("method not allowed"))
+			return
+		}
+
+		id, command, _ := strings.Cut(strings.TrimPrefix(r.URL.Path, "/api/clips/"), "/")
+		audioFile, err := a.resolveClip(id)
+		if err != nil {
+			writeRemoteControlError(w, http.StatusNotFound, err)
+			return
+		}
+
+		switch command {
+		case "play":
+			writeRemoteControlResponse(w, nil, a.actions.Run(ActionPlayAudioFile, audioFile, 1))
+		case "stop":
+			writeRemoteControlResponse(w, nil, a.actions.Run(ActionStopAudioFile, audioFile, 1))
+		default:
+			writeRemoteControlError(w, http.StatusNotFound, fmt.Errorf("unknown clip command %q", command))
+		}
+	})
+
+	mux.HandleFunc("/api/stop-all", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			writeRemoteControlError(w, http.StatusMethodNotAllowed, errors.New("method not allowed"))
+			return
+		}
+
+		writeRemoteControlResponse(w, nil, a.actions.Run(ActionStopAll, "", 1))
+	})
+
+	mux.HandleFunc("/api/devices/capture", func(w http.ResponseWriter, r *http.Request) {
+		a.serveRemoteControlDevices(w, r, a.ListCaptureDevices, a.GetCaptureDeviceID, a.SetCaptureDeviceID)
+	})
+
+	mux.HandleFunc("/api/devices/playback", func(w http.ResponseWriter, r *http.Request) {
+		a.serveRemoteControlDevices(w, r, a.ListPlaybackDevices, a.GetPlaybackDeviceID, a.SetPlaybackDeviceID)
+	})
+
+	mux.HandleFunc("/api/volume", func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case http.MethodGet:
+			volume, err := a.GetVolume()
+			writeRemoteControlResponse(w, map[string]float64{"volume": volume}, err)
+		case http.MethodPut:
+			var body struct {
+				Volume float64 `json:"volume"`
+			}
+			if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+				writeRemoteControlError(w, http.StatusBadRequest, err)
+				return
+			}
+
+			writeRemoteControlResponse(w, nil, a.actions.Run(ActionSetVolume, "", body.Volume))
+		default:
+			writeRemoteControlError(w, http.StatusMethodNotAllowed, errors.New("method not allowed"))
+		}
+	})
+
+	mux.Handle("/api/events", websocket.Server{
+		// Browsers send an Origin header but other clients do not, and the
+		// origin was already checked along with the token
+		Handshake: func(*websocket.Config, *http.Request) error {
+			return nil
+		},
+		Handler: func(ws *websocket.Conn) {
+			if !a.trackRemoteControlConn(ws) {
+				return
+			}
+			defer a.untrackRemoteControlConn(ws)
+
+			events := make(chan AudioFileStateEvent, 64)
+			unsubscribe := a.voices.Subscribe(func(event AudioFileStateEvent) {
+				select {
+				case events <- event:
+				default:
+					// Drop events for clients that cannot keep up
+				}
+			})
+			defer unsubscribe()
+
+			closed := make(chan struct{})
+			go func() {
+				// Clients do not send anything, so reading only detects the close
+				var discard []byte
+				for websocket.Message.Receive(ws, &discard) == nil {
+				}
+				close(closed)
+			}()
+
+			for {
+				select {
+				case event := <-events:
+					if err := websocket.JSON.Send(ws, event); err != nil {
+						return
+					}
+				case <-closed:
+					return
+				}
+			}
+		},
+	})
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !allowRemoteControlOrigin(r, address) {
+			writeRemoteControlError(w, http.StatusForbidden, errors.New("origin not allowed"))
+			return
+		}
+
+		if !authorizeRemoteControl(r, token) {
+			writeRemoteControlError(w, http.StatusUnauthorized, errors.New("invalid token"))
+			return
+		}
+
+		mux.ServeHTTP(w, r)
+	})
+}
+
+// serveRemoteControlDevices lists devices along with the selected one, or selects a device
+func (a *App) serveRemoteControlDevices(
+	w http.ResponseWriter,
+	r *http.Request,
+	list func() ([]MediaDeviceInfo, error),
+	get func() (string, error),
+	set func(string) error,
+) {
+	switch r.Method {
+	case http.MethodGet:
+		devices, err := list()
+		if err != nil {
+			writeRemoteControlResponse(w, nil, err)
+			return
+		}
+
+		deviceID, err := get()
+		writeRemoteControlResponse(w, map[string]any{
+			"devices":  devices,
+			"deviceId": deviceID,
+		}, err)
+	case http.MethodPut:
+		var body struct {
+			DeviceID string `json:"deviceId"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+			writeRemoteControlError(w, http.StatusBadRequest, err)
+			return
+		}
+
+		writeRemoteControlResponse(w, nil, set(body.DeviceID))
+	default:
+		writeRemoteControlError(w, http.StatusMethodNotAllowed, errors.New("method not allowed"))
+	}
+}
+
+// authorizeRemoteControl checks the bearer token, which WebSocket clients that
+// cannot set headers may pass as the token query parameter instead
+func authorizeRemoteControl(r *http.Request, token string) bool {
+	if token == "" {
+		return false
+	}
+
+	given, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+	if !ok {
+		given = r.URL.Query().Get("token")
+	}
+
+	return subtle.ConstantTimeCompare([]byte(given), []byte(token)) == 1
+}
+
+// allowRemoteControlOrigin checks that requests from browsers come from a page
+// on localhost or on the host of address, so that other web pages cannot use
+// a token they have learnt. Other clients do not send an Origin.
+func allowRemoteControlOrigin(r *http.Request, address string) bool {
+	origin := r.Header.Get("Origin")
+	if origin == "" {
+		return true
+	}
+
+	originURL, err := url.Parse(origin)
+	if err != nil {
+		return false
+	}
+
+	hostname := originURL.Hostname()
+	if hostname == "localhost" {
+		return true
+	}
+	if ip := net.ParseIP(hostname); ip != nil && ip.IsLoopback() {
+		return true
+	}
+
+	host, _, err := net.SplitHostPort(address)
+	if err != nil {
+		host = address
+	}
+
+	// Listening on all interfaces says nothing about the host pages come from
+	if ip := net.ParseIP(host); host == "" || ip != nil && ip.IsUnspecified() {
+		return false
+	}
+
+	return strings.EqualFold(hostname, host)
+}
+
+func writeRemoteControlResponse(w http.ResponseWriter, body any, err error) {
+	if err != nil {
+		writeRemoteControlError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	if body == nil {
+		w.WriteHeader(http.StatusNoContent)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(body); err != nil {
+		log.Println(err)
+	}
+}
+
+func writeRemoteControlError(w http.ResponseWriter, status int, err error) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	if err := json.NewEncoder(w).Encode(map[string]string{"error": err.Error()}); err != nil {
+		log.Println(err)
+	}
+}
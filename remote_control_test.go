@@ -0,0 +1,185 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"golang.org/x/net/websocket"
+)
+
+func TestAllowRemoteControlOrigin(t *testing.T) {
+	tests := []struct {
+		origin  string
+		address string
+		want    bool
+	}{
+		{"", "0.0.0.0:8765", true},
+		{"http://localhost:3000", "127.0.0.1:8765", true},
+		{"http://127.0.0.1:8765", "127.0.0.1:8765", true},
+		{"http://[::1]:8765", "0.0.0.0:8765", true},
+		{"http://192.168.1.20:8765", "192.168.1.20:8765", true},
+		{"http://deck.local", "Deck.local:8765", true},
+		{"http://192.168.1.20:8765", "0.0.0.0:8765", false},
+		{"http://192.168.1.20:8765", ":8765", false},
+		{"https://example.com", "127.0.0.1:8765", false},
+		{"null", "127.0.0.1:8765", false},
+	}
+
+	for _, tt := range tests {
+		r := httptest.NewRequest("GET", "/api/events", nil)
+		if tt.origin != "" {
+			r.Header.Set("Origin", tt.origin)
+		}
+
+		if got := allowRemoteControlOrigin(r, tt.address); got != tt.want {
+			t.Errorf("allowRemoteControlOrigin(%q, %q) = %v, want %v", tt.origin, tt.address, got, tt.want)
+		}
+	}
+}
+
+func TestAuthorizeRemoteControl(t *testing.T) {
+	tests := []struct {
+		name   string
+		header string
+		query  string
+		token  string
+		want   bool
+	}{
+		{"header", "Bearer secret", "", "secret", true},
+		{"query", "", "secret", "secret", true},
+		{"header over query", "Bearer secret", "wrong", "secret", true},
+		{"missing", "", "", "secret", false},
+		{"wrong header", "Bearer wrong", "", "secret", false},
+		{"wrong query", "", "wrong", "secret", false},
+		{"not a bearer", "Basic secret", "", "secret", false},
+		{"no token set", "Bearer ", "", "", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest("GET", "/api/clips?token="+tt.query, nil)
+			if tt.header != "" {
+				r.Header.Set("Authorization", tt.header)
+			}
+
+			if got := authorizeRemoteControl(r, tt.token); got != tt.want {
+				t.Errorf("authorizeRemoteControl() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRemoteControlAPI(t *testing.T) {
+	a := newTestApp(t)
+	if err := a.AddAudioFile("/clips/airhorn.wav"); err != nil {
+		t.Fatal(err)
+	}
+
+	server := httptest.NewServer(a.remoteControlHandler("secret", defaultRemoteControlAddress))
+	defer server.Close()
+
+	tests := []struct {
+		method string
+		path   string
+		token  string
+		body   string
+		status int
+		want   string
+	}{
+		{"GET", "/api/clips", "", "", http.StatusUnauthorized, "invalid token"},
+		{"GET", "/api/clips", "wrong", "", http.StatusUnauthorized, "invalid token"},
+		{"GET", "/api/clips", "secret", "", http.StatusOK, `"audioFile":"/clips/airhorn.wav","state":"missing"`},
+		{"POST", "/api/clips", "secret", "", http.StatusMethodNotAllowed, "method not allowed"},
+		{"POST", "/api/clips/7/play", "secret", "", http.StatusNotFound, "no clip with ID 7"},
+		{"POST", "/api/clips/airhorn/rewind", "secret", "", http.StatusNotFound, `unknown clip command \"rewind\"`},
+		{"POST", "/api/stop-all", "secret", "", http.StatusNoContent, ""},
+		{"PUT", "/api/volume", "secret", `{"volume":0.25}`, http.StatusNoContent, ""},
+		{"GET", "/api/volume", "secret", "", http.StatusOK, `{"volume":0.25}`},
+		{"PUT", "/api/volume", "secret", `{`, http.StatusBadRequest, "unexpected EOF"},
+		{"DELETE", "/api/volume", "secret", "", http.StatusMethodNotAllowed, "method not allowed"},
+	}
+
+	for _, tt := range tests {
+		r, err := http.NewRequest(tt.method, server.URL+tt.path, strings.NewReader(tt.body))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if tt.token != "" {
+			r.Header.Set("Authorization", "Bearer "+tt.token)
+		}
+
+		response, err := http.DefaultClient.Do(r)
+		if err != nil {
+			t.Fatal(err)
+		}
+		body, err := io.ReadAll(response.Body)
+		response.Body.Close()
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if response.StatusCode != tt.status || !strings.Contains(string(body), tt.want) {
+			t.Errorf("%s %s = %d %s, want %d containing %s", tt.method, tt.path, response.StatusCode, body, tt.status, tt.want)
+		}
+	}
+}
+
+func TestRemoteControlClosesWebSockets(t *testing.T) {
+	tests := []struct {
+		name     string
+		settings RemoteControlSettings
+	}{
+		{"disable", RemoteControlSettings{}},
+		{"rotate token", RemoteControlSettings{Enabled: true, Token: "rotated"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			a := newTestApp(t)
+			a.ctx = context.Background()
+			defer a.stopRemoteControl()
+
+			// Find a free port to listen on
+			listener, err := net.Listen("tcp", "127.0.0.1:0")
+			if err != nil {
+				t.Fatal(err)
+			}
+			address := listener.Addr().String()
+			listener.Close()
+
+			if _, err := a.SetRemoteControlSettings(RemoteControlSettings{Enabled: true, Address: address, Token: "secret"}); err != nil {
+				t.Fatal(err)
+			}
+
+			ws, err := websocket.Dial("ws://"+address+"/api/events?token=secret", "", "http://"+address)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer ws.Close()
+
+			waitFor(t, "the connection to be tracked", func() bool {
+				a.remoteControlMu.Lock()
+				defer a.remoteControlMu.Unlock()
+				return len(a.remoteControlConns) == 1
+			})
+
+			tt.settings.Address = address
+			if _, err := a.SetRemoteControlSettings(tt.settings); err != nil {
+				t.Fatal(err)
+			}
+
+			ws.SetReadDeadline(time.Now().Add(5 * time.Second))
+			var event AudioFileStateEvent
+			if err := websocket.JSON.Receive(ws, &event); !errors.Is(err, io.EOF) {
+				t.Errorf("got %v, want the connection closed", err)
+			}
+		})
+	}
+}
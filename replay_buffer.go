@@ -0,0 +1,259 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+)
+
+// How much audio the replay buffer can be set to keep
+const (
+	replayBufferMinSeconds = 30
+	replayBufferMaxSeconds = 120
+)
+
+// replayBufferDrainInterval is how often the tapped audio is moved into the
+// replay buffer
+const replayBufferDrainInterval = 100 * time.Millisecond
+
+var errReplayBufferOff = errors.New("the replay buffer is off")
+
+// ReplayBufferSettings configures the replay buffer, which keeps the last
+// Seconds of the mic or of the main bus in memory to save as a clip when the
+// keybinding is pressed
+type ReplayBufferSettings struct {
+	Enabled    bool   `json:"enabled"`
+	Seconds    int    `json:"seconds"`
+	Source     string `json:"source"`
+	Keybinding string `json:"keybinding"`
+}
+
+// defaultReplayBufferSettings keeps the shortest replay of the mic once enabled
+var defaultReplayBufferSettings = ReplayBufferSettings{
+	Seconds: replayBufferMinSeconds,
+	Source:  micTap,
+}
+
+// ReplayBuffer keeps the most recent samples written to it, overwriting the
+// oldest ones once full
+type ReplayBuffer struct {
+	mu         sync.Mutex
+	samples    []float32
+	pos        int
+	full       bool
+	sampleRate uint32
+	channels   int
+}
+
+// NewReplayBuffer creates a new ReplayBuffer holding seconds of audio at the
+// given format
+func NewReplayBuffer(sampleRate uint32, channels int, seconds int) *ReplayBuffer {
+	return &ReplayBuffer{
+		samples:    make([]float32, int(sampleRate)*channels*seconds),
+		sampleRate: sampleRate,
+		channels:   channels,
+	}
+}
+
+// Write appends samples, dropping the oldest ones to make room
+func (b *ReplayBuffer) Write(samples []float32) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if len(samples) > len(b.samples) {
+		samples = samples[len(samples)-len(b.samples):]
+	}
+
+	n := copy(b.samples[b.pos:], samples)
+	copy(b.samples, samples[n:])
+	if b.pos+len(samples) >= len(b.samples) {
+		b.full = true
+	}
+	b.pos = (b.pos + len(samples)) % len(b.samples)
+}
+
+// Snapshot returns a copy of the samples held, oldest first
+func (b *ReplayBuffer) Snapshot() []float32 {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if !b.full {
+		return append([]float32(nil), b.samples[:b.pos]...)
+	}
+
+	return append(append([]float32(nil), b.samples[b.pos:]...), b.samples[:b.pos]...)
+}
+
+// GetReplayBufferSettings gets the replay buffer settings
+func (a *App) GetReplayBufferSettings() (ReplayBufferSettings, error) {
+	serializedReplayBufferSettings, _ := a.fs.GetItem("replayBufferSettings")
+	if serializedReplayBufferSettings == "" {
+		return defaultReplayBufferSettings, nil
+	}
+
+	var replayBufferSettings ReplayBufferSettings
+	if err := json.Unmarshal([]byte(serializedReplayBufferSettings), &replayBufferSettings); err != nil {
+		return ReplayBufferSettings{}, err
+	}
+
+	return replayBufferSettings, nil
+}
+
+// SetReplayBufferSettings sets the replay buffer settings and restarts it,
+// which drops what it was holding
+func (a *App) SetReplayBufferSettings(replayBufferSettings ReplayBufferSettings) error {
+	if replayBufferSettings.Seconds < replayBufferMinSeconds || replayBufferSettings.Seconds > replayBufferMaxSeconds {
+		return fmt.Errorf("the replay buffer keeps between %d and %d seconds", replayBufferMinSeconds, replayBufferMaxSeconds)
+	}
+
+	if replayBufferSettings.Source != micTap && replayBufferSettings.Source != mainBus {
+		return fmt.Errorf("unsupported replay buffer source %q", replayBufferSettings.Source)
+	}
+
+	if replayBufferSettings.Keybinding != "" {
+		if _, err := ParseHotkey(replayBufferSettings.Keybinding); err != nil {
+			return err
+		}
+	}
+
+	serializedReplayBufferSettings, err := json.Marshal(replayBufferSettings)
+	if err != nil {
+		return err
+	}
+
+	if err := a.fs.SetItem("replayBufferSettings", string(serializedReplayBufferSettings)); err != nil {
+		return err
+	}
+
+	if err := a.restartReplayBuffer(); err != nil {
+		return err
+	}
+
+	return a.registerKeybindings()
+}
+
+// restartReplayBuffer starts filling a new replay buffer with the stored
+// settings at the loopback's format, or leaves it off if it is disabled
+func (a *App) restartReplayBuffer() error {
+	a.replayBufferMu.Lock()
+	defer a.replayBufferMu.Unlock()
+
+	a.stopReplayBuffer()
+
+	replayBufferSettings, err := a.GetReplayBufferSettings()
+	if err != nil {
+		return err
+	}
+	if !replayBufferSettings.Enabled {
+		return nil
+	}
+
+	loopbackSettings, err := a.GetLoopbackSettings()
+	if err != nil {
+		return err
+	}
+
+	channels := int(loopbackSettings.Channels)
+	buffer := NewReplayBuffer(loopbackSettings.SampleRate, channels, replayBufferSettings.Seconds)
+	tap := NewMixerTap(replayBufferSettings.Source, loopbackSettings.SampleRate, channels, int(loopbackSettings.SampleRate)*channels)
+	a.mixer.AddTap(tap)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	a.cancelReplayBuffer = cancel
+	a.replayBufferDone = done
+	a.replayBuffer.Store(buffer)
+
+	go func() {
+		defer close(done)
+		defer a.mixer.RemoveTap(tap)
+
+		samples := make([]float32, tap.queue.Cap())
+		ticker := time.NewTicker(replayBufferDrainInterval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				n := tap.Read(samples)
+				buffer.Write(samples[:n-n%channels])
+			}
+		}
+	}()
+
+	return nil
+}
+
+// stopReplayBuffer stops filling the replay buffer and drops it. The replay
+// buffer lock must be held.
+func (a *App) stopReplayBuffer() {
+	if a.cancelReplayBuffer == nil {
+		return
+	}
+
+	a.cancelReplayBuffer()
+	<-a.replayBufferDone
+
+	a.cancelReplayBuffer = nil
+	a.replayBufferDone = nil
+	a.replayBuffer.Store(nil)
+}
+
+// SaveReplay saves what the replay buffer holds as a WAV file in the clip
+// library and adds it to the audio files, returning its path
+func (a *App) SaveReplay() (string, error) {
+	buffer := a.replayBuffer.Load()
+	if buffer == nil {
+		return "", errReplayBufferOff
+	}
+
+	samples := buffer.Snapshot()
+	if len(samples) == 0 {
+		return "", errors.New("the replay buffer has not heard anything yet")
+	}
+
+	filePath, err := saveClip("replay", samples, buffer.sampleRate, buffer.channels)
+	if err != nil {
+		return "", err
+	}
+
+	return filePath, a.AddAudioFile(filePath)
+}
+
+// saveClip writes samples to a new WAV file in the clip library, named after
+// the time with the prefix and numbered if there is already a clip from the
+// same second
+func saveClip(prefix string, samples []float32, sampleRate uint32, channels int) (string, error) {
+	path := clipLibraryPath()
+	if err := os.MkdirAll(path, 0755); err != nil {
+		return "", err
+	}
+
+	file, err := createAudioFile(path, prefix+"-"+time.Now().Format("2006-01-02_15-04-05"), "wav")
+	if err != nil {
+		return "", err
+	}
+	defer file.Close()
+	filePath := file.Name()
+
+	encoder, err := NewWAVEncoder(file, sampleRate, channels)
+	if err != nil {
+		return "", err
+	}
+
+	if err := encoder.Write(samples); err != nil {
+		return "", err
+	}
+
+	if err := encoder.Close(); err != nil {
+		return "", err
+	}
+
+	return filePath, file.Close()
+}
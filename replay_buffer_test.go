@@ -0,0 +1,83 @@
+package main
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestReplayBuffer(t *testing.T) {
+	// Two seconds at 5 Hz in stereo hold 20 samples
+	const capacity = 20
+
+	tests := []struct {
+		name   string
+		writes []int
+	}{
+		{"empty", nil},
+		{"partly filled", []int{6, 4}},
+		{"exactly full", []int{12, 8}},
+		{"wrapping around", []int{12, 12}},
+		{"wrapping around twice", []int{14, 14, 14, 14}},
+		{"ending at the start", []int{10, 30}},
+		{"one write over capacity", []int{50}},
+		{"write over capacity after wrapping", []int{6, 18, 46}},
+		{"small writes", []int{2, 2, 2, 2, 2, 2, 2, 2, 2, 2, 2, 2, 2}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			b := NewReplayBuffer(5, 2, 2)
+
+			// Samples count up, so the snapshot should hold the last ones
+			// written, in order
+			var written []float32
+			for _, n := range tt.writes {
+				samples := make([]float32, n)
+				for i := range samples {
+					samples[i] = float32(len(written) + i)
+				}
+				b.Write(samples)
+				written = append(written, samples...)
+			}
+
+			want := written[max(len(written)-capacity, 0):]
+			if got := b.Snapshot(); !slices.Equal(got, want) {
+				t.Errorf("got %v, want %v", got, want)
+			}
+		})
+	}
+
+	// Snapshots are copies, which writes after them leave alone
+	b := NewReplayBuffer(5, 2, 2)
+	b.Write([]float32{1, 2, 3})
+	snapshot := b.Snapshot()
+	b.Write(make([]float32, capacity))
+	if !slices.Equal(snapshot, []float32{1, 2, 3}) {
+		t.Errorf("snapshot changed to %v", snapshot)
+	}
+}
+
+func TestSaveClip(t *testing.T) {
+	newTestApp(t)
+
+	samples := sine(440, 0.5)
+
+	// Saves within the same second get numbered instead of failing
+	seen := map[string]bool{}
+	for i := 0; i < 3; i++ {
+		filePath, err := saveClip("clip", samples, testSampleRate, 1)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if seen[filePath] {
+			t.Fatalf("saved over %s", filePath)
+		}
+		seen[filePath] = true
+
+		stream, err := openAudioStream(filePath)
+		if err != nil {
+			t.Fatal(err)
+		}
+		stream.Close()
+	}
+}
@@ -0,0 +1,275 @@
+package main
+
+import (
+	"math"
+)
+
+// Limits and gains of the drift controller. The ratio never strays more than
+// maxDriftRatio from 1, which is far beyond real clock drift but inaudible.
+const (
+	maxDriftRatio       = 0.005
+	driftProportional   = 0.005
+	driftIntegral       = 0.00001
+	driftFillSmoothing  = 0.02
+	driftIntegralWindup = maxDriftRatio / driftIntegral
+)
+
+// DriftResampler reads interleaved audio from a RingBuffer at a ratio that
+// follows the buffer's fill level, so that a producer and consumer running on
+// different clocks stay in sync without dropping or repeating audio. It is
+// meant for the consumer of the buffer only.
+type DriftResampler struct {
+	channels   int
+	targetFill int
+	ratio      float64
+	fill       float64
+	integral   float64
+	pos        float64
+	prev       []float32
+	next       []float32
+	primed     bool
+	input      []float32
+}
+
+// NewDriftResampler creates a new DriftResampler keeping about targetFill
+// samples in the buffer
+func NewDriftResampler(channels int, targetFill int) *DriftResampler {
+	return &DriftResampler{
+		channels:   channels,
+		targetFill: targetFill,
+		ratio:      1,
+		fill:       float64(targetFill),
+		prev:       make([]float32, channels),
+		next:       make([]float32, channels),
+	}
+}
+
+// Ratio returns how many input frames are consumed per output frame
+func (r *DriftResampler) Ratio() float64 {
+	return r.ratio
+}
+
+// Read fills out with resampled frames from queue, reporting false if the
+// queue ran dry during this read. After running dry it plays silence until
+// the queue is back at its target fill, reporting true while it waits as
+// nothing more is missing from the queue.
+func (r *DriftResampler) Read(queue *RingBuffer, out []float32) bool {
+	frames := len(out) / r.channels
+
+	if !r.primed {
+		if queue.Len() < r.targetFill+len(out) {
+			clear(out)
+			return true
+		}
+		r.primed = true
+		r.integral = 0
+		r.fill = float64(queue.Len())
+	}
+
+	// Linear interpolation between prev and next, advancing through the input
+	// by ratio frames for every output frame
+	advance := int(r.pos + float64(frames)*r.ratio)
+	need := advance * r.channels
+	if cap(r.input) < need {
+		r.input = make([]float32, need)
+	}
+	input := r.input[:need]
+	ok := queue.Read(input) == need
+	if !ok {
+		r.primed = false
+	}
+
+	consumed := 0
+	for i := 0; i < frames; i++ {
+		t := float32(r.pos)
+		for c := 0; c < r.channels; c++ {
+			out[i*r.channels+c] = r.prev[c] + (r.next[c]-r.prev[c])*t
+		}
+
+		for r.pos += r.ratio; r.pos >= 1; r.pos-- {
+			copy(r.prev, r.next)
+			if consumed < advance {
+				copy(r.next, input[consumed*r.channels:(consumed+1)*r.channels])
+				consumed++
+			}
+		}
+	}
+
+	r.steer(queue.Len(), frames)
+
+	return ok
+}
+
+// steer adjusts the ratio from the smoothed fill level left after a read,
+// consuming faster while the queue is above its target and slower while below
+func (r *DriftResampler) steer(fill int, frames int) {
+	r.fill += (float64(fill) - r.fill) * driftFillSmoothing
+
+	if r.targetFill == 0 {
+		return
+	}
+
+	err := (r.fill - float64(r.targetFill)) / float64(r.targetFill)
+	r.integral += err * float64(frames) / 1000
+	r.integral = max(-driftIntegralWindup, min(driftIntegralWindup, r.integral))
+
+	r.ratio = 1 + max(-maxDriftRatio, min(maxDriftRatio, driftProportional*err+driftIntegral*r.integral))
+}
+
+// Shape of the interpolation kernel of the SincResampler. It passes up to the
+// cutoff, as a fraction of the lower of the two Nyquist frequencies, and is
+// windowed to sincZeroCrossings on either side, tabulated at sincResolution
+// points between input frames.
+const (
+	sincZeroCrossings = 16
+	sincCutoff        = 0.92
+	sincResolution    = 256
+)
+
+// SincResampler converts interleaved audio from one sample rate to another
+// by band-limited interpolation with a Blackman-windowed sinc kernel, pulling
+// its input as it goes. Audio at the same rate passes through untouched.
+type SincResampler struct {
+	channels  int
+	step      float64
+	halfWidth int
+	kernel    []float32
+	weights   []float32
+	buf       []float32
+	frames    int
+	pos       float64
+	end       int
+}
+
+// NewSincResampler creates a new SincResampler from inputRate to outputRate
+func NewSincResampler(inputRate uint32, outputRate uint32, channels int) *SincResampler {
+	r := &SincResampler{
+		channels: channels,
+		step:     float64(inputRate) / float64(outputRate),
+		end:      -1,
+	}
+
+	if inputRate != outputRate {
+		// The kernel widens to filter below the output's Nyquist frequency
+		// when there are fewer output frames than input frames
+		cutoff := sincCutoff * min(1, 1/r.step)
+		r.halfWidth = int(math.Ceil(sincZeroCrossings / cutoff))
+		r.weights = make([]float32, 2*r.halfWidth)
+
+		r.kernel = make([]float32, r.halfWidth*sincResolution+2)
+		for i := range r.kernel {
+			x := float64(i) / sincResolution
+			if x >= float64(r.halfWidth) {
+				break
+			}
+
+			sinc := 1.0
+			if x > 0 {
+				sinc = math.Sin(math.Pi*cutoff*x) / (math.Pi * cutoff * x)
+			}
+			w := math.Pi * (x/float64(r.halfWidth) + 1)
+			blackman := 0.42 - 0.5*math.Cos(w) + 0.08*math.Cos(2*w)
+			r.kernel[i] = float32(cutoff * sinc * blackman)
+		}
+	}
+
+	// The kernel starts centred on the first input frame, with silence before it
+	r.frames = r.halfWidth
+	r.pos = float64(r.halfWidth)
+	r.buf = make([]float32, r.frames*channels)
+
+	return r
+}
+
+// Read fills out with resampled frames, calling fill for more input frames
+// whenever it runs out. fill writes up to len(input) samples of whole frames
+// and returns how many frames it wrote, and false once the input has ended.
+// Frames it could not provide yet are taken as silence. Read reports false
+// once the output has reached the end of the input, filling the rest of out
+// with silence.
+func (r *SincResampler) Read(out []float32, fill func(input []float32) (int, bool)) bool {
+	channels := r.channels
+	frames := len(out) / channels
+
+	for i := 0; i < frames; i++ {
+		center := int(r.pos)
+		if center+r.halfWidth >= r.frames {
+			r.refill(frames-i, fill)
+			center = int(r.pos)
+		}
+
+		if r.end >= 0 && center >= r.end {
+			clear(out[i*channels:])
+			return false
+		}
+
+		frame := out[i*channels : (i+1)*channels]
+		if r.halfWidth == 0 {
+			copy(frame, r.buf[center*channels:(center+1)*channels])
+			r.pos += r.step
+			continue
+		}
+
+		frac := r.pos - float64(center)
+		for j := range r.weights {
+			r.weights[j] = r.kernelAt(float64(j-r.halfWidth+1) - frac)
+		}
+
+		input := r.buf[(center-r.halfWidth+1)*channels:]
+		for ch := range frame {
+			var sum float32
+			for j, w := range r.weights {
+				sum += w * input[j*channels+ch]
+			}
+			frame[ch] = sum
+		}
+
+		r.pos += r.step
+	}
+
+	return true
+}
+
+// kernelAt interpolates the kernel at x input frames from its centre
+func (r *SincResampler) kernelAt(x float64) float32 {
+	a := math.Abs(x) * sincResolution
+	i := int(a)
+	if i+1 >= len(r.kernel) {
+		return 0
+	}
+
+	t := float32(a - float64(i))
+	return r.kernel[i] + (r.kernel[i+1]-r.kernel[i])*t
+}
+
+// refill drops the input frames the kernel has moved past and pulls in enough
+// for the given number of output frames
+func (r *SincResampler) refill(outputFrames int, fill func(input []float32) (int, bool)) {
+	channels := r.channels
+
+	drop := min(max(int(r.pos)-max(r.halfWidth-1, 0), 0), r.frames)
+	copy(r.buf, r.buf[drop*channels:r.frames*channels])
+	r.frames -= drop
+	r.pos -= float64(drop)
+	if r.end >= 0 {
+		r.end -= drop
+	}
+
+	want := int(math.Ceil(float64(outputFrames)*r.step)) + r.halfWidth + 1
+	if need := (r.frames + want) * channels; cap(r.buf) < need {
+		r.buf = append(r.buf[:r.frames*channels], make([]float32, need-r.frames*channels)...)
+	}
+	r.buf = r.buf[:(r.frames+want)*channels]
+	input := r.buf[r.frames*channels:]
+
+	n := 0
+	if r.end < 0 {
+		var more bool
+		n, more = fill(input)
+		if !more {
+			r.end = r.frames + n
+		}
+	}
+	clear(input[n*channels:])
+	r.frames += want
+}
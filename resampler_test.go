@@ -0,0 +1,170 @@
+package main
+
+import (
+	"math"
+	"testing"
+)
+
+// TestDriftResampler runs a capture device whose clock is 300 ppm fast against
+// a playback device through a RingBuffer, advancing whichever callback is due
+// next, and checks the resampler holds the queue at its target fill
+func TestDriftResampler(t *testing.T) {
+	const (
+		sampleRate     = 48000
+		channels       = 2
+		captureRatio   = 1.0003
+		warmUpSeconds  = 30
+		runningSeconds = 600
+	)
+
+	tests := []struct {
+		name           string
+		captureFrames  int
+		playbackFrames int
+	}{
+		{"equal periods", 480, 480},
+		{"unequal periods", 441, 512},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			targetFill := sampleRate / 50 * channels
+			queue := NewRingBuffer(sampleRate * channels)
+			resampler := NewDriftResampler(channels, targetFill)
+
+			in := make([]float32, tt.captureFrames*channels)
+			out := make([]float32, tt.playbackFrames*channels)
+			var captureTime, playbackTime, phase float64
+			var underruns, overruns uint64
+			minFill, maxFill := math.MaxInt, 0
+			var ratioSum float64
+			var reads int
+
+			for playbackTime < runningSeconds {
+				nextCapture := captureTime + float64(tt.captureFrames)/(sampleRate*captureRatio)
+				nextPlayback := playbackTime + float64(tt.playbackFrames)/sampleRate
+
+				if nextCapture <= nextPlayback {
+					captureTime = nextCapture
+					for i := 0; i < tt.captureFrames; i++ {
+						s := float32(0.5 * math.Sin(phase))
+						phase += 2 * math.Pi * 440 / sampleRate
+						for c := 0; c < channels; c++ {
+							in[i*channels+c] = s
+						}
+					}
+					queue.Write(in)
+					continue
+				}
+
+				playbackTime = nextPlayback
+				ok := resampler.Read(queue, out)
+
+				if playbackTime < warmUpSeconds {
+					underruns, overruns = queue.Underruns(), queue.Overruns()
+					continue
+				}
+				if !ok {
+					t.Fatalf("ran dry at %.1f s", playbackTime)
+				}
+
+				minFill = min(minFill, queue.Len())
+				maxFill = max(maxFill, queue.Len())
+				ratioSum += resampler.Ratio()
+				reads++
+			}
+
+			if queue.Underruns() != underruns || queue.Overruns() != overruns {
+				t.Errorf("%d underruns and %d overruns after warming up", queue.Underruns()-underruns, queue.Overruns()-overruns)
+			}
+
+			// The fill swings by up to a capture period between callbacks
+			slack := tt.captureFrames*channels + 64
+			if minFill < targetFill-slack || maxFill > targetFill+slack {
+				t.Errorf("fill between %d and %d, want %d ± %d", minFill, maxFill, targetFill, slack)
+			}
+
+			if ratio := ratioSum / float64(reads); math.Abs(ratio-captureRatio) > 5e-6 {
+				t.Errorf("average ratio %.6f, want %.6f", ratio, captureRatio)
+			}
+		})
+	}
+}
+
+// TestSincResampler converts a tone between sample rates, reading it in
+// uneven blocks, and compares it with the same tone generated at the output
+// rate
+func TestSincResampler(t *testing.T) {
+	const (
+		frequency = 1000
+		amplitude = 0.5
+		seconds   = 1
+	)
+
+	tests := []struct {
+		name       string
+		inputRate  uint32
+		outputRate uint32
+		channels   int
+		maxErrorDB float64
+	}{
+		{"same rate", 48000, 48000, 2, -140},
+		{"upsampling", 44100, 48000, 2, -90},
+		{"downsampling", 48000, 44100, 1, -90},
+		{"large ratio", 8000, 48000, 1, -90},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resampler := NewSincResampler(tt.inputRate, tt.outputRate, tt.channels)
+
+			inputFrames := int(tt.inputRate) * seconds
+			var read int
+			fill := func(input []float32) (int, bool) {
+				frames := min(len(input)/tt.channels, inputFrames-read)
+				for i := 0; i < frames; i++ {
+					s := float32(amplitude * math.Sin(2*math.Pi*frequency*float64(read+i)/float64(tt.inputRate)))
+					for c := 0; c < tt.channels; c++ {
+						input[i*tt.channels+c] = s
+					}
+				}
+				read += frames
+				return frames, read < inputFrames
+			}
+
+			outputFrames := int(tt.outputRate) * seconds
+			out := make([]float32, outputFrames*tt.channels)
+			blocks := []int{1, 7, 480, 1000}
+			for i, block := 0, 0; i < outputFrames; i, block = i+blocks[block], (block+1)%len(blocks) {
+				frames := min(blocks[block], outputFrames-i)
+				if !resampler.Read(out[i*tt.channels:(i+frames)*tt.channels], fill) {
+					t.Fatalf("ended after %d frames", i)
+				}
+			}
+
+			// The kernel only sees silence around the ends, so they are left out
+			var signal, noise float64
+			for i := outputFrames / 10; i < outputFrames*9/10; i++ {
+				want := amplitude * math.Sin(2*math.Pi*frequency*float64(i)/float64(tt.outputRate))
+				for c := 0; c < tt.channels; c++ {
+					got := float64(out[i*tt.channels+c])
+					signal += want * want
+					noise += (got - want) * (got - want)
+				}
+			}
+			if errorDB := 10 * math.Log10(noise/signal); errorDB > tt.maxErrorDB {
+				t.Errorf("error %.1f dB, want at most %.1f dB", errorDB, tt.maxErrorDB)
+			}
+
+			end := make([]float32, 4096*tt.channels)
+			if resampler.Read(end, fill) {
+				t.Error("still reading after the end of the input")
+			}
+			for _, s := range end[len(end)-tt.channels:] {
+				if s != 0 {
+					t.Fatalf("got %v after the end, want silence", s)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,101 @@
+package main
+
+import (
+	"math/bits"
+	"sync/atomic"
+)
+
+// RingBuffer is a lock-free queue of samples between one producer and one
+// consumer, such as a capture callback and a playback callback. Only the
+// producer may call Write and only the consumer may call Read and Discard.
+type RingBuffer struct {
+	samples   []float32
+	mask      uint64
+	written   atomic.Uint64
+	read      atomic.Uint64
+	underruns atomic.Uint64
+	overruns  atomic.Uint64
+}
+
+// NewRingBuffer creates a new RingBuffer holding at least capacity samples
+func NewRingBuffer(capacity int) *RingBuffer {
+	size := uint64(1) << bits.Len(uint(max(capacity, 2)-1))
+	return &RingBuffer{
+		samples: make([]float32, size),
+		mask:    size - 1,
+	}
+}
+
+// Len returns the number of samples waiting to be read
+func (r *RingBuffer) Len() int {
+	return int(r.written.Load() - r.read.Load())
+}
+
+// Cap returns the number of samples the buffer holds
+func (r *RingBuffer) Cap() int {
+	return len(r.samples)
+}
+
+// Write queues as many samples as fit, counting an overrun if some did not
+func (r *RingBuffer) Write(samples []float32) int {
+	written := r.written.Load()
+	free := uint64(len(r.samples)) - (written - r.read.Load())
+
+	n := uint64(len(samples))
+	if n > free {
+		n = free
+		r.overruns.Add(1)
+	}
+
+	for i := uint64(0); i < n; i++ {
+		r.samples[(written+i)&r.mask] = samples[i]
+	}
+	r.written.Store(written + n)
+
+	return int(n)
+}
+
+// Read dequeues up to len(samples) samples, filling the rest with silence and
+// counting an underrun if there were not enough
+func (r *RingBuffer) Read(samples []float32) int {
+	read := r.read.Load()
+	available := r.written.Load() - read
+
+	n := uint64(len(samples))
+	if n > available {
+		n = available
+		r.underruns.Add(1)
+	}
+
+	for i := uint64(0); i < n; i++ {
+		samples[i] = r.samples[(read+i)&r.mask]
+	}
+	clear(samples[n:])
+	r.read.Store(read + n)
+
+	return int(n)
+}
+
+// Discard drops up to n of the oldest samples
+func (r *RingBuffer) Discard(n int) int {
+	read := r.read.Load()
+	available := r.written.Load() - read
+
+	m := uint64(max(n, 0))
+	if m > available {
+		m = available
+	}
+	r.read.Store(read + m)
+
+	return int(m)
+}
+
+// Underruns counts the reads that ran out of samples
+func (r *RingBuffer) Underruns() uint64 {
+	return r.underruns.Load()
+}
+
+// Overruns counts the writes that ran out of space
+func (r *RingBuffer) Overruns() uint64 {
+	return r.overruns.Load()
+}
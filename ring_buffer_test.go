@@ -0,0 +1,152 @@
+package main
+
+import (
+	"math"
+	"slices"
+	"testing"
+)
+
+func TestNewRingBuffer(t *testing.T) {
+	tests := []struct {
+		capacity int
+		wantCap  int
+	}{
+		{0, 2},
+		{1, 2},
+		{2, 2},
+		{3, 4},
+		{1000, 1024},
+		{1024, 1024},
+	}
+
+	for _, tt := range tests {
+		if got := NewRingBuffer(tt.capacity).Cap(); got != tt.wantCap {
+			t.Errorf("capacity %d holds %d samples, want %d", tt.capacity, got, tt.wantCap)
+		}
+	}
+}
+
+func TestRingBuffer(t *testing.T) {
+	// The steps run on a buffer of 8 samples in order, the values written
+	// counting up from 1 and the ones read expected to follow
+	type step struct {
+		op            string
+		n             int
+		want          int
+		wantLen       int
+		wantOverruns  uint64
+		wantUnderruns uint64
+	}
+
+	tests := []struct {
+		name  string
+		steps []step
+	}{
+		{"write and read", []step{
+			{"write", 5, 5, 5, 0, 0},
+			{"read", 3, 3, 2, 0, 0},
+			{"read", 2, 2, 0, 0, 0},
+		}},
+		{"overrun", []step{
+			{"write", 6, 6, 6, 0, 0},
+			{"write", 4, 2, 8, 1, 0},
+			{"write", 1, 0, 8, 2, 0},
+			{"read", 8, 8, 0, 2, 0},
+		}},
+		{"underrun", []step{
+			{"read", 1, 0, 0, 0, 1},
+			{"write", 3, 3, 3, 0, 1},
+			{"read", 5, 3, 0, 0, 2},
+		}},
+		{"exactly full and empty", []step{
+			{"write", 8, 8, 8, 0, 0},
+			{"read", 8, 8, 0, 0, 0},
+		}},
+		{"discard", []step{
+			{"write", 6, 6, 6, 0, 0},
+			{"discard", 4, 4, 2, 0, 0},
+			{"discard", -1, 0, 2, 0, 0},
+			{"read", 2, 2, 0, 0, 0},
+			{"discard", 3, 0, 0, 0, 0},
+		}},
+		{"wrapping around", []step{
+			{"write", 6, 6, 6, 0, 0},
+			{"read", 5, 5, 1, 0, 0},
+			{"write", 7, 7, 8, 0, 0},
+			{"read", 4, 4, 4, 0, 0},
+			{"write", 6, 4, 8, 1, 0},
+			{"read", 10, 8, 0, 1, 1},
+		}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := NewRingBuffer(8)
+			var next, nextRead float32 = 1, 1
+
+			for i, s := range tt.steps {
+				var got int
+				switch s.op {
+				case "write":
+					samples := make([]float32, s.n)
+					for j := range samples {
+						samples[j] = next + float32(j)
+					}
+					got = r.Write(samples)
+					next += float32(got)
+				case "read":
+					samples := make([]float32, s.n)
+					for j := range samples {
+						samples[j] = -1
+					}
+					got = r.Read(samples)
+					for j, sample := range samples {
+						want := float32(0)
+						if j < got {
+							want = nextRead + float32(j)
+						}
+						if sample != want {
+							t.Fatalf("step %d read %g at %d, want %g", i, sample, j, want)
+						}
+					}
+					nextRead += float32(got)
+				case "discard":
+					got = r.Discard(s.n)
+					nextRead += float32(got)
+				}
+
+				if got != s.want || r.Len() != s.wantLen || r.Overruns() != s.wantOverruns || r.Underruns() != s.wantUnderruns {
+					t.Fatalf("step %d %s %d got %d with %d queued, %d overruns and %d underruns, want %d with %d queued, %d overruns and %d underruns",
+						i, s.op, s.n, got, r.Len(), r.Overruns(), r.Underruns(), s.want, s.wantLen, s.wantOverruns, s.wantUnderruns)
+				}
+			}
+		})
+	}
+}
+
+// TestRingBufferIndexWraparound checks that the buffer keeps working once its
+// counts of written and read samples wrap around
+func TestRingBufferIndexWraparound(t *testing.T) {
+	r := NewRingBuffer(8)
+	r.written.Store(math.MaxUint64 - 2)
+	r.read.Store(math.MaxUint64 - 2)
+
+	for i := 0; i < 4; i++ {
+		samples := []float32{float32(5 * i), float32(5*i + 1), float32(5*i + 2), float32(5*i + 3), float32(5*i + 4)}
+		if n := r.Write(samples); n != len(samples) {
+			t.Fatalf("wrote %d samples, want %d", n, len(samples))
+		}
+		if r.Len() != len(samples) {
+			t.Fatalf("%d samples queued, want %d", r.Len(), len(samples))
+		}
+
+		got := make([]float32, len(samples))
+		if n := r.Read(got); n != len(samples) || !slices.Equal(got, samples) {
+			t.Fatalf("read %v, want %v", got[:n], samples)
+		}
+	}
+
+	if r.Overruns() != 0 || r.Underruns() != 0 {
+		t.Errorf("got %d overruns and %d underruns, want none", r.Overruns(), r.Underruns())
+	}
+}
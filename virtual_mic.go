@@ -0,0 +1,138 @@
+package main
+
+import (
+	"encoding/json"
+	"log"
+)
+
+// Names of the virtual microphone's sink and source, which other apps list
+// by their descriptions
+const (
+	virtualMicSinkName          = "various_yam_sink"
+	virtualMicSinkDescription   = "various-yam Output"
+	virtualMicSourceName        = "various_yam_mic"
+	virtualMicSourceDescription = "various-yam Virtual Mic"
+)
+
+// VirtualMicSettings configures the virtual microphone. While it is enabled
+// the loopback plays to its sink instead of the selected playback device.
+type VirtualMicSettings struct {
+	Enabled bool `json:"enabled"`
+}
+
+// GetVirtualMicSettings gets the virtual microphone settings
+func (a *App) GetVirtualMicSettings() (VirtualMicSettings, error) {
+	serializedVirtualMicSettings, _ := a.fs.GetItem("virtualMicSettings")
+	if serializedVirtualMicSettings == "" {
+		return VirtualMicSettings{}, nil
+	}
+
+	var virtualMicSettings VirtualMicSettings
+	if err := json.Unmarshal([]byte(serializedVirtualMicSettings), &virtualMicSettings); err != nil {
+		return VirtualMicSettings{}, err
+	}
+
+	return virtualMicSettings, nil
+}
+
+// SetVirtualMicSettings sets the virtual microphone settings, creating or
+// removing it and moving the loopback onto or off its sink
+func (a *App) SetVirtualMicSettings(virtualMicSettings VirtualMicSettings) error {
+	if virtualMicSettings.Enabled {
+		if err := a.createVirtualMic(); err != nil {
+			return err
+		}
+	}
+
+	serializedVirtualMicSettings, err := json.Marshal(virtualMicSettings)
+	if err != nil {
+		return err
+	}
+
+	if err := a.fs.SetItem("virtualMicSettings", string(serializedVirtualMicSettings)); err != nil {
+		return err
+	}
+
+	if err := a.restartLoopbackAudio(); err != nil {
+		return err
+	}
+
+	// The sink is only removed once the loopback has moved off it
+	if !virtualMicSettings.Enabled {
+		return a.removeVirtualMic()
+	}
+
+	return nil
+}
+
+// startVirtualMic creates the virtual microphone if it is enabled
+func (a *App) startVirtualMic() {
+	virtualMicSettings, err := a.GetVirtualMicSettings()
+	if err != nil {
+		log.Println(err)
+		return
+	}
+
+	if !virtualMicSettings.Enabled {
+		return
+	}
+
+	if err := a.createVirtualMic(); err != nil {
+		log.Println(err)
+	}
+}
+
+// createVirtualMic creates the virtual microphone unless it exists already
+func (a *App) createVirtualMic() error {
+	a.virtualMicMu.Lock()
+	defer a.virtualMicMu.Unlock()
+
+	if a.virtualMic != nil {
+		return nil
+	}
+
+	virtualMic, err := createVirtualMic()
+	if err != nil {
+		return err
+	}
+	a.virtualMic = virtualMic
+
+	return nil
+}
+
+// removeVirtualMic removes the virtual microphone if it exists
+func (a *App) removeVirtualMic() error {
+	a.virtualMicMu.Lock()
+	defer a.virtualMicMu.Unlock()
+
+	if a.virtualMic == nil {
+		return nil
+	}
+
+	err := a.virtualMic.Remove()
+	a.virtualMic = nil
+
+	return err
+}
+
+// virtualMicSink finds the virtual microphone's sink among the playback
+// devices while it is enabled
+func (a *App) virtualMicSink(devices []MediaDeviceInfo) (MediaDeviceInfo, bool) {
+	virtualMicSettings, err := a.GetVirtualMicSettings()
+	if err != nil {
+		log.Println(err)
+		return MediaDeviceInfo{}, false
+	}
+
+	if !virtualMicSettings.Enabled {
+		return MediaDeviceInfo{}, false
+	}
+
+	for _, device := range filterDevices(devices, "audiooutput") {
+		if device.Label == virtualMicSinkDescription {
+			return device, true
+		}
+	}
+
+	return MediaDeviceInfo{}, false
+}
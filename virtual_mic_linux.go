@@ -0,0 +1,135 @@
+//go:build linux
+
+package main
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/jfreymuth/pulse"
+	"github.com/jfreymuth/pulse/proto"
+)
+
+// VirtualMic is a null sink with a remap source on its monitor, which other
+// apps see as a microphone playing whatever is sent to the sink. It is loaded
+// over the native protocol into PulseAudio, or into PipeWire through
+// pipewire-pulse.
+type VirtualMic struct {
+	modules []uint32
+}
+
+// createVirtualMic loads the null sink and the remap source, unloading any
+// left behind by an earlier run that did not shut down cleanly
+func createVirtualMic() (*VirtualMic, error) {
+	client, err := newPulseClient()
+	if err != nil {
+		return nil, err
+	}
+	defer client.Close()
+
+	if err := unloadVirtualMicModules(client); err != nil {
+		return nil, err
+	}
+
+	v := &VirtualMic{}
+
+	sink, err := loadModule(client, "module-null-sink",
+		"sink_name="+virtualMicSinkName,
+		"sink_properties=device.description="+escapeModuleArgument(virtualMicSinkDescription),
+	)
+	if err != nil {
+		return nil, err
+	}
+	v.modules = append(v.modules, sink)
+
+	source, err := loadModule(client, "module-remap-source",
+		"master="+virtualMicSinkName+".monitor",
+		"source_name="+virtualMicSourceName,
+		"source_properties=device.description="+escapeModuleArgument(virtualMicSourceDescription),
+	)
+	if err != nil {
+		return nil, errors.Join(err, v.unload(client))
+	}
+	v.modules = append(v.modules, source)
+
+	return v, nil
+}
+
+// Remove unloads the remap source and the null sink
+func (v *VirtualMic) Remove() error {
+	client, err := newPulseClient()
+	if err != nil {
+		return err
+	}
+	defer client.Close()
+
+	return v.unload(client)
+}
+
+// unload unloads the modules last loaded first
+func (v *VirtualMic) unload(client *pulse.Client) error {
+	var errs []error
+	for i := len(v.modules) - 1; i >= 0; i-- {
+		if err := unloadModule(client, v.modules[i]); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	v.modules = nil
+
+	return errors.Join(errs...)
+}
+
+// newPulseClient connects to the sound server
+func newPulseClient() (*pulse.Client, error) {
+	client, err := pulse.NewClient(pulse.ClientApplicationName("various-yam"))
+	if err != nil {
+		return nil, fmt.Errorf("creating the virtual microphone needs PulseAudio or pipewire-pulse: %w", err)
+	}
+
+	return client, nil
+}
+
+// loadModule loads a module, returning its index
+func loadModule(client *pulse.Client, name string, arguments ...string) (uint32, error) {
+	var reply proto.LoadModuleReply
+	if err := client.RawRequest(&proto.LoadModule{Name: name, Args: strings.Join(arguments, " ")}, &reply); err != nil {
+		return 0, fmt.Errorf("loading %s: %w", name, err)
+	}
+
+	return reply.ModuleIndex, nil
+}
+
+func unloadModule(client *pulse.Client, index uint32) error {
+	if err := client.RawRequest(&proto.UnloadModule{ModuleIndex: index}, nil); err != nil {
+		return fmt.Errorf("unloading module %d: %w", index, err)
+	}
+	return nil
+}
+
+// unloadVirtualMicModules unloads the modules of any virtual microphone
+// loaded earlier, recognized by the names of their sink and source
+func unloadVirtualMicModules(client *pulse.Client) error {
+	var modules proto.GetModuleInfoListReply
+	if err := client.RawRequest(&proto.GetModuleInfoList{}, &modules); err != nil {
+		return fmt.Errorf("listing modules: %w", err)
+	}
+
+	var errs []error
+	for _, module := range modules {
+		if strings.Contains(module.ModuleArgs, "sink_name="+virtualMicSinkName) ||
+			strings.Contains(module.ModuleArgs, "source_name="+virtualMicSourceName) {
+			if err := unloadModule(client, module.ModuleIndex); err != nil {
+				errs = append(errs, err)
+			}
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+// escapeModuleArgument escapes the spaces of a module argument value, which
+// would otherwise end it
+func escapeModuleArgument(value string) string {
+	return strings.ReplaceAll(value, " ", `\ `)
+}
@@ -0,0 +1,20 @@
+//go:build !linux
+
+package main
+
+import (
+	"errors"
+)
+
+// VirtualMic is unavailable, as virtual microphones are only created on Linux
+type VirtualMic struct{}
+
+// createVirtualMic fails, as virtual microphones are only created on Linux
+func createVirtualMic() (*VirtualMic, error) {
+	return nil, errors.New("the virtual microphone is only supported on Linux")
+}
+
+// Remove does nothing
+func (v *VirtualMic) Remove() error {
+	return nil
+}
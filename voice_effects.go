@@ -0,0 +1,362 @@
+package main
+
+import (
+	"math"
+	"math/cmplx"
+)
+
+// Phase vocoder frame size and overlap. Larger frames resolve low voices
+// better at the cost of latency.
+const (
+	pitchShiftFrameSize   = 1024
+	pitchShiftOversamples = 4
+)
+
+// fft transforms x in place with an unnormalized radix-2 FFT, or its inverse.
+// The length of x must be a power of two.
+func fft(x []complex128, inverse bool) {
+	n := len(x)
+
+	for i, j := 1, 0; i < n; i++ {
+		bit := n >> 1
+		for ; j&bit != 0; bit >>= 1 {
+			j ^= bit
+		}
+		j |= bit
+		if i < j {
+			x[i], x[j] = x[j], x[i]
+		}
+	}
+
+	sign := -1.0
+	if inverse {
+		sign = 1
+	}
+
+	for size := 2; size <= n; size <<= 1 {
+		step := cmplx.Rect(1, sign*2*math.Pi/float64(size))
+		for start := 0; start < n; start += size {
+			w := complex(1, 0)
+			for k := 0; k < size/2; k++ {
+				even, odd := x[start+k], x[start+k+size/2]*w
+				x[start+k] = even + odd
+				x[start+k+size/2] = even - odd
+				w *= step
+			}
+		}
+	}
+}
+
+type pitchShiftChannel struct {
+	input      []float64
+	output     []float64
+	accum      []float64
+	lastPhase  []float64
+	offset     []float64
+	nextOffset []float64
+	rover      int
+}
+
+// PitchShifter shifts the pitch without changing the tempo with a phase
+// vocoder, delaying the signal by one frame. Every peak of the spectrum is
+// moved along with the bins around it, which keeps the shape of the window's
+// main lobe and with it the level of the partial.
+type PitchShifter struct {
+	ratio     float64
+	hop       int
+	window    []float64
+	bins      []complex128
+	shifted   []complex128
+	magnitude []float64
+	frequency []float64
+	peaks     []int
+	channels  []pitchShiftChannel
+}
+
+// NewPitchShifter creates a new PitchShifter shifting by semitones
+func NewPitchShifter(channels int, semitones float64) *PitchShifter {
+	size := pitchShiftFrameSize
+	p := &PitchShifter{
+		ratio:     math.Pow(2, semitones/12),
+		hop:       size / pitchShiftOversamples,
+		window:    make([]float64, size),
+		bins:      make([]complex128, size),
+		shifted:   make([]complex128, size),
+		magnitude: make([]float64, size/2+1),
+		frequency: make([]float64, size/2+1),
+		peaks:     make([]int, 0, size/2),
+		channels:  make([]pitchShiftChannel, channels),
+	}
+
+	for k := range p.window {
+		p.window[k] = 0.5 - 0.5*math.Cos(2*math.Pi*float64(k)/float64(size))
+	}
+
+	for c := range p.channels {
+		p.channels[c] = pitchShiftChannel{
+			input:      make([]float64, size),
+			output:     make([]float64, size),
+			accum:      make([]float64, 2*size),
+			lastPhase:  make([]float64, size/2+1),
+			offset:     make([]float64, size/2+1),
+			nextOffset: make([]float64, size/2+1),
+			rover:      size - p.hop,
+		}
+	}
+
+	return p
+}
+
+func (p *PitchShifter) Process(samples []float32) {
+	latency := len(p.window) - p.hop
+	for i, s := range samples {
+		channel := &p.channels[i%len(p.channels)]
+
+		channel.input[channel.rover] = float64(s)
+		samples[i] = float32(channel.output[channel.rover-latency])
+		channel.rover++
+
+		if channel.rover == len(p.window) {
+			channel.rover = latency
+			p.shiftFrame(channel)
+		}
+	}
+}
+
+// shiftFrame analyzes a full input frame into bin magnitudes and true
+// frequencies, moves the region around every peak by the whole bins nearest
+// to its shifted frequency and resynthesizes them
+func (p *PitchShifter) shiftFrame(channel *pitchShiftChannel) {
+	size := len(p.window)
+	half := size / 2
+	expected := 2 * math.Pi * float64(p.hop) / float64(size)
+
+	for k := range p.bins {
+		p.bins[k] = complex(channel.input[k]*p.window[k], 0)
+	}
+	fft(p.bins, false)
+
+	for k := 0; k <= half; k++ {
+		magnitude, phase := cmplx.Polar(p.bins[k])
+
+		// The phase advance beyond what the bin's center frequency explains
+		// gives how far the true frequency is from it
+		delta := phase - channel.lastPhase[k] - float64(k)*expected
+		channel.lastPhase[k] = phase
+		delta -= 2 * math.Pi * math.Round(delta/(2*math.Pi))
+
+		p.magnitude[k] = magnitude
+		p.frequency[k] = float64(k) + delta*pitchShiftOversamples/(2*math.Pi)
+	}
+
+	p.peaks = p.peaks[:0]
+	for k := 1; k < half; k++ {
+		if p.magnitude[k] > p.magnitude[k-1] && p.magnitude[k] >= p.magnitude[k+1] {
+			p.peaks = append(p.peaks, k)
+		}
+	}
+
+	clear(p.shifted)
+	clear(channel.nextOffset)
+	start := 0
+	for i, peak := range p.peaks {
+		// A peak's region ends at the quietest bin before the next peak
+		end := half + 1
+		if i+1 < len(p.peaks) {
+			end = peak + 1
+			for k := peak + 2; k < p.peaks[i+1]; k++ {
+				if p.magnitude[k] < p.magnitude[end] {
+					end = k
+				}
+			}
+			end++
+		}
+
+		// Moving by whole bins keeps the bins' phases relative to each other,
+		// and the whole region is turned by however much further the shifted
+		// frequency has advanced since the first frame. The offset follows
+		// the partial to wherever its peak is in the next frame.
+		frequency := p.frequency[peak]
+		shift := int(math.Round(frequency * (p.ratio - 1)))
+		offset := math.Mod(channel.offset[peak]+frequency*(p.ratio-1)*expected, 2*math.Pi)
+		rotation := cmplx.Rect(1, offset)
+		for k := start; k < end; k++ {
+			channel.nextOffset[k] = offset
+			if j := k + shift; j >= 0 && j <= half {
+				p.shifted[j] += p.bins[k] * rotation
+			}
+		}
+
+		start = end
+	}
+	channel.offset, channel.nextOffset = channel.nextOffset, channel.offset
+	fft(p.shifted, true)
+
+	// Only the positive frequencies are resynthesized, which halves the real
+	// part, and overlapping frames windowed twice add up to 1.5 with a Hann
+	// window at four times overlap
+	scale := 2 / float64(size) / 1.5
+	for k := range p.window {
+		channel.accum[k] += scale * p.window[k] * real(p.shifted[k])
+	}
+
+	copy(channel.output, channel.accum[:p.hop])
+	copy(channel.accum, channel.accum[p.hop:])
+	clear(channel.accum[len(channel.accum)-p.hop:])
+	copy(channel.input, channel.input[p.hop:])
+}
+
+// RingModulator multiplies the signal by a sine carrier, which at low
+// frequencies gives the classic robot voice
+type RingModulator struct {
+	channels int
+	step     float64
+	phase    float64
+	mix      float32
+}
+
+// NewRingModulator creates a new RingModulator with a carrier at frequency,
+// blending mix of the modulated signal with the dry one
+func NewRingModulator(sampleRate uint32, channels int, frequency, mix float64) *RingModulator {
+	return &RingModulator{
+		channels: channels,
+		step:     2 * math.Pi * frequency / float64(sampleRate),
+		mix:      float32(mix),
+	}
+}
+
+func (r *RingModulator) Process(samples []float32) {
+	for i := 0; i+r.channels <= len(samples); i += r.channels {
+		carrier := float32(math.Sin(r.phase))
+		r.phase = math.Mod(r.phase+r.step, 2*math.Pi)
+
+		for c := i; c < i+r.channels; c++ {
+			samples[c] += (samples[c]*carrier - samples[c]) * r.mix
+		}
+	}
+}
+
+// Freeverb tunings in samples at 44.1 kHz, and its gains
+var (
+	freeverbCombTunings    = []int{1116, 1188, 1277, 1356, 1422, 1491, 1557, 1617}
+	freeverbAllpassTunings = []int{556, 441, 341, 225}
+)
+
+const (
+	freeverbStereoSpread = 23
+	freeverbInputGain    = 0.015
+	freeverbWetGain      = 3
+	freeverbScaleRoom    = 0.28
+	freeverbOffsetRoom   = 0.7
+	freeverbScaleDamping = 0.4
+	freeverbAllpassGain  = 0.5
+)
+
+type freeverbComb struct {
+	buffer []float32
+	index  int
+	store  float32
+}
+
+type freeverbAllpass struct {
+	buffer []float32
+	index  int
+}
+
+// Reverb is a Freeverb reverberator, with parallel lowpass-feedback comb
+// filters followed by allpass filters for every channel
+type Reverb struct {
+	channels  int
+	combs     [][]freeverbComb
+	allpasses [][]freeverbAllpass
+	feedback  float32
+	damping   float32
+	mix       float32
+}
+
+// NewReverb creates a new Reverb. Room size and damping go from 0 to 1, and
+// mix blends the reverberated signal with the dry one.
+func NewReverb(sampleRate uint32, channels int, roomSize, damping, mix float64) *Reverb {
+	scale := float64(sampleRate) / 44100
+	length := func(tuning, channel int) int {
+		return max(1, int(float64(tuning+channel*freeverbStereoSpread)*scale))
+	}
+
+	r := &Reverb{
+		channels:  channels,
+		combs:     make([][]freeverbComb, channels),
+		allpasses: make([][]freeverbAllpass, channels),
+		feedback:  float32(roomSize*freeverbScaleRoom + freeverbOffsetRoom),
+		damping:   float32(damping * freeverbScaleDamping),
+		mix:       float32(mix),
+	}
+
+	for c := 0; c < channels; c++ {
+		for _, tuning := range freeverbCombTunings {
+			r.combs[c] = append(r.combs[c], freeverbComb{buffer: make([]float32, length(tuning, c))})
+		}
+		for _, tuning := range freeverbAllpassTunings {
+			r.allpasses[c] = append(r.allpasses[c], freeverbAllpass{buffer: make([]float32, length(tuning, c))})
+		}
+	}
+
+	return r
+}
+
+func (r *Reverb) Process(samples []float32) {
+	for i, s := range samples {
+		c := i % r.channels
+		input := s * freeverbInputGain
+
+		var wet float32
+		for j := range r.combs[c] {
+			comb := &r.combs[c][j]
+			output := comb.buffer[comb.index]
+			comb.store = output*(1-r.damping) + comb.store*r.damping
+			comb.buffer[comb.index] = input + comb.store*r.feedback
+			comb.index = (comb.index + 1) % len(comb.buffer)
+			wet += output
+		}
+
+		for j := range r.allpasses[c] {
+			allpass := &r.allpasses[c][j]
+			buffered := allpass.buffer[allpass.index]
+			allpass.buffer[allpass.index] = wet + buffered*freeverbAllpassGain
+			allpass.index = (allpass.index + 1) % len(allpass.buffer)
+			wet = buffered - wet
+		}
+
+		samples[i] = s + (wet*freeverbWetGain-s)*r.mix
+	}
+}
+
+// Delay repeats the signal after a delay, feeding the repeats back into it
+// for an echo that fades out
+type Delay struct {
+	buffer   []float32
+	index    int
+	feedback float32
+	mix      float32
+}
+
+// NewDelay creates a new Delay of the given milliseconds. Feedback is how
+// loud each repeat is compared to the one before, and mix blends the repeats
+// with the dry signal.
+func NewDelay(sampleRate uint32, channels int, milliseconds, feedback, mix float64) *Delay {
+	frames := max(1, int(milliseconds*float64(sampleRate)/1000))
+	return &Delay{
+		buffer:   make([]float32, frames*channels),
+		feedback: float32(feedback),
+		mix:      float32(mix),
+	}
+}
+
+func (d *Delay) Process(samples []float32) {
+	for i, s := range samples {
+		delayed := d.buffer[d.index]
+		d.buffer[d.index] = s + delayed*d.feedback
+		d.index = (d.index + 1) % len(d.buffer)
+
+		samples[i] = s + (delayed-s)*d.mix
+	}
+}
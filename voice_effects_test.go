@@ -0,0 +1,173 @@
+package main
+
+import (
+	"math"
+	"math/cmplx"
+	"testing"
+)
+
+// strongestFrequency finds the loudest frequency of the last 32768 samples,
+// interpolating between the bins around it
+func strongestFrequency(samples []float32) float64 {
+	const size = 1 << 15
+	bins := make([]complex128, size)
+	for i, s := range samples[len(samples)-size:] {
+		bins[i] = complex(float64(s)*(0.5-0.5*math.Cos(2*math.Pi*float64(i)/size)), 0)
+	}
+	fft(bins, false)
+
+	peak := 1
+	for k := 2; k < size/2-1; k++ {
+		if cmplx.Abs(bins[k]) > cmplx.Abs(bins[peak]) {
+			peak = k
+		}
+	}
+
+	before, at, after := cmplx.Abs(bins[peak-1]), cmplx.Abs(bins[peak]), cmplx.Abs(bins[peak+1])
+	offset := 0.5 * (before - after) / (before - 2*at + after)
+
+	return (float64(peak) + offset) * testSampleRate / size
+}
+
+func TestPitchShifter(t *testing.T) {
+	for _, frequency := range []float64{200, 440, 1000, 3000} {
+		for _, semitones := range []float64{-12, -7, -3, -1, 0, 1, 3, 7, 12} {
+			samples := sine(frequency, 0.5)
+			NewPitchShifter(1, semitones).Process(samples)
+
+			want := frequency * math.Pow(2, semitones/12)
+			if got := strongestFrequency(samples); math.Abs(got-want) > max(0.005*want, 2) {
+				t.Errorf("%g Hz shifted by %g semitones is at %.1f Hz, want %.1f Hz", frequency, semitones, got, want)
+			}
+
+			// The level holds within 1 dB
+			if peak := settledPeak(samples); math.Abs(linearToDB(peak/0.5)) > 1 {
+				t.Errorf("%g Hz shifted by %g semitones peaks at %.3f, want 0.5", frequency, semitones, peak)
+			}
+		}
+	}
+}
+
+func TestPitchShifterChannels(t *testing.T) {
+	// The channels are shifted apart, a tone on the left leaving the right
+	// silent
+	left := sine(440, 0.5)
+	samples := make([]float32, 2*len(left))
+	for i, s := range left {
+		samples[2*i] = s
+	}
+
+	NewPitchShifter(2, 5).Process(samples)
+
+	for i := 1; i < len(samples); i += 2 {
+		if samples[i] != 0 {
+			t.Fatalf("right channel has %g at frame %d, want silence", samples[i], i/2)
+		}
+	}
+	for i := range left {
+		left[i] = samples[2*i]
+	}
+	if peak := settledPeak(left); math.Abs(linearToDB(peak/0.5)) > 1 {
+		t.Errorf("left channel peaks at %.3f, want 0.5", peak)
+	}
+}
+
+func TestRingModulator(t *testing.T) {
+	tests := []struct {
+		name string
+		mix  float64
+		want func(carrier float64) float64
+	}{
+		{"dry", 0, func(float64) float64 { return 1 }},
+		{"wet", 1, func(carrier float64) float64 { return carrier }},
+		{"half", 0.5, func(carrier float64) float64 { return 0.5 + 0.5*carrier }},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// A constant signal is modulated into the carrier itself, the
+			// same on both channels
+			samples := make([]float32, 2*testSampleRate/10)
+			for i := range samples {
+				samples[i] = 1
+			}
+			NewRingModulator(testSampleRate, 2, 50, tt.mix).Process(samples)
+
+			for i := 0; i < len(samples); i += 2 {
+				want := tt.want(math.Sin(2 * math.Pi * 50 * float64(i/2) / testSampleRate))
+				if math.Abs(float64(samples[i])-want) > 1e-4 || samples[i+1] != samples[i] {
+					t.Fatalf("got %g, %g at frame %d, want %g", samples[i], samples[i+1], i/2, want)
+				}
+			}
+		})
+	}
+}
+
+func TestDelay(t *testing.T) {
+	// An impulse on the left repeats every 100 ms at half the level of the
+	// repeat before, blended half with the dry signal
+	samples := make([]float32, 2*testSampleRate)
+	samples[0] = 1
+	NewDelay(testSampleRate, 2, 100, 0.5, 0.5).Process(samples)
+
+	const frames = testSampleRate / 10
+	want := map[int]float32{0: 0.5, frames: 0.5, 2 * frames: 0.25, 3 * frames: 0.125}
+	for i, s := range samples {
+		if i%2 == 1 {
+			if s != 0 {
+				t.Fatalf("right channel has %g at frame %d, want silence", s, i/2)
+			}
+			continue
+		}
+		if s != want[i/2] {
+			t.Fatalf("got %g at frame %d, want %g", s, i/2, want[i/2])
+		}
+		if i/2 == 3*frames {
+			break
+		}
+	}
+}
+
+func TestReverb(t *testing.T) {
+	impulse := func() []float32 {
+		samples := make([]float32, 2*2*testSampleRate)
+		samples[0], samples[1] = 1, 1
+		return samples
+	}
+
+	// Fully dry leaves the signal alone
+	samples := impulse()
+	NewReverb(testSampleRate, 2, 0.5, 0.5, 0).Process(samples)
+	if samples[0] != 1 || framePeak(samples[2:]) != 0 {
+		t.Error("dry reverb changed the signal")
+	}
+
+	// The tail dies away, and lasts longer in a larger room
+	tails := make(map[float64]float64)
+	for _, roomSize := range []float64{0.2, 0.9} {
+		samples := impulse()
+		NewReverb(testSampleRate, 2, roomSize, 0.5, 1).Process(samples)
+
+		early := framePeak(samples[2*testSampleRate/10 : 2*testSampleRate/5])
+		late := framePeak(samples[len(samples)-2*testSampleRate/10:])
+		if early < 0.001 || late > early/10 {
+			t.Errorf("room of %g has an early peak of %g and a late one of %g", roomSize, early, late)
+		}
+		tails[roomSize] = late / early
+
+		// The channels are spread apart
+		same := true
+		for i := 0; i < len(samples); i += 2 {
+			if samples[i] != samples[i+1] {
+				same = false
+				break
+			}
+		}
+		if same {
+			t.Errorf("room of %g has the same reverb on both channels", roomSize)
+		}
+	}
+	if tails[0.9] <= tails[0.2] {
+		t.Errorf("larger room decays to %g of its early peak, smaller one to %g", tails[0.9], tails[0.2])
+	}
+}
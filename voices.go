@@ -0,0 +1,160 @@
+package main
+
+import (
+	"context"
+	"os"
+	"sync"
+)
+
+// Audio file states reported to the UI and controllers
+const (
+	AudioFileStateIdle    = "idle"
+	AudioFileStatePlaying = "playing"
+	AudioFileStateLooping = "looping"
+	AudioFileStateMissing = "missing"
+)
+
+// AudioFileStateEvent reports an audio file changing state
+type AudioFileStateEvent struct {
+	AudioFile string `json:"audioFile"`
+	State     string `json:"state"`
+}
+
+type voice struct {
+	id     uint64
+	loop   bool
+	cancel context.CancelFunc
+}
+
+// VoiceManager tracks the audio files being played and notifies subscribers
+// when they start and finish
+type VoiceManager struct {
+	mu          sync.Mutex
+	voices      map[string]*voice
+	nextID      uint64
+	subscribers map[uint64]func(AudioFileStateEvent)
+	nextSubID   uint64
+}
+
+// NewVoiceManager creates a new VoiceManager
+func NewVoiceManager() *VoiceManager {
+	return &VoiceManager{
+		voices:      make(map[string]*voice),
+		subscribers: make(map[uint64]func(AudioFileStateEvent)),
+	}
+}
+
+// Subscribe calls callback whenever an audio file changes state, until the
+// returned function is called
+func (m *VoiceManager) Subscribe(callback func(AudioFileStateEvent)) func() {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	id := m.nextSubID
+	m.nextSubID++
+	m.subscribers[id] = callback
+
+	return func() {
+		m.mu.Lock()
+		defer m.mu.Unlock()
+
+		delete(m.subscribers, id)
+	}
+}
+
+// Start registers a new voice for an audio file, stopping any voice already
+// playing it. The returned ID must be passed to Finish once playback ends.
+func (m *VoiceManager) Start(audioFile string, loop bool, cancel context.CancelFunc) uint64 {
+	m.mu.Lock()
+	if v, ok := m.voices[audioFile]; ok {
+		v.cancel()
+	}
+
+	id := m.nextID
+	m.nextID++
+	m.voices[audioFile] = &voice{
+		id:     id,
+		loop:   loop,
+		cancel: cancel,
+	}
+	m.mu.Unlock()
+
+	m.Notify(audioFile)
+
+	return id
+}
+
+// Finish removes the voice with the given ID once its playback has ended
+func (m *VoiceManager) Finish(audioFile string, id uint64) {
+	m.mu.Lock()
+	v, ok := m.voices[audioFile]
+	if !ok || v.id != id {
+		m.mu.Unlock()
+		return
+	}
+
+	v.cancel()
+	delete(m.voices, audioFile)
+	m.mu.Unlock()
+
+	m.Notify(audioFile)
+}
+
+// Stop stops the voice playing an audio file
+func (m *VoiceManager) Stop(audioFile string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if v, ok := m.voices[audioFile]; ok {
+		v.cancel()
+	}
+}
+
+// StopAll stops all voices
+func (m *VoiceManager) StopAll() {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for _, v := range m.voices {
+		v.cancel()
+	}
+}
+
+// State reports the state of an audio file
+func (m *VoiceManager) State(audioFile string) string {
+	m.mu.Lock()
+	v, ok := m.voices[audioFile]
+	m.mu.Unlock()
+
+	switch {
+	case ok && v.loop:
+		return AudioFileStateLooping
+	case ok:
+		return AudioFileStatePlaying
+	}
+
+	if _, err := os.Stat(audioFile); err != nil {
+		return AudioFileStateMissing
+	}
+
+	return AudioFileStateIdle
+}
+
+// Notify sends the current state of an audio file to all subscribers
+func (m *VoiceManager) Notify(audioFile string) {
+	event := AudioFileStateEvent{
+		AudioFile: audioFile,
+		State:     m.State(audioFile),
+	}
+
+	m.mu.Lock()
+	subscribers := make([]func(AudioFileStateEvent), 0, len(m.subscribers))
+	for _, subscriber := range m.subscribers {
+		subscribers = append(subscribers, subscriber)
+	}
+	m.mu.Unlock()
+
+	for _, subscriber := range subscribers {
+		subscriber(event)
+	}
+}